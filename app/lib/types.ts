@@ -9,6 +9,7 @@ export type Sponsor = {
   href?: string;
   instagram?: string;
   logo?: string;
+  logoPng?: string;
   active?: boolean;
   category?: Array<'sponsor' | 'partner' | 'chili' | 'music' | 'booze' | 'foodtruck' | 'merch' | 'vendor'>;
   type?: 'community' | 'best-friend' | 'neighbor' | 'benefactor';
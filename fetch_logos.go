@@ -1,48 +1,208 @@
 package main
 
 import (
+	"bufio"
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/binary"
+	"encoding/hex"
 	"encoding/json"
+	"encoding/xml"
 	"errors"
 	"flag"
 	"fmt"
+	"html"
+	"image"
+	"image/color"
+	"image/draw"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
 	"io"
+	"math"
+	"math/bits"
+	"math/rand"
+	"net"
 	"net/http"
 	"net/url"
 	"os"
+	"os/exec"
 	"path/filepath"
+	"reflect"
 	"regexp"
+	"sort"
 	"strconv"
 	"strings"
+	"sync"
 	"time"
+	"unicode/utf8"
+
+	"sonofest/internal/content"
 )
 
-type Sponsor struct {
-	Name      string   `json:"name"`
-	Href      string   `json:"href"`
-	Instagram string   `json:"instagram"`
-	Logo      string   `json:"logo"`
-	Active    bool     `json:"active"`
-	Category  []string `json:"category"`
-	Type      string   `json:"type"`
-}
+type Sponsor = content.Sponsor
 
 // Enrichment flags / config
 type EnrichConfig struct {
-	Enable     bool
+	Policy     string // never | missing-href | missing-instagram | all
 	Provider   string // serpapi | openai | hybrid
 	SerpAPIKey string
 	OpenAIKey  string
+	Budget     *callBudget // paid provider calls for the whole run; nil = unlimited
+	Cache      *diskCache  // search results and LLM answers; nil = none
+
+	// Provider endpoints, overridable for tests and proxies; empty = default.
+	SerpAPIURL    string // search endpoint (SERPAPI_URL)
+	OpenAIBaseURL string // API base, without /chat/completions (OPENAI_BASE_URL)
+}
+
+const (
+	defaultSerpAPIURL    = "https://serpapi.com/search.json"
+	defaultOpenAIBaseURL = "https://api.openai.com/v1"
+)
+
+func (c EnrichConfig) serpAPIURL() string {
+	if c.SerpAPIURL != "" {
+		return c.SerpAPIURL
+	}
+	return defaultSerpAPIURL
+}
+
+func (c EnrichConfig) chatCompletionsURL() string {
+	base := c.OpenAIBaseURL
+	if base == "" {
+		base = defaultOpenAIBaseURL
+	}
+	return strings.TrimRight(base, "/") + "/chat/completions"
+}
+
+// forReplay adjusts the config for -replay. It stands in for missing API
+// keys (recordings hold no keys, but the providers won't run without one)
+// and drops the budget, since replayed calls cost nothing.
+func (c *EnrichConfig) forReplay() {
+	c.Budget = nil
+	if c.SerpAPIKey == "" {
+		c.SerpAPIKey = "replay"
+	}
+	if c.OpenAIKey == "" {
+		c.OpenAIKey = "replay"
+	}
+}
+
+// Enrichment policies: which blank sponsor fields are looked up.
+const (
+	enrichNever            = "never"
+	enrichMissingHref      = "missing-href"
+	enrichMissingInstagram = "missing-instagram"
+	enrichAll              = "all"
+)
+
+func validEnrichPolicy(p string) bool {
+	switch p {
+	case enrichNever, enrichMissingHref, enrichMissingInstagram, enrichAll:
+		return true
+	}
+	return false
+}
+
+// wantsHref and wantsInstagram report whether the policy looks up that field
+// when it is blank.
+func (c EnrichConfig) wantsHref() bool {
+	return c.Policy == enrichMissingHref || c.Policy == enrichAll
+}
+
+func (c EnrichConfig) wantsInstagram() bool {
+	return c.Policy == enrichMissingInstagram || c.Policy == enrichAll
+}
+
+// errBudgetExhausted is returned instead of making a paid call once the run's
+// budget is used up.
+var errBudgetExhausted = errors.New("paid call budget exhausted")
+
+// callBudget counts the paid SerpAPI/OpenAI calls of one run, shared by all
+// workers, and refuses calls past max (0 = no cap).
+type callBudget struct {
+	max int
+
+	mu      sync.Mutex
+	used    map[string]int // provider → calls made
+	refused int
+}
+
+func newCallBudget(max int) *callBudget {
+	return &callBudget{max: max, used: map[string]int{}}
 }
 
-var debug bool
+// spend reserves one call to provider, or returns errBudgetExhausted.
+func (b *callBudget) spend(provider string) error {
+	if b == nil {
+		return nil
+	}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.max > 0 && b.total() >= b.max {
+		b.refused++
+		return errBudgetExhausted
+	}
+	b.used[provider]++
+	return nil
+}
+
+// total is the number of calls made; callers hold mu.
+func (b *callBudget) total() int {
+	n := 0
+	for _, c := range b.used {
+		n += c
+	}
+	return n
+}
+
+// summary is the "Paid calls" line of the run summary.
+func (b *callBudget) summary() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	s := fmt.Sprintf("Paid calls: %d", b.total())
+	if b.max > 0 {
+		s += fmt.Sprintf(" of %d", b.max)
+	}
+	names := make([]string, 0, len(b.used))
+	for p := range b.used {
+		names = append(names, p)
+	}
+	sort.Strings(names)
+	for i, p := range names {
+		sep := ", "
+		if i == 0 {
+			sep = " ("
+		}
+		s += fmt.Sprintf("%s%s %d", sep, p, b.used[p])
+	}
+	if len(names) > 0 {
+		s += ")"
+	}
+	if b.refused > 0 {
+		s += fmt.Sprintf("; budget reached, %d calls refused", b.refused)
+	}
+	return s
+}
+
+var (
+	debug   bool
+	debugMu sync.Mutex // one debug line at a time, from any worker
+)
 
+// dbg logs to stderr, so debug lines from concurrent workers never mix into
+// the per-sponsor output printed on stdout.
 func dbg(format string, args ...interface{}) {
 	if !debug {
 		return
 	}
 	ts := time.Now().Format("15:04:05.000")
-	fmt.Printf("[DBG %s] "+format+"\n", append([]interface{}{ts}, args...)...)
+	debugMu.Lock()
+	defer debugMu.Unlock()
+	fmt.Fprintf(os.Stderr, "[DBG %s] "+format+"\n", append([]interface{}{ts}, args...)...)
 }
 
 // truncBytes truncates a byte slice to max length for debug output.
@@ -69,464 +229,6739 @@ func dbgDumpReq(req *http.Request) {
 		if strings.EqualFold(k, "Authorization") {
 			v = "****"
 		}
-		fmt.Fprintf(&b, "  %s: %s\n", k, v)
+		fmt.Fprintf(&b, "  %s: %s\n", k, v)
+	}
+	dbg("%s", b.String())
+}
+
+// ---- command line ----
+
+// globalOptions are the flags every command accepts, before or after the
+// command name.
+type globalOptions struct {
+	envFile   string
+	sitePath  string
+	publicDir string
+	debug     bool
+}
+
+func (g *globalOptions) register(fs *flag.FlagSet) {
+	fs.StringVar(&g.envFile, "env-file", g.envFile, "Path to .env file to load (optional)")
+	fs.StringVar(&g.sitePath, "site", g.sitePath, "Path to site.json")
+	fs.StringVar(&g.publicDir, "public", g.publicDir, "Public directory (where images/ lives)")
+	fs.BoolVar(&g.debug, "debug", g.debug, "Verbose debug logging")
+}
+
+// setup applies the global flags once a command's flags are parsed.
+func (g *globalOptions) setup() {
+	debug = g.debug
+	if debug {
+		fmt.Println("   • DEBUG MODE ON")
+	}
+	if err := loadDotEnv(g.envFile); err == nil {
+		fmt.Printf("   • Loaded env: %s\n", g.envFile)
+	}
+}
+
+func (g *globalOptions) logoDir() string {
+	return filepath.Join(g.publicDir, "images", "logos")
+}
+
+// command is one "group name" subcommand, e.g. "logos fetch". Commands
+// with an empty group are invoked by name alone.
+type command struct {
+	group, name string
+	summary     string
+	run         func(g *globalOptions, args []string)
+}
+
+func (c command) String() string {
+	return strings.TrimSpace(c.group + " " + c.name)
+}
+
+var commands = []command{
+	{"logos", "fetch", "Discover and download missing sponsor logos", cmdLogosFetch},
+	{"logos", "duplicates", "Report identical or near-identical logos and shared Instagram handles", cmdLogosDuplicates},
+	{"logos", "prune", "Report unreferenced logo files and broken logo paths; move the files to a trash folder", cmdLogosPrune},
+	{"sponsors", "enrich", "Look up missing sponsor websites and Instagram handles (no logo downloads)", cmdSponsorsEnrich},
+	{"sponsors", "normalize", "Rewrite sponsor types, categories, and handles to canonical forms", cmdSponsorsNormalize},
+	{"content", "validate", "Check site.json and exit non-zero on problems", cmdContentValidate},
+	{"content", "schema", "Write JSON Schemas for the content files from the Go models (-check: fail if stale)", cmdContentSchema},
+	{"", "linkcheck", "Check every URL and /docs/, /images/ path in the content files", cmdLinkcheck},
+	{"", "report", "Summarize sponsor content: missing links, logos, types, and categories", cmdReport},
+}
+
+func main() {
+	g := &globalOptions{envFile: ".env", sitePath: content.SiteFile, publicDir: "public"}
+	top := flag.NewFlagSet("sonofest", flag.ExitOnError)
+	g.register(top)
+	top.Usage = func() {
+		out := top.Output()
+		fmt.Fprintf(out, "Usage: sonofest [global flags] <command> [flags]\n\nCommands:\n")
+		for _, c := range commands {
+			fmt.Fprintf(out, "  %-20s %s\n", c, c.summary)
+		}
+		fmt.Fprintf(out, "\nGlobal flags (accepted before or after the command):\n")
+		top.PrintDefaults()
+		fmt.Fprintf(out, "\nRun 'sonofest <command> -h' for a command's flags.\n")
+	}
+	top.Parse(os.Args[1:])
+
+	args := top.Args()
+	for _, c := range commands {
+		switch {
+		case c.group == "" && len(args) >= 1 && args[0] == c.name:
+			c.run(g, args[1:])
+			return
+		case c.group != "" && len(args) >= 2 && args[0] == c.group && args[1] == c.name:
+			c.run(g, args[2:])
+			return
+		}
+	}
+	if len(args) > 0 {
+		fmt.Fprintf(top.Output(), "unknown command %q\n\n", strings.Join(args, " "))
+	}
+	top.Usage()
+	os.Exit(2)
+}
+
+// newCommandFlags returns a flag set for c that also accepts the globals.
+func newCommandFlags(g *globalOptions, c string) *flag.FlagSet {
+	fs := flag.NewFlagSet("sonofest "+c, flag.ExitOnError)
+	g.register(fs)
+	return fs
+}
+
+// parseCommand parses a command's flags and applies the globals.
+func parseCommand(g *globalOptions, fs *flag.FlagSet, args []string) {
+	fs.Parse(args)
+	if fs.NArg() > 0 {
+		fmt.Fprintf(fs.Output(), "unexpected arguments: %v\n", fs.Args())
+		fs.Usage()
+		os.Exit(2)
+	}
+	g.setup()
+}
+
+// sponsorFilter selects which sponsors a command works on.
+type sponsorFilter struct {
+	onlyActive bool
+	categories string
+	cats       map[string]bool
+}
+
+func addFilterFlags(fs *flag.FlagSet) *sponsorFilter {
+	f := &sponsorFilter{}
+	fs.BoolVar(&f.onlyActive, "only-active", false, "Process only active sponsors")
+	fs.StringVar(&f.categories, "categories", "", "Comma-separated category filter (e.g. sponsor,chili)")
+	return f
+}
+
+// work returns the indexes of the sponsors that pass the filter, printing
+// the filters in effect.
+func (f *sponsorFilter) work(sponsors []Sponsor) []int {
+	if strings.TrimSpace(f.categories) != "" {
+		f.cats = make(map[string]bool)
+		for _, c := range strings.Split(f.categories, ",") {
+			c = strings.TrimSpace(c)
+			if c != "" {
+				f.cats[strings.ToLower(c)] = true
+			}
+		}
+	}
+	if f.onlyActive {
+		fmt.Println("   • Filter: only active")
+	}
+	if len(f.cats) > 0 {
+		fmt.Printf("   • Filter: categories in %v\n", keys(f.cats))
+	}
+	var work []int
+	for i, s := range sponsors {
+		if f.onlyActive && !s.Active {
+			continue
+		}
+		if len(f.cats) > 0 && !overlapsLower(s.Category, f.cats) {
+			continue
+		}
+		work = append(work, i)
+	}
+	return work
+}
+
+// httpFlags configure the shared HTTP client and its on-disk cache.
+type httpFlags struct {
+	concurrency int
+	hostRate    float64
+	retries     int
+	cacheDir    string
+	refresh     bool
+	offline     bool
+	record      string
+	replay      string
+}
+
+func addHTTPFlags(fs *flag.FlagSet, concurrency int) *httpFlags {
+	h := &httpFlags{}
+	fs.IntVar(&h.concurrency, "concurrency", concurrency, "Number of sponsors (or links) to process in parallel")
+	fs.Float64Var(&h.hostRate, "host-rate", 2, "Max requests per second to any single host (0 = unlimited)")
+	fs.IntVar(&h.retries, "retries", 3, "Retries for 429/5xx/timeouts, with exponential backoff")
+	fs.StringVar(&h.cacheDir, "cache-dir", ".cache/sonofest", "Cache for search results, LLM answers, pages, and logo URLs (empty = no cache)")
+	fs.BoolVar(&h.refresh, "refresh", false, "Ignore cached entries and fetch everything again (the cache is updated)")
+	fs.BoolVar(&h.offline, "offline", false, "Make no network calls; use cached entries, however old")
+	fs.StringVar(&h.record, "record", "", "Save every HTTP request and response under this directory (turns the cache off)")
+	fs.StringVar(&h.replay, "replay", "", "Answer HTTP requests from a -record directory, without network (turns the cache off)")
+	return h
+}
+
+// client returns the shared HTTP client and the cache it reads through
+// (nil without -cache-dir). Recording and replaying bypass the cache so
+// that a recording holds every request a replay will make.
+func (h *httpFlags) client() (*http.Client, *diskCache) {
+	if h.refresh && h.offline {
+		fatal("parsing flags", errors.New("-refresh and -offline can't be combined"))
+	}
+	if h.record != "" && h.replay != "" {
+		fatal("parsing flags", errors.New("-record and -replay can't be combined"))
+	}
+	if h.replay != "" {
+		if _, err := os.Stat(h.replay); err != nil {
+			fatal("opening -replay directory", err)
+		}
+		fmt.Printf("   • Replaying HTTP from %s (cache off)\n", h.replay)
+		return &http.Client{Timeout: 2 * time.Minute, Transport: newRecordTransport(nil, h.replay)}, nil
+	}
+	client := newHTTPClient(h.hostRate, h.retries)
+	if h.record != "" {
+		if err := clearRecordings(h.record); err != nil {
+			fatal("clearing -record directory", err)
+		}
+		client.Transport = newRecordTransport(client.Transport, h.record)
+		fmt.Printf("   • Recording HTTP to %s (cache off)\n", h.record)
+		return client, nil
+	}
+	if h.cacheDir == "" {
+		if h.offline {
+			fatal("parsing flags", errors.New("-offline needs a -cache-dir"))
+		}
+		return client, nil
+	}
+	c := &diskCache{dir: h.cacheDir, refresh: h.refresh, offline: h.offline}
+	client.Transport = &cacheTransport{base: client.Transport, cache: c}
+	mode := ""
+	if c.refresh {
+		mode = " (refresh)"
+	} else if c.offline {
+		mode = " (offline)"
+	}
+	fmt.Printf("   • Cache: %s%s\n", h.cacheDir, mode)
+	return client, c
+}
+
+// liveClient is client without the disk cache, for checks that must see
+// each site as it is now. -record and -replay still apply.
+func (h *httpFlags) liveClient() *http.Client {
+	if h.offline {
+		fatal("parsing flags", errors.New("-offline can't be used here: links are always checked live"))
+	}
+	live := *h
+	live.cacheDir, live.refresh = "", false
+	client, _ := live.client()
+	return client
+}
+
+// enrichFlags configure the search providers used to fill in sponsor links.
+type enrichFlags struct {
+	policy     string
+	provider   string
+	serpAPIKey string
+	openAIKey  string
+	maxCalls   int
+}
+
+func addEnrichFlags(fs *flag.FlagSet, policy string) *enrichFlags {
+	e := &enrichFlags{}
+	fs.StringVar(&e.policy, "enrich", policy, "Look up blank sponsor fields via search: never|missing-href|missing-instagram|all")
+	fs.IntVar(&e.maxCalls, "max-paid-calls", 100, "Stop making SerpAPI/OpenAI calls after this many in one run (0 = no cap)")
+	fs.StringVar(&e.provider, "search-provider", "hybrid", "Search provider: serpapi|openai|hybrid")
+	fs.StringVar(&e.serpAPIKey, "serpapi-key", "", "SerpAPI key (or SERPAPI_KEY env / .env)")
+	fs.StringVar(&e.openAIKey, "openai-key", "", "OpenAI API key (or OPENAI_API_KEY env / .env)")
+	return e
+}
+
+// config backfills empty keys from the environment (after .env is loaded).
+func (e *enrichFlags) config() EnrichConfig {
+	policy := strings.ToLower(strings.TrimSpace(e.policy))
+	if !validEnrichPolicy(policy) {
+		fatal("parsing -enrich", fmt.Errorf("unknown policy %q (never, missing-href, missing-instagram, or all)", e.policy))
+	}
+	if e.serpAPIKey == "" {
+		e.serpAPIKey = os.Getenv("SERPAPI_KEY")
+	}
+	if e.openAIKey == "" {
+		e.openAIKey = os.Getenv("OPENAI_API_KEY")
+	}
+	c := EnrichConfig{
+		Policy:        policy,
+		Provider:      strings.ToLower(strings.TrimSpace(e.provider)),
+		SerpAPIKey:    strings.TrimSpace(e.serpAPIKey),
+		OpenAIKey:     strings.TrimSpace(e.openAIKey),
+		SerpAPIURL:    strings.TrimSpace(os.Getenv("SERPAPI_URL")),
+		OpenAIBaseURL: strings.TrimSpace(os.Getenv("OPENAI_BASE_URL")),
+	}
+	if c.Policy != enrichNever {
+		c.Budget = newCallBudget(e.maxCalls)
+		fmt.Printf("   • Enrichment: policy=%s provider=%s max-paid-calls=%d\n", c.Policy, c.Provider, e.maxCalls)
+	}
+	return c
+}
+
+const userAgent = "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128 Safari/537.36"
+
+func cmdLogosFetch(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "logos fetch")
+	filter := addFilterFlags(fs)
+	hf := addHTTPFlags(fs, 1)
+	ef := addEnrichFlags(fs, enrichNever)
+	rf := addReviewFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "Run without downloading or writing changes")
+	enrichMissing := fs.Bool("enrich-missing", false, "Shorthand for -enrich all")
+	explain := fs.Bool("explain", false, "Print the ranked logo candidates and their scores for each sponsor")
+	minLogoPx := fs.Int("min-logo-px", 64, "Reject downloaded logos whose shortest side is below this many pixels")
+	minICOPx := fs.Int("min-ico-px", 16, "Accept ICO favicons (the last resort) down to this many pixels, with a warning")
+	maxAspect := fs.Float64("max-aspect", 6, "Reject downloaded logos more stretched than this long:short ratio")
+	normalize := fs.Bool("normalize", false, "Trim, scale, pad, and re-encode logos (originals kept under -norm-originals)")
+	normMaxWidth := fs.Int("norm-max-width", 600, "Normalize: max logo width in pixels (0 = no limit)")
+	normBox := fs.String("norm-box", "", "Normalize: pad logos onto a WxH canvas, e.g. 400x200 (empty = no padding)")
+	normTolerance := fs.Int("norm-trim-tolerance", 8, "Normalize: per-channel color difference treated as border when trimming")
+	normFormat := fs.String("norm-format", "png", "Normalize: output format png|jpg|webp (webp needs cwebp from libwebp on PATH)")
+	normOriginals := fs.String("norm-originals", "", "Normalize: where untouched downloads are kept (default originals/ beside the logo directory)")
+	logoPNGSize := fs.Int("logo-png-size", 512, "Longest side in pixels of the PNG companion rendered for SVG logos (0 = off)")
+	parseCommand(g, fs, args)
+	if rf.apply != "" {
+		cmdApplyPatch(g, rf.apply)
+		return
+	}
+	if *enrichMissing {
+		ef.policy = enrichAll
+	}
+
+	boxW, boxH, err := parseBox(*normBox)
+	if err != nil {
+		fatal("parsing -norm-box", err)
+	}
+	norm := normalizeConfig{
+		Enable:    *normalize,
+		MaxWidth:  *normMaxWidth,
+		BoxW:      boxW,
+		BoxH:      boxH,
+		Tolerance: *normTolerance,
+		Format:    strings.TrimPrefix(strings.ToLower(strings.TrimSpace(*normFormat)), "."),
+		Originals: *normOriginals,
+	}
+	if norm.Originals == "" {
+		// A sibling of the logo directory: public/images/originals.
+		norm.Originals = filepath.Join(filepath.Dir(g.logoDir()), originalsDirName)
+	}
+	if norm.Format == "jpeg" {
+		norm.Format = "jpg"
+	}
+	switch norm.Format {
+	case "png", "jpg":
+	case "webp":
+		if _, err := exec.LookPath(cwebpTool); err != nil && norm.Enable {
+			fatal("-norm-format webp needs "+cwebpTool+" (from libwebp) on PATH", err)
+		}
+	default:
+		fatal("parsing -norm-format", fmt.Errorf("unsupported format %q (png, jpg, or webp)", norm.Format))
+	}
+
+	logoDir := g.logoDir()
+	if err := os.MkdirAll(logoDir, 0o755); err != nil {
+		fatal("creating logo dir", err)
+	}
+
+	client, cache := hf.client()
+	econf := ef.config()
+	econf.Cache = cache
+	if hf.replay != "" {
+		econf.forReplay()
+	}
+	if norm.Enable {
+		fmt.Printf("   • Normalize: max-width=%d box=%q format=%s\n", norm.MaxWidth, *normBox, norm.Format)
+	}
+	opts := &runOptions{
+		publicDir: g.publicDir,
+		logoDir:   logoDir,
+		dryRun:    *dryRun,
+		explain:   *explain,
+		gate:      imageGate{MinSide: *minLogoPx, MinICOSide: *minICOPx, MaxAspect: *maxAspect},
+		normalize: norm,
+		pngSize:   *logoPNGSize,
+		client:    client,
+		cache:     cache,
+		ua:        userAgent,
+		econf:     econf,
+	}
+	runSponsors(g, opts, filter, rf, hf.concurrency, "saved", "ok", "failed")
+}
+
+func cmdSponsorsEnrich(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "sponsors enrich")
+	filter := addFilterFlags(fs)
+	hf := addHTTPFlags(fs, 1)
+	ef := addEnrichFlags(fs, enrichAll)
+	rf := addReviewFlags(fs)
+	dryRun := fs.Bool("dry-run", false, "Look up links but don't write site.json")
+	parseCommand(g, fs, args)
+	if rf.apply != "" {
+		cmdApplyPatch(g, rf.apply)
+		return
+	}
+
+	client, cache := hf.client()
+	econf := ef.config()
+	if econf.Policy == enrichNever {
+		fatal("parsing -enrich", errors.New("sponsors enrich needs a policy other than never"))
+	}
+	econf.Cache = cache
+	if hf.replay != "" {
+		econf.forReplay()
+	}
+	opts := &runOptions{
+		publicDir:  g.publicDir,
+		logoDir:    g.logoDir(),
+		dryRun:     *dryRun,
+		enrichOnly: true,
+		client:     client,
+		cache:      cache,
+		ua:         userAgent,
+		econf:      econf,
+	}
+	runSponsors(g, opts, filter, rf, hf.concurrency, "updated", "ok", "not found")
+}
+
+// runSponsors processes the filtered sponsors of site.json with opts, prints
+// the summary with the given outcome labels, and writes back any changes
+// (after review, with rf.review).
+func runSponsors(g *globalOptions, opts *runOptions, filter *sponsorFilter, rf *reviewFlags, concurrency int, savedLabel, okLabel, failedLabel string) {
+	sponsors, raw, err := readSite(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	before := append([]Sponsor(nil), sponsors...)
+	logoSources := make(map[int]string)
+	if rf.review && !opts.dryRun && !opts.enrichOnly {
+		opts = opts.staged()
+		fmt.Printf("   • Review: files are staged in %s until approved\n", opts.stage.dir)
+	}
+
+	fmt.Printf("🔎 Scanning %d sponsors in %s\n", len(sponsors), g.sitePath)
+	work := filter.work(sponsors)
+	if opts.dryRun {
+		fmt.Println("   • DRY RUN (no downloads / no file writes)")
+	}
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	if concurrency > 1 {
+		fmt.Printf("   • Concurrency: %d workers\n", concurrency)
+	}
+	fmt.Println()
+
+	success, skipped, fail := 0, 0, 0
+	updatedJSON := false
+	for res := range processSponsors(opts, sponsors, work, concurrency) {
+		os.Stdout.Write(res.out.Bytes())
+		sponsors[res.index] = res.sponsor
+		if res.logoURL != "" {
+			logoSources[res.index] = res.logoURL
+		}
+		if res.changed {
+			updatedJSON = true
+		}
+		switch res.outcome {
+		case outcomeSaved:
+			success++
+		case outcomeOK:
+			skipped++
+		case outcomeFailed:
+			fail++
+		}
+	}
+
+	fmt.Printf("\nSummary: %d %s, %d %s, %d %s\n", success, savedLabel, skipped, okLabel, fail, failedLabel)
+	if opts.econf.Budget != nil {
+		fmt.Println(opts.econf.Budget.summary())
+	}
+
+	if !updatedJSON || opts.dryRun {
+		return
+	}
+	if rf.review {
+		ops, err := diffSponsors(before, sponsors)
+		if err != nil {
+			fatal("staging changes", err)
+		}
+		for i := range ops {
+			if op := &ops[i]; op.field == "logo" || op.field == "logoPng" {
+				op.Source = logoSources[op.index]
+				var file string
+				json.Unmarshal(op.Value, &file)
+				if file != "" {
+					op.Preview = desiredLocalLogoPath(opts.publicDir, opts.logoDir, Sponsor{Logo: file})
+					if opts.stage != nil {
+						op.Files = opts.stage.files(op.Preview, op.field == "logo")
+					}
+				}
+			}
+		}
+		if rf.patch != "" {
+			b, _ := json.MarshalIndent(ops, "", "  ")
+			if err := os.WriteFile(rf.patch, append(b, '\n'), 0o644); err != nil {
+				fatal("writing patch", err)
+			}
+			fmt.Printf("📝 Staged %d changes in %s; delete any you reject, then run with -apply %s\n", len(ops), rf.patch, rf.patch)
+			if opts.stage != nil {
+				fmt.Printf("   New logo files wait in %s; -apply moves in those of the changes you keep.\n", opts.stage.dir)
+			}
+			return
+		}
+		accepted := reviewOps(os.Stdin, os.Stdout, ops)
+		fmt.Printf("\nReview: %d of %d changes accepted\n", len(accepted), len(ops))
+		if opts.stage != nil {
+			// Whatever wasn't moved in was rejected.
+			defer os.RemoveAll(opts.stage.dir)
+		}
+		if len(accepted) == 0 {
+			return
+		}
+		sponsors = before
+		applied, _, err := applyPatch(sponsors, accepted, func() error {
+			return writeSite(g.sitePath, raw, sponsors, false)
+		})
+		if err != nil {
+			fatal("applying reviewed changes", err)
+		}
+		if applied == 0 {
+			return
+		}
+	} else if err := writeSite(g.sitePath, raw, sponsors, false); err != nil {
+		fatal("writing updated site.json", err)
+	}
+	fmt.Printf("📝 Updated only 'sponsors' in %s (backup created; other fields preserved).\n", g.sitePath)
+}
+
+func cmdLogosPrune(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "logos prune")
+	dryRun := fs.Bool("dry-run", false, "Report only; don't move anything")
+	trashDir := fs.String("trash", "", "Where unreferenced logos are moved (default logos/trash beside the public directory)")
+	parseCommand(g, fs, args)
+
+	sponsors, _, err := readSite(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	orphans, broken, err := findOrphans(g.logoDir(), g.publicDir, sponsors)
+	if err != nil {
+		fatal("scanning logos", err)
+	}
+	reportOrphans(os.Stdout, orphans, broken)
+	if len(orphans) == 0 {
+		return
+	}
+	if *dryRun {
+		fmt.Println("\n   (dry-run) not moving unreferenced files")
+		return
+	}
+	if *trashDir == "" {
+		// Beside the public directory, not in it: trash isn't deployed.
+		*trashDir = filepath.Join(filepath.Dir(filepath.Clean(g.publicDir)), "logos", "trash")
+	}
+	dir, err := moveToTrash(orphans, *trashDir)
+	if err != nil {
+		fatal("moving unreferenced logos", err)
+	}
+	fmt.Printf("\n📦 Moved %d files to %s\n", len(orphans), rel(dir))
+}
+
+func cmdLogosDuplicates(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "logos duplicates")
+	dryRun := fs.Bool("dry-run", false, "Report only; don't write site.json")
+	share := fs.Bool("share", false, "Point sponsors with identical logo files at one shared file")
+	similarBits := fs.Int("similar-bits", 6, "Max differing bits of the 64-bit perceptual hash for near-identical logos (-1 = off)")
+	parseCommand(g, fs, args)
+
+	sponsors, raw, err := readSite(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	files, err := scanLogoFiles(g.logoDir(), sponsors, g.publicDir)
+	if err != nil {
+		fatal("scanning logos", err)
+	}
+	fmt.Printf("🔎 Fingerprinting %d files in %s\n\n", len(files), rel(g.logoDir()))
+	if reportDuplicates(os.Stdout, sponsors, files, *similarBits, *share) && !*dryRun {
+		if err := writeSite(g.sitePath, raw, sponsors, false); err != nil {
+			fatal("writing updated site.json", err)
+		}
+		fmt.Printf("📝 Updated only 'sponsors' in %s (backup created; other fields preserved).\n", g.sitePath)
+	}
+}
+
+func cmdContentValidate(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "content validate")
+	parseCommand(g, fs, args)
+
+	doc, err := os.ReadFile(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	if !json.Valid(doc) {
+		var v interface{}
+		fatal("parsing "+g.sitePath, json.Unmarshal(doc, &v))
+	}
+	problems, err := validateContent(doc, siteRule, g.publicDir)
+	if err != nil {
+		fatal("validating "+g.sitePath, err)
+	}
+	for _, p := range problems {
+		fmt.Printf("%s:%s\n", g.sitePath, p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("\n❌ %d problems in %s\n", len(problems), g.sitePath)
+		os.Exit(1)
+	}
+	fmt.Printf("✅ %s matches app/lib/types.ts\n", g.sitePath)
+}
+
+func cmdContentSchema(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "content schema")
+	outDir := fs.String("out", "app/content/schema", "Directory for the schema files")
+	typesPath := fs.String("types", content.TypesFile, "TypeScript types the Go models must agree with")
+	check := fs.Bool("check", false, "Write nothing; exit non-zero if a schema is stale or the TypeScript types disagree")
+	parseCommand(g, fs, args)
+
+	schemas, err := content.Schemas()
+	if err != nil {
+		fatal("generating schemas", err)
+	}
+	if !*check {
+		if err := os.MkdirAll(*outDir, 0o755); err != nil {
+			fatal("creating schema dir", err)
+		}
+	}
+	failed := false
+	for _, sf := range content.SchemaFiles {
+		path := filepath.Join(*outDir, sf.Name)
+		old, _ := os.ReadFile(path)
+		switch {
+		case bytes.Equal(old, schemas[sf.Name]):
+			fmt.Printf("   ✓ %s is current\n", path)
+		case *check:
+			fmt.Printf("   ✗ %s is stale; run 'go run fetch_logos.go content schema'\n", path)
+			failed = true
+		default:
+			if err := os.WriteFile(path, schemas[sf.Name], 0o644); err != nil {
+				fatal("writing "+path, err)
+			}
+			fmt.Printf("   📝 wrote %s\n", path)
+		}
+	}
+
+	src, err := os.ReadFile(*typesPath)
+	if err != nil {
+		fatal("reading TypeScript types", err)
+	}
+	problems, err := content.CheckTypeScript(string(src))
+	if err != nil {
+		fatal("parsing TypeScript types", err)
+	}
+	for _, p := range problems {
+		fmt.Printf("   ✗ %s\n", p)
+	}
+	if len(problems) > 0 {
+		fmt.Printf("\n❌ %d differences between %s and internal/content\n", len(problems), *typesPath)
+		failed = true
+	}
+	if failed {
+		os.Exit(1)
+	}
+}
+
+func cmdSponsorsNormalize(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "sponsors normalize")
+	mapPath := fs.String("map", "app/content/normalize.json", "Mapping file of canonical sponsor types and categories")
+	dryRun := fs.Bool("dry-run", false, "Print the changes but don't write site.json")
+	parseCommand(g, fs, args)
+
+	rules, err := loadNormalizeRules(*mapPath)
+	if err != nil {
+		fatal("reading "+*mapPath, err)
+	}
+	sponsors, raw, err := readSite(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	empty, err := emptySponsorFields(raw)
+	if err != nil {
+		fatal("scanning site.json", err)
+	}
+	fmt.Printf("🔎 Normalizing %d sponsors in %s with %s\n\n", len(sponsors), g.sitePath, *mapPath)
+	changed := 0
+	for i := range sponsors {
+		var changes []string
+		sponsors[i], changes = normalizeSponsor(sponsors[i], rules)
+		for _, key := range empty[i] {
+			changes = append(changes, key+`: "" removed`)
+		}
+		if len(changes) == 0 {
+			continue
+		}
+		changed++
+		fmt.Printf("• %s\n", sponsors[i].Name)
+		for _, c := range changes {
+			fmt.Printf("   %s\n", c)
+		}
+	}
+	fmt.Printf("\nSummary: %d of %d sponsors changed\n", changed, len(sponsors))
+	if changed == 0 {
+		return
+	}
+	if *dryRun {
+		fmt.Println("   (dry-run) not writing site.json")
+		return
+	}
+	if err := writeSite(g.sitePath, raw, sponsors, true); err != nil {
+		fatal("writing updated site.json", err)
+	}
+	fmt.Printf("📝 Updated only 'sponsors' in %s (backup created; other fields preserved).\n", g.sitePath)
+}
+
+func cmdLinkcheck(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "linkcheck")
+	hf := addHTTPFlags(fs, 8)
+	parseCommand(g, fs, args)
+
+	links, err := collectLinks(g.sitePath)
+	if err != nil {
+		fatal("reading content", err)
+	}
+	client := hf.liveClient()
+	fmt.Printf("🔗 Checking %d links from %s\n\n", len(links), filepath.Dir(g.sitePath))
+	checkLinks(client, links, g.publicDir, hf.concurrency)
+	if reportLinks(os.Stdout, links) {
+		os.Exit(1)
+	}
+}
+
+func cmdReport(g *globalOptions, args []string) {
+	fs := newCommandFlags(g, "report")
+	parseCommand(g, fs, args)
+
+	sponsors, _, err := readSite(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	writeReport(os.Stdout, sponsors, g.publicDir, g.logoDir())
+}
+
+// writeReport prints sponsor counts: active, missing links and logos, and
+// the spread of types and categories.
+func writeReport(w io.Writer, sponsors []Sponsor, publicDir, logoDir string) {
+	var active, noHref, noIG, noLogo, missingFile int
+	types := make(map[string]int)
+	cats := make(map[string]int)
+	for _, s := range sponsors {
+		if s.Active {
+			active++
+		}
+		if strings.TrimSpace(s.Href) == "" {
+			noHref++
+		}
+		if strings.TrimSpace(s.Instagram) == "" {
+			noIG++
+		}
+		if strings.TrimSpace(s.Logo) == "" {
+			noLogo++
+		} else if !fileExists(desiredLocalLogoPath(publicDir, logoDir, s)) {
+			missingFile++
+		}
+		types[s.Type]++
+		for _, c := range s.Category {
+			cats[c]++
+		}
+	}
+	fmt.Fprintf(w, "Sponsors:            %d (%d active)\n", len(sponsors), active)
+	fmt.Fprintf(w, "  missing href:      %d\n", noHref)
+	fmt.Fprintf(w, "  missing instagram: %d\n", noIG)
+	fmt.Fprintf(w, "  no logo:           %d\n", noLogo)
+	fmt.Fprintf(w, "  logo file missing: %d\n", missingFile)
+	printCounts := func(title string, m map[string]int) {
+		fmt.Fprintf(w, "%s:\n", title)
+		names := make([]string, 0, len(m))
+		for k := range m {
+			names = append(names, k)
+		}
+		sort.Slice(names, func(i, j int) bool {
+			if m[names[i]] != m[names[j]] {
+				return m[names[i]] > m[names[j]]
+			}
+			return names[i] < names[j]
+		})
+		for _, k := range names {
+			label := k
+			if label == "" {
+				label = "(empty)"
+			}
+			fmt.Fprintf(w, "  %-18s %d\n", label, m[k])
+		}
+	}
+	printCounts("Types", types)
+	printCounts("Categories", cats)
+}
+
+// ---- sponsor processing ----
+
+// runOptions holds the settings shared by every sponsor worker.
+type runOptions struct {
+	publicDir  string
+	logoDir    string
+	dryRun     bool
+	enrichOnly bool // sponsors enrich: look up links, skip logos
+	explain    bool
+	gate       imageGate
+	normalize  normalizeConfig
+	pngSize    int // PNG companion size for SVG logos; 0 = off
+	client     *http.Client
+	cache      *diskCache // resolved logo URLs; nil = none
+	ua         string
+	econf      EnrichConfig
+	stage      *staging // -review: where written files wait for approval; nil = in place
+}
+
+// Per-sponsor outcomes, tallied into the run summary.
+const (
+	outcomeNone = iota
+	outcomeSaved
+	outcomeOK
+	outcomeFailed
+)
+
+// sponsorResult is what a worker hands back for one sponsor. Console output is
+// buffered in out so that concurrent sponsors never interleave their lines.
+type sponsorResult struct {
+	index   int // position in sponsors
+	pos     int // position in the work list
+	sponsor Sponsor
+	changed bool
+	outcome int
+	logoURL string // image a new logo was downloaded from
+	out     bytes.Buffer
+}
+
+// processSponsors runs processSponsor for each index in work using up to
+// concurrency goroutines. Results are delivered in the order of work, so the
+// console output reads the same as a sequential run.
+func processSponsors(opts *runOptions, sponsors []Sponsor, work []int, concurrency int) <-chan *sponsorResult {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan int)
+	done := make(chan *sponsorResult)
+	ordered := make(chan *sponsorResult)
+
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for pos := range jobs {
+				res := processSponsor(opts, sponsors[work[pos]])
+				res.index, res.pos = work[pos], pos
+				done <- res
+			}
+		}()
+	}
+	go func() {
+		for pos := range work {
+			jobs <- pos
+		}
+		close(jobs)
+		wg.Wait()
+		close(done)
+	}()
+
+	// Reorder: hold finished sponsors until everything before them is out.
+	go func() {
+		pending := make(map[int]*sponsorResult)
+		next := 0
+		for res := range done {
+			pending[res.pos] = res
+			for {
+				r, ok := pending[next]
+				if !ok {
+					break
+				}
+				delete(pending, next)
+				ordered <- r
+				next++
+			}
+		}
+		close(ordered)
+	}()
+	return ordered
+}
+
+// processSponsor enriches, discovers and downloads the logo for a single
+// sponsor (only enriches, with opts.enrichOnly). It works on its own copy of
+// s and never touches shared state.
+func processSponsor(opts *runOptions, s Sponsor) *sponsorResult {
+	res := &sponsorResult{sponsor: s}
+	out := &res.out
+
+	sponsorStart := time.Now()
+	dbg("sponsor=%q href=%q logo=%q active=%v cats=%v", s.Name, s.Href, s.Logo, s.Active, s.Category)
+
+	// Look up only the blank fields the enrichment policy covers.
+	needHref := strings.TrimSpace(s.Href) == "" && opts.econf.wantsHref()
+	needIG := strings.TrimSpace(s.Instagram) == "" && opts.econf.wantsInstagram()
+	shouldEnrich := needHref || needIG
+	// The homepage is fetched at most once; enrichment and discovery share it.
+	var page *homePage
+	if shouldEnrich {
+		if needIG && strings.TrimSpace(s.Href) != "" {
+			page = fetchHomePage(opts.client, opts.ua, s.Href)
+		}
+		tEnrich := time.Now()
+		fmt.Fprintf(out, "enrich start: provider=%s needHref=%v needIG=%v\n", opts.econf.Provider, needHref, needIG)
+		foundHref, foundIG, err := enrichSponsor(opts.client, opts.ua, &s, page, opts.econf)
+		fmt.Fprintf(out, "enrich done in %s → href=%q ig=%q err=%v\n", time.Since(tEnrich), foundHref, foundIG, err)
+		if err != nil {
+			fmt.Fprintf(out, "   (enrich warn) %s: %v\n", s.Name, err)
+		}
+		if needHref && strings.TrimSpace(foundHref) != "" {
+			res.sponsor.Href = foundHref
+			res.changed = true
+			fmt.Fprintf(out, "   🔗 set href → %s\n", foundHref)
+		}
+		// Store the handle, not the profile URL
+		if needIG && strings.TrimSpace(foundIG) != "" {
+			res.sponsor.Instagram = igHandle(foundIG)
+			res.changed = true
+			fmt.Fprintf(out, "   📸 set instagram → @%s\n", res.sponsor.Instagram)
+		}
+	}
+
+	if opts.enrichOnly {
+		switch {
+		case res.changed:
+			res.outcome = outcomeSaved
+		case shouldEnrich:
+			fmt.Fprintf(out, "⚠️  %-30s nothing found\n", s.Name)
+			res.outcome = outcomeFailed
+		default:
+			fmt.Fprintf(out, "✅ %-30s nothing to look up\n", s.Name)
+			res.outcome = outcomeOK
+		}
+		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+		return res
+	}
+
+	// Determine intended local path
+	localPath := desiredLocalLogoPath(opts.publicDir, opts.logoDir, s)
+	if opts.stage != nil && strings.TrimSpace(s.Logo) != "" {
+		if err := opts.stage.seed(localPath); err != nil {
+			fmt.Fprintf(out, "   ⚠️  staging %s: %v\n", s.Logo, err)
+		}
+	}
+
+	// If logo path exists & file present → skip
+	if strings.TrimSpace(s.Logo) != "" && fileExists(localPath) {
+		fmt.Fprintf(out, "✅ %-30s logo OK → %s\n", s.Name, rel(localPath))
+		if opts.normalize.Enable && !opts.dryRun {
+			normalizeSponsorLogo(opts, res, localPath, false)
+		}
+		if !opts.dryRun {
+			logoPNGCompanion(opts, res, localPath)
+		}
+		res.outcome = outcomeOK
+		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+		return res
+	}
+
+	if strings.TrimSpace(s.Href) == "" {
+		fmt.Fprintf(out, "⚠️  %-30s no href to discover logo; logo path set=%t\n", s.Name, strings.TrimSpace(s.Logo) != "")
+		res.outcome = outcomeFailed
+		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+		return res
+	}
+
+	fmt.Fprintf(out, "→ %-30s discovering logo from %s\n", s.Name, s.Href)
+	if opts.dryRun {
+		fmt.Fprintln(out, "   (dry-run) skipping discovery/download")
+		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+		return res
+	}
+
+	var explain io.Writer
+	if opts.explain {
+		explain = out
+	}
+	fetch := func(imgURL string) (*logoImage, error) {
+		img, err := fetchImage(opts.client, opts.ua, s.Href, imgURL, opts.gate)
+		if err != nil {
+			fmt.Fprintf(out, "   ✖ skipped %s: %v\n", displayURL(imgURL), err)
+		}
+		return img, err
+	}
+	// A logo URL resolved on an earlier run is tried first; discovery runs
+	// only if it no longer downloads (or to show the ranking with -explain).
+	var img *logoImage
+	var err error
+	var known logoURL
+	if !opts.explain && opts.cache.lookup(cacheLogoURL, s.Href, &known) {
+		if img, _ = fetch(known.URL); img != nil {
+			fmt.Fprintf(out, "   (cached logo URL %s)\n", displayURL(known.URL))
+		}
+	}
+	if img == nil {
+		img, err = discoverLogoURL(opts.client, opts.ua, s.Href, page, explain, fetch)
+		if err == nil {
+			opts.cache.store(cacheLogoURL, s.Href, logoURL{URL: img.URL, Ext: img.Info.ext()})
+		}
+	}
+	if err != nil {
+		fmt.Fprintf(out, "   ✖ discovery failed: %v\n", err)
+		res.outcome = outcomeFailed
+		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+		return res
+	}
+	res.logoURL = img.URL
+	// Keep a chosen basename; writeImage sets the extension to the real format.
+	want := filepath.Join(opts.logoDir, slugify(s.Name)+".png")
+	if strings.TrimSpace(s.Logo) != "" {
+		want = localPath
+	}
+	target, err := writeImage(img, want)
+	if err != nil {
+		fmt.Fprintf(out, "   ✖ writing %s: %v\n", rel(want), err)
+		res.outcome = outcomeFailed
+		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+		return res
+	}
+	if info := img.Info; info.Format != "svg" && min(info.Width, info.Height) < opts.gate.MinSide {
+		fmt.Fprintf(out, "   ⚠️  only a small favicon was usable (%dx%d); replace it with a proper logo when you can\n", info.Width, info.Height)
+	}
+
+	// Point the sponsor at the file we actually wrote
+	var sitePath string
+	if strings.TrimSpace(s.Logo) != "" {
+		sitePath = replaceExt(s.Logo, filepath.Ext(target))
+	} else {
+		sitePath = "/images/logos/" + filepath.Base(target)
+	}
+	if sitePath != s.Logo {
+		res.sponsor.Logo = sitePath
+		res.changed = true
+	}
+	fmt.Fprintf(out, "   ✅ saved: %s\n", rel(target))
+	if opts.normalize.Enable {
+		normalizeSponsorLogo(opts, res, target, true)
+	}
+	logoPNGCompanion(opts, res, target)
+	res.outcome = outcomeSaved
+	dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+	return res
+}
+
+// logoURL is a cached discovery result: the image that was picked for a
+// sponsor's href.
+type logoURL struct {
+	URL string `json:"url"`
+	Ext string `json:"ext"`
+}
+
+// normalizeSponsorLogo runs the normalization stage on a sponsor's logo file
+// and repoints the sponsor if the re-encoded file has a new extension.
+func normalizeSponsorLogo(opts *runOptions, res *sponsorResult, path string, fresh bool) {
+	out, err := normalizeLogo(path, opts.normalize, fresh)
+	if errors.Is(err, errSkipNormalize) {
+		dbg("normalize %s: %v", path, err)
+		return
+	}
+	if err != nil {
+		fmt.Fprintf(&res.out, "   ⚠️  normalize failed: %v\n", err)
+		return
+	}
+	fmt.Fprintf(&res.out, "   🪄 normalized: %s\n", rel(out))
+	if out != path && strings.TrimSpace(res.sponsor.Logo) != "" {
+		res.sponsor.Logo = replaceExt(res.sponsor.Logo, filepath.Ext(out))
+		res.changed = true
+	}
+}
+
+// logoPNGCompanion keeps a PNG rendering next to an SVG logo (social cards
+// and email can't embed SVG) and records it in the sponsor's logoPng. The PNG
+// is re-rendered when missing or older than the SVG.
+func logoPNGCompanion(opts *runOptions, res *sponsorResult, path string) {
+	if !strings.EqualFold(filepath.Ext(path), ".svg") {
+		if res.sponsor.LogoPng != "" {
+			// The logo is a raster now; it serves as its own PNG.
+			res.sponsor.LogoPng = ""
+			res.changed = true
+		}
+		return
+	}
+	if opts.pngSize <= 0 {
+		return
+	}
+	pngPath := replaceExt(path, ".png")
+	if !isNewer(pngPath, path) {
+		if err := renderSVGToPNG(path, pngPath, opts.pngSize); err != nil {
+			// A PNG saved there by hand is picked up on the next run.
+			fmt.Fprintf(&res.out, "   ⚠️  PNG companion failed: %v; save one by hand as %s\n", err, rel(pngPath))
+			return
+		}
+		fmt.Fprintf(&res.out, "   🖼  PNG companion: %s\n", rel(pngPath))
+	}
+	if sitePath := replaceExt(res.sponsor.Logo, ".png"); res.sponsor.Logo != "" && res.sponsor.LogoPng != sitePath {
+		res.sponsor.LogoPng = sitePath
+		res.changed = true
+	}
+}
+
+// renderSVGToPNG rasterizes the SVG at src to a PNG at dst whose longer side
+// is size pixels.
+func renderSVGToPNG(src, dst string, size int) error {
+	b, err := os.ReadFile(src)
+	if err != nil {
+		return err
+	}
+	img, err := rasterizeSVG(b, size)
+	if err != nil {
+		return err
+	}
+	var buf bytes.Buffer
+	if err := (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img); err != nil {
+		return err
+	}
+	return writeFileAtomic(dst, &buf)
+}
+
+// ---- site.json helpers ----
+
+// readSite returns the sponsors in the site.json at path and the file's
+// bytes, which writeSite patches.
+func readSite(path string) (sponsors []Sponsor, raw []byte, err error) {
+	site, raw, err := content.LoadSite(path)
+	if err != nil {
+		return nil, nil, err
+	}
+	return site.Sponsors, raw, nil
+}
+
+// writeSite backs up path (whose contents are original) and saves sponsors
+// into it with content.Save: only the values that changed are rewritten,
+// and everything else in the file stays byte-for-byte. With dropEmpty,
+// sponsor fields holding "" are removed as well; the rest of the file keeps
+// its empty strings.
+func writeSite(path string, original []byte, sponsors []Sponsor, dropEmpty bool) error {
+	var site content.SiteContent
+	if err := json.Unmarshal(original, &site); err != nil {
+		return err
+	}
+	site.Sponsors = sponsors
+	scope := ""
+	if dropEmpty {
+		scope = "/sponsors"
+	}
+	return content.Save(path, original, &site, scope)
+}
+
+// sponsorField is one JSON field of a Sponsor: its key, its value, and the
+// value's compact encoding for comparison (nil for an empty omitempty field).
+type sponsorField struct {
+	key  string
+	v    interface{}
+	json []byte
+}
+
+// sponsorFields lists s's JSON fields in struct order.
+func sponsorFields(s Sponsor) ([]sponsorField, error) {
+	t, v := reflect.TypeOf(s), reflect.ValueOf(s)
+	fields := make([]sponsorField, 0, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		name := content.JSONKey(t.Field(i))
+		if name == "" {
+			continue
+		}
+		_, opts, _ := strings.Cut(t.Field(i).Tag.Get("json"), ",")
+		f := sponsorField{key: name, v: v.Field(i).Interface()}
+		if !(strings.Contains(opts, "omitempty") && v.Field(i).IsZero()) {
+			b, err := json.Marshal(f.v)
+			if err != nil {
+				return nil, err
+			}
+			f.json = b
+		}
+		fields = append(fields, f)
+	}
+	return fields, nil
+}
+
+// ---- review (staged site.json changes) ----
+
+// patchOp is one RFC 6902 JSON Patch operation on site.json. The members
+// after Path are context for the reviewer; JSON Patch tools ignore them.
+type patchOp struct {
+	Op      string          `json:"op"`   // add | replace | remove
+	Path    string          `json:"path"` // /sponsors/<index>/<field>
+	Value   json.RawMessage `json:"value,omitempty"`
+	Sponsor string          `json:"sponsor"`
+	Old     json.RawMessage `json:"old,omitempty"`     // value the change was proposed against
+	Source  string          `json:"source,omitempty"`  // logo: the image URL it was downloaded from
+	Preview string          `json:"preview,omitempty"` // logo: the downloaded file, to look at
+	// Files are staged files (logo, PNG companion, original) to move into
+	// place, by destination, when the operation is applied.
+	Files map[string]string `json:"files,omitempty"`
+
+	index int
+	field string
+}
+
+// diffSponsors returns the patch turning before into after, one operation
+// per changed field. The slices are parallel.
+func diffSponsors(before, after []Sponsor) ([]patchOp, error) {
+	var ops []patchOp
+	for i := range after {
+		oldFields, err := sponsorFields(before[i])
+		if err != nil {
+			return nil, err
+		}
+		newFields, err := sponsorFields(after[i])
+		if err != nil {
+			return nil, err
+		}
+		for j, f := range newFields {
+			old := oldFields[j].json
+			if bytes.Equal(f.json, old) {
+				continue
+			}
+			op := patchOp{
+				Op:      "replace",
+				Path:    fmt.Sprintf("/sponsors/%d/%s", i, f.key),
+				Value:   f.json,
+				Sponsor: before[i].Name,
+				Old:     old,
+				index:   i,
+				field:   f.key,
+			}
+			switch {
+			case f.json == nil:
+				op.Op = "remove"
+			case old == nil:
+				op.Op = "add"
+			}
+			ops = append(ops, op)
+		}
+	}
+	return ops, nil
+}
+
+// parsePatchPath splits /sponsors/<index>/<field>.
+func parsePatchPath(p string) (index int, field string, err error) {
+	parts := strings.Split(p, "/")
+	if len(parts) != 4 || parts[0] != "" || parts[1] != "sponsors" {
+		return 0, "", fmt.Errorf("unsupported path %q (want /sponsors/<index>/<field>)", p)
+	}
+	index, err = strconv.Atoi(parts[2])
+	if err != nil || index < 0 {
+		return 0, "", fmt.Errorf("bad sponsor index in %q", p)
+	}
+	return index, parts[3], nil
+}
+
+// applyPatch applies ops to sponsors in place, moves each applied
+// operation's staged files into place, and then calls save (if any operation
+// applied) to write the result. An operation whose sponsor was renamed or
+// moved, whose field no longer holds the value it was proposed against, or
+// whose staged files are gone, is a conflict: it is skipped and described in
+// the returned list. Nothing changes unless everything succeeds: every
+// operation is checked before any file moves, and the moved files go back
+// if a move or save fails.
+func applyPatch(sponsors []Sponsor, ops []patchOp, save func() error) (applied int, conflicts []string, err error) {
+	next := append([]Sponsor(nil), sponsors...)
+	files := make(map[string]string)
+	for _, op := range ops {
+		i, field, err := parsePatchPath(op.Path)
+		if err != nil {
+			return 0, nil, err
+		}
+		if i >= len(next) || next[i].Name != op.Sponsor {
+			conflicts = append(conflicts, fmt.Sprintf("%s: sponsor %q is no longer at index %d", op.Path, op.Sponsor, i))
+			continue
+		}
+		m := make(map[string]json.RawMessage)
+		b, _ := json.Marshal(next[i])
+		json.Unmarshal(b, &m)
+		if !sameJSON(m[field], op.Old) {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s): now %s, patch expects %s", op.Path, op.Sponsor, orNone(m[field]), orNone(op.Old)))
+			continue
+		}
+		if missing := missingFiles(op.Files); len(missing) > 0 {
+			conflicts = append(conflicts, fmt.Sprintf("%s (%s): staged %s no longer exists", op.Path, op.Sponsor, strings.Join(missing, ", ")))
+			continue
+		}
+		switch op.Op {
+		case "add", "replace":
+			m[field] = op.Value
+		case "remove":
+			delete(m, field)
+		default:
+			return 0, nil, fmt.Errorf("%s: unsupported op %q", op.Path, op.Op)
+		}
+		b, _ = json.Marshal(m)
+		var s Sponsor
+		if err := json.Unmarshal(b, &s); err != nil {
+			return 0, nil, fmt.Errorf("%s: %w", op.Path, err)
+		}
+		for src, dst := range op.Files {
+			files[src] = dst
+		}
+		next[i] = s
+		applied++
+	}
+
+	var moved []*published
+	undo := func() {
+		for i := len(moved) - 1; i >= 0; i-- {
+			if err := moved[i].undo(); err != nil {
+				fmt.Fprintf(os.Stderr, "⚠️  couldn't move %s back: %v\n", moved[i].dst, err)
+			}
+		}
+	}
+	for _, src := range sortedStrings(files) {
+		p, err := publishFile(src, files[src])
+		if err != nil {
+			undo()
+			return 0, nil, err
+		}
+		moved = append(moved, p)
+	}
+	old := append([]Sponsor(nil), sponsors...)
+	copy(sponsors, next)
+	if save != nil && applied > 0 {
+		if err := save(); err != nil {
+			copy(sponsors, old)
+			undo()
+			return 0, nil, err
+		}
+	}
+	for _, p := range moved {
+		p.done()
+	}
+	return applied, conflicts, nil
+}
+
+// sameJSON reports whether a and b encode the same value; missing, null,
+// and "" all count as empty.
+func sameJSON(a, b json.RawMessage) bool {
+	var va, vb interface{}
+	json.Unmarshal(a, &va)
+	json.Unmarshal(b, &vb)
+	if va == "" {
+		va = nil
+	}
+	if vb == "" {
+		vb = nil
+	}
+	return reflect.DeepEqual(va, vb)
+}
+
+func orNone(v json.RawMessage) string {
+	if len(v) == 0 || string(v) == `""` || string(v) == "null" {
+		return "(none)"
+	}
+	return string(v)
+}
+
+// reviewOps asks about each operation on out, reading answers from in, and
+// returns the accepted ones (edited values included). End of input rejects
+// whatever is left.
+func reviewOps(in io.Reader, out io.Writer, ops []patchOp) []patchOp {
+	r := bufio.NewReader(in)
+	ask := func(prompt string) (string, bool) {
+		fmt.Fprint(out, prompt)
+		line, err := r.ReadString('\n')
+		if err != nil && line == "" {
+			return "", false
+		}
+		return strings.TrimSpace(line), true
+	}
+	var accepted []patchOp
+	all := false
+	for n, op := range ops {
+		fmt.Fprintf(out, "\n[%d/%d] %s · %s\n", n+1, len(ops), op.Sponsor, op.field)
+		fmt.Fprintf(out, "   - %s\n   + %s\n", orNone(op.Old), orNone(op.Value))
+		if op.Source != "" {
+			fmt.Fprintf(out, "   from %s\n", op.Source)
+		}
+		if op.Preview != "" {
+			fmt.Fprintf(out, "   preview %s\n", op.Preview)
+		}
+		if all {
+			accepted = append(accepted, op)
+			continue
+		}
+		for decided := false; !decided; {
+			answer, ok := ask("   [a]ccept, [r]eject, [e]dit, accept [A]ll, [q]uit? ")
+			decided = true
+			switch {
+			case !ok || answer == "q":
+				return accepted
+			case answer == "a" || answer == "y":
+				accepted = append(accepted, op)
+			case answer == "A":
+				all = true
+				accepted = append(accepted, op)
+			case answer == "r" || answer == "n" || answer == "":
+			case answer == "e":
+				text, ok := ask("   new value: ")
+				if !ok {
+					return accepted
+				}
+				v, err := editedValue(op, text)
+				if err != nil {
+					fmt.Fprintf(out, "   ✖ %v\n", err)
+					decided = false
+					continue
+				}
+				op.Value = v
+				if op.Op == "remove" {
+					op.Op = "add"
+				}
+				accepted = append(accepted, op)
+			default:
+				decided = false
+			}
+		}
+	}
+	return accepted
+}
+
+// editedValue turns what the reviewer typed into the operation's new value:
+// plain text for string fields, JSON for anything else.
+func editedValue(op patchOp, text string) (json.RawMessage, error) {
+	sample := op.Value
+	if len(sample) == 0 {
+		sample = op.Old
+	}
+	if len(sample) == 0 || sample[0] == '"' {
+		return json.Marshal(text)
+	}
+	if !json.Valid([]byte(text)) {
+		return nil, fmt.Errorf("not valid JSON: %s", text)
+	}
+	return json.RawMessage(text), nil
+}
+
+// reviewFlags stage a run's site.json changes for approval instead of
+// writing them.
+type reviewFlags struct {
+	review bool
+	patch  string
+	apply  string
+}
+
+func addReviewFlags(fs *flag.FlagSet) *reviewFlags {
+	r := &reviewFlags{}
+	fs.BoolVar(&r.review, "review", false, "Accept, reject, or edit each site.json change at a prompt before writing")
+	fs.StringVar(&r.patch, "patch", "", "With -review: write the changes to this JSON Patch file instead of prompting")
+	fs.StringVar(&r.apply, "apply", "", "Apply a reviewed JSON Patch file to site.json and exit")
+	return r
+}
+
+// cmdApplyPatch applies a patch file written by -review -patch.
+func cmdApplyPatch(g *globalOptions, path string) {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		fatal("reading patch", err)
+	}
+	var ops []patchOp
+	if err := json.Unmarshal(b, &ops); err != nil {
+		fatal("parsing patch", err)
+	}
+	sponsors, raw, err := readSite(g.sitePath)
+	if err != nil {
+		fatal("reading site.json", err)
+	}
+	applied, conflicts, err := applyPatch(sponsors, ops, func() error {
+		return writeSite(g.sitePath, raw, sponsors, false)
+	})
+	if err != nil {
+		fatal("applying patch", err)
+	}
+	for _, c := range conflicts {
+		fmt.Printf("⚠️  skipped %s\n", c)
+	}
+	fmt.Printf("Applied %d of %d changes from %s\n", applied, len(ops), path)
+	if applied > 0 {
+		fmt.Printf("📝 Updated %s (backup created).\n", g.sitePath)
+	}
+	if len(conflicts) > 0 {
+		os.Exit(1)
+	}
+}
+
+// staging holds the files a -review run writes until their changes are
+// approved. The stage mirrors publicDir and the originals directory, so a
+// run works in it as it would in place, and nothing deployed changes
+// before applyPatch moves an approved operation's files in.
+type staging struct {
+	dir       string
+	publicDir string // the real directories
+	originals string
+}
+
+// staged returns a copy of opts that writes into a new stage beside the
+// public directory (logos/review/<time>), like the trash.
+func (opts *runOptions) staged() *runOptions {
+	st := &staging{
+		dir:       filepath.Join(filepath.Dir(filepath.Clean(opts.publicDir)), "logos", "review", time.Now().Format("20060102-150405")),
+		publicDir: opts.publicDir,
+		originals: opts.normalize.Originals,
+	}
+	o := *opts
+	o.stage = st
+	o.publicDir = st.path(opts.publicDir)
+	o.logoDir = st.path(opts.logoDir)
+	if o.normalize.Originals != "" {
+		o.normalize.Originals = st.path(opts.normalize.Originals)
+	}
+	return &o
+}
+
+// path maps a path in the real public or originals directory into the
+// stage.
+func (st *staging) path(p string) string {
+	if r, ok := within(st.originals, p); ok && st.originals != "" {
+		return filepath.Join(st.dir, originalsDirName, r)
+	}
+	if r, ok := within(st.publicDir, p); ok {
+		return filepath.Join(st.dir, "public", r)
+	}
+	return p
+}
+
+// real is the inverse of path.
+func (st *staging) real(p string) string {
+	if r, ok := within(filepath.Join(st.dir, originalsDirName), p); ok {
+		return filepath.Join(st.originals, r)
+	}
+	if r, ok := within(filepath.Join(st.dir, "public"), p); ok {
+		return filepath.Join(st.publicDir, r)
+	}
+	return p
+}
+
+// within returns p relative to dir, if p is dir or inside it.
+func within(dir, p string) (string, bool) {
+	r, err := filepath.Rel(dir, p)
+	if err != nil || r == ".." || strings.HasPrefix(r, ".."+string(filepath.Separator)) {
+		return "", false
+	}
+	return r, true
+}
+
+// seed copies a logo's live file, its PNG companion, and its original into
+// the stage, so checks, normalization, and rendering see them there.
+func (st *staging) seed(logo string) error {
+	live := st.real(logo)
+	stem := strings.TrimSuffix(filepath.Base(live), filepath.Ext(live))
+	srcs := []string{live, replaceExt(live, ".png")}
+	if st.originals != "" {
+		origs, _ := filepath.Glob(filepath.Join(st.originals, globEscape(stem)+".*"))
+		srcs = append(srcs, origs...)
+	}
+	for _, src := range srcs {
+		dst := st.path(src)
+		if !fileExists(src) || fileExists(dst) {
+			continue
+		}
+		if err := copyFile(src, dst); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// files lists the staged files an operation setting a logo (or logoPng) to
+// the staged file p carries: p and, for a logo, its original, each unless
+// the live copy is already the same.
+func (st *staging) files(p string, logo bool) map[string]string {
+	srcs := []string{p}
+	if logo {
+		stem := strings.TrimSuffix(filepath.Base(p), filepath.Ext(p))
+		origs, _ := filepath.Glob(filepath.Join(st.dir, originalsDirName, globEscape(stem)+".*"))
+		srcs = append(srcs, origs...)
+	}
+	files := make(map[string]string)
+	for _, src := range srcs {
+		if !fileExists(src) {
+			continue
+		}
+		dst := st.real(src)
+		a, _ := os.ReadFile(src)
+		if b, err := os.ReadFile(dst); err == nil && bytes.Equal(a, b) {
+			continue
+		}
+		files[src] = dst
+	}
+	if len(files) == 0 {
+		return nil
+	}
+	return files
+}
+
+func missingFiles(files map[string]string) []string {
+	var missing []string
+	for _, src := range sortedStrings(files) {
+		if !fileExists(src) {
+			missing = append(missing, src)
+		}
+	}
+	return missing
+}
+
+// published is a staged file publishFile moved in, with the files it
+// displaced set aside until the move is kept (done) or reverted (undo).
+type published struct {
+	src, dst string
+	aside    map[string]string // displaced file → where it waits
+}
+
+// publishFile moves the staged file src to dst, setting aside the file it
+// replaces. An original also displaces any other original of the same logo,
+// as stashOriginal does.
+func publishFile(src, dst string) (*published, error) {
+	p := &published{src: src, dst: dst, aside: make(map[string]string)}
+	displaced := []string{dst}
+	if filepath.Base(filepath.Dir(dst)) == originalsDirName {
+		stem := strings.TrimSuffix(filepath.Base(dst), filepath.Ext(dst))
+		old, _ := filepath.Glob(filepath.Join(filepath.Dir(dst), globEscape(stem)+".*"))
+		displaced = append(displaced, old...)
+	}
+	for _, f := range displaced {
+		if _, set := p.aside[f]; set || !fileExists(f) {
+			continue
+		}
+		// A dot name: prune and duplicate checks skip it meanwhile.
+		aside := filepath.Join(filepath.Dir(f), ".apply-"+filepath.Base(f))
+		if err := os.Rename(f, aside); err != nil {
+			p.restore()
+			return nil, err
+		}
+		p.aside[f] = aside
+	}
+	if err := os.MkdirAll(filepath.Dir(dst), 0o755); err != nil {
+		p.restore()
+		return nil, err
+	}
+	if err := moveFile(src, dst); err != nil {
+		p.restore()
+		return nil, err
+	}
+	return p, nil
+}
+
+// undo moves the published file back to the stage and restores what it
+// displaced.
+func (p *published) undo() error {
+	if err := moveFile(p.dst, p.src); err != nil {
+		return err
+	}
+	return p.restore()
+}
+
+// restore puts the displaced files back.
+func (p *published) restore() error {
+	var first error
+	for f, aside := range p.aside {
+		if err := os.Rename(aside, f); err != nil && first == nil {
+			first = err
+		}
+	}
+	return first
+}
+
+// done drops the displaced files.
+func (p *published) done() {
+	for _, aside := range p.aside {
+		os.Remove(aside)
+	}
+}
+
+// moveFile renames src to dst, copying across filesystems.
+func moveFile(src, dst string) error {
+	if err := os.Rename(src, dst); err == nil {
+		return nil
+	}
+	if err := copyFile(src, dst); err != nil {
+		return err
+	}
+	return os.Remove(src)
+}
+
+// copyFile copies src to dst, keeping its modification time (which decides
+// whether a PNG companion is stale).
+func copyFile(src, dst string) error {
+	f, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	fi, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if err := writeFileAtomic(dst, f); err != nil {
+		return err
+	}
+	return os.Chtimes(dst, fi.ModTime(), fi.ModTime())
+}
+
+func sortedStrings(m map[string]string) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// ---- discovery & downloading ----
+
+// homePage is a sponsor's homepage, fetched and parsed once and shared by
+// enrichment (JSON-LD sameAs) and logo discovery.
+type homePage struct {
+	href string
+	raw  []byte
+	doc  *htmlNode // nil if the fetch failed or wasn't HTML
+	base *url.URL  // href with any <base href> applied
+}
+
+func fetchHomePage(client *http.Client, ua, href string) *homePage {
+	p := &homePage{href: href}
+	base, err := url.Parse(href)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return p
+	}
+	p.base = base
+	raw, err := getHTML(client, ua, href, href)
+	if err != nil || len(raw) == 0 {
+		dbg("homepage %s: %v", href, err)
+		return p
+	}
+	p.raw, p.doc = raw, parseHTML(raw)
+	p.base = p.doc.baseURL(base)
+	return p
+}
+
+// discoverLogoURL ranks every logo candidate for href and downloads them,
+// best first, with fetch. A candidate fetch rejects (e.g. a download that
+// fails inspection) falls through to the next one. Each download is scored
+// again on what it measures (real size, transparency), and the best is
+// returned; candidates that could not beat it even if they measured
+// perfectly are not downloaded. page is href's homepage if the caller
+// already has it; nil fetches it.
+func discoverLogoURL(client *http.Client, ua, href string, page *homePage, explain io.Writer, fetch func(imgURL string) (*logoImage, error)) (*logoImage, error) {
+	dbg("discoverLogoURL: href=%s", href)
+	tDisc := time.Now()
+	base, err := url.Parse(href)
+	if err != nil || base.Scheme == "" || base.Host == "" {
+		return nil, fmt.Errorf("invalid href: %s", href)
+	}
+
+	// 1) Fetch HTML and collect every plausible logo on the page
+	var cands []logoCandidate
+	if page == nil || page.href != href {
+		page = fetchHomePage(client, ua, href)
+	}
+	if page.doc != nil {
+		cands = append(cands, pageLogoCandidates(page.doc, page.base, page.raw)...)
+	}
+
+	// 2) Add icons from the web app manifest and browserconfig.xml
+	cands = append(cands, manifestCandidates(client, ua, href, page.doc, page.base)...)
+
+	// 3) Add common icon paths (PNG/JPG/WEBP/SVG, and favicon.ico as a last resort)
+	cands = append(cands, commonPathCandidates(base)...)
+
+	// 4) Rank, download the likely ones, and keep the best by measured score
+	ranked := rankCandidates(cands)
+	dbg("discoverLogoURL: %d candidates ranked in %s", len(ranked), time.Since(tDisc))
+	if explain != nil {
+		printRanked(explain, ranked)
+	}
+	attempts := 0
+	var best *logoImage
+	bestScore := 0
+	var lastErr error
+	for _, c := range ranked {
+		if attempts >= maxLogoAttempts {
+			break
+		}
+		if best != nil && c.ceiling() <= bestScore {
+			continue
+		}
+		if c.Source == srcPath {
+			tHead := time.Now()
+			dbg("HEAD %s", c.URL)
+			if !headOKImage(client, ua, href, c.URL, isICO(c.URL)) {
+				continue
+			}
+			dbg("candidate OK %s in %s", c.URL, time.Since(tHead))
+		}
+		attempts++
+		img, err := fetch(c.URL)
+		if err != nil {
+			dbg("discoverLogoURL: %s rejected: %v", c.URL, err)
+			lastErr = err
+			continue
+		}
+		c.Measured = &img.Info
+		scoreCandidate(&c)
+		if explain != nil {
+			fmt.Fprintf(explain, "   measured %4d  %s\n              %s\n", c.Score, displayURL(c.URL), strings.Join(c.Reasons, ", "))
+		}
+		if best == nil || c.Score > bestScore {
+			best, bestScore = img, c.Score
+		}
+	}
+	if best != nil {
+		dbg("discoverLogoURL: picked %s (score %d) in %s", best.URL, bestScore, time.Since(tDisc))
+		return best, nil
+	}
+	dbg("discoverLogoURL: no image found after %s", time.Since(tDisc))
+	if lastErr != nil {
+		return nil, fmt.Errorf("no viable image found (last: %v)", lastErr)
+	}
+	return nil, errors.New("no viable image found")
+}
+
+// maxLogoAttempts caps how many candidates are downloaded per sponsor.
+const maxLogoAttempts = 6
+
+func getHTML(client *http.Client, ua, referer, pageURL string) ([]byte, error) {
+	req, _ := http.NewRequest("GET", pageURL, nil)
+	req.Header.Set("User-Agent", ua)
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	dbgDumpReq(req)
+	t := time.Now()
+	dbg("GET %s (referer=%s)", pageURL, referer)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK || !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		return nil, fmt.Errorf("status %d or non-html", resp.StatusCode)
+	}
+	dbg("GET %s status=%d ct=%q in %s", pageURL, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
+	// 2MB cap
+	b, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
+	return b, err
+}
+
+// imgSource returns the best URL for an <img>: the largest srcset entry, then
+// src and the common lazy-loading attributes. For an <img> inside <picture>,
+// the <source> elements are consulted first.
+func imgSource(n *htmlNode) string {
+	if n.parent != nil && n.parent.tag == "picture" {
+		for _, c := range n.parent.children {
+			if c.tag != "source" {
+				continue
+			}
+			if t := strings.ToLower(c.attr("type")); t != "" && !strings.HasPrefix(t, "image/") {
+				continue
+			}
+			if u := bestSrcset(c.attrOr("srcset", "data-srcset")); u != "" {
+				return u
+			}
+		}
+	}
+	if u := bestSrcset(n.attrOr("srcset", "data-srcset")); u != "" {
+		return u
+	}
+	return strings.TrimSpace(n.attrOr("src", "data-src", "data-original", "data-lazy-src"))
+}
+
+// bestSrcset picks the widest (or highest-density) candidate in a srcset.
+func bestSrcset(srcset string) string {
+	best, bestScore := "", -1.0
+	for _, part := range strings.Split(srcset, ",") {
+		f := strings.Fields(part)
+		if len(f) == 0 || strings.HasPrefix(f[0], "data:") {
+			continue
+		}
+		score := 1.0
+		if len(f) > 1 {
+			d := strings.ToLower(f[1])
+			if v, err := strconv.ParseFloat(strings.TrimRight(d, "wx"), 64); err == nil {
+				score = v
+				if strings.HasSuffix(d, "x") {
+					// Density descriptors rank below any width descriptor >= 1000px.
+					score *= 1000
+				}
+			}
+		}
+		if score > bestScore {
+			best, bestScore = f[0], score
+		}
+	}
+	return best
+}
+
+// isLogoish reports whether an element's class, id, or label mentions "logo".
+func isLogoish(n *htmlNode) bool {
+	return containsLogo(n.attr("class")) || containsLogo(n.attr("id")) || containsLogo(n.attr("aria-label"))
+}
+
+func containsLogo(s string) bool {
+	return strings.Contains(strings.ToLower(s), "logo")
+}
+
+// hasToken reports whether the space-separated list s contains tok.
+func hasToken(s, tok string) bool {
+	for _, f := range strings.Fields(strings.ToLower(s)) {
+		if f == tok {
+			return true
+		}
+	}
+	return false
+}
+
+// headOKImage reports whether u answers HEAD with 200 and an image type.
+// Icon types only count when allowICO is set.
+func headOKImage(client *http.Client, ua, referer, u string, allowICO bool) bool {
+	t := time.Now()
+	req, _ := http.NewRequest("HEAD", u, nil)
+	req.Header.Set("User-Agent", ua)
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	dbgDumpReq(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return false
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		dbg("HEAD %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
+		return false
+	}
+	ct := strings.ToLower(resp.Header.Get("Content-Type"))
+	if !strings.HasPrefix(ct, "image/") {
+		dbg("HEAD %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
+		return false
+	}
+	dbg("HEAD %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
+	return allowICO || (!strings.Contains(ct, "x-icon") && !strings.Contains(ct, "vnd.microsoft.icon"))
+}
+
+// logoImage is a downloaded logo candidate that passed the quality gate.
+type logoImage struct {
+	URL  string
+	Data []byte // sanitized (SVG) or converted (ICO) bytes, ready to write
+	Info imageInfo
+}
+
+// fetchImage fetches imgURL and inspects the bytes, returning them if they
+// pass gate.
+func fetchImage(client *http.Client, ua, referer, imgURL string, gate imageGate) (*logoImage, error) {
+	var b []byte
+	if strings.HasPrefix(imgURL, "data:") {
+		d, err := decodeDataURL(imgURL)
+		if err != nil {
+			return nil, err
+		}
+		b = d
+	} else {
+		d, err := getImage(client, ua, referer, imgURL)
+		if err != nil {
+			return nil, err
+		}
+		b = d
+	}
+	b, info, err := checkImage(b, gate)
+	if err != nil {
+		return nil, err
+	}
+	return &logoImage{URL: imgURL, Data: b, Info: info}, nil
+}
+
+// getImage downloads imgURL, refusing HTML error pages and oversized bodies.
+func getImage(client *http.Client, ua, referer, imgURL string) ([]byte, error) {
+	t := time.Now()
+	dbg("GET image %s (referer=%s)", imgURL, referer)
+	req, _ := http.NewRequest("GET", imgURL, nil)
+	req.Header.Set("User-Agent", ua)
+	if referer != "" {
+		req.Header.Set("Referer", referer)
+	}
+	dbgDumpReq(req)
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("bad status %d for %s", resp.StatusCode, imgURL)
+	}
+	ct := strings.ToLower(resp.Header.Get("Content-Type"))
+	if strings.HasPrefix(ct, "text/html") {
+		return nil, fmt.Errorf("not an image: %s", ct)
+	}
+	b, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) > maxImageBytes {
+		return nil, fmt.Errorf("image larger than %dMB", maxImageBytes>>20)
+	}
+	dbg("GET image %s status=%d ct=%q bytes=%d in %s", imgURL, resp.StatusCode, ct, len(b), time.Since(t))
+	return b, nil
+}
+
+// maxImageBytes caps a single logo download.
+const maxImageBytes = 50 << 20
+
+// checkImage inspects b and returns the bytes to save (sanitized SVG, or
+// ICO converted to PNG) with their description, or explains why it isn't an
+// acceptable logo.
+func checkImage(b []byte, gate imageGate) ([]byte, imageInfo, error) {
+	info, err := inspectImage(b)
+	if err != nil {
+		return nil, imageInfo{}, err
+	}
+	if info.Format == "svg" {
+		// Saved SVGs are served from our own domain; never keep active content.
+		if b, err = sanitizeSVG(b); err != nil {
+			return nil, imageInfo{}, fmt.Errorf("unsafe SVG rejected: %w", err)
+		}
+		if info, err = inspectImage(b); err != nil {
+			return nil, imageInfo{}, fmt.Errorf("sanitized SVG: %w", err)
+		}
+	}
+	if info.Format == "ico" {
+		if b, err = icoToPNG(b); err != nil {
+			return nil, imageInfo{}, fmt.Errorf("ICO: %w", err)
+		}
+		if info, err = inspectImage(b); err != nil {
+			return nil, imageInfo{}, fmt.Errorf("ICO frame: %w", err)
+		}
+		// Favicons are rarely 64px; a small one beats no logo at all.
+		if gate.MinICOSide > 0 && gate.MinICOSide < gate.MinSide {
+			gate.MinSide = gate.MinICOSide
+		}
+	}
+	if err := gate.check(info); err != nil {
+		return nil, imageInfo{}, err
+	}
+	dbg("image ok: %s %dx%d alpha=%v (%d bytes)", info.Format, info.Width, info.Height, info.Alpha, len(b))
+	return b, info, nil
+}
+
+// writeImage writes img to target with the extension of its real format and
+// returns the path written.
+func writeImage(img *logoImage, target string) (string, error) {
+	target = replaceExt(target, img.Info.ext())
+	if err := writeFileAtomic(target, bytes.NewReader(img.Data)); err != nil {
+		return "", err
+	}
+	return target, nil
+}
+
+// decodeDataURL returns the bytes of an inline data: image (e.g. an <svg>
+// lifted from the page header).
+func decodeDataURL(dataURL string) ([]byte, error) {
+	meta, payload, ok := strings.Cut(strings.TrimPrefix(dataURL, "data:"), ",")
+	if !ok {
+		return nil, errors.New("malformed data URL")
+	}
+	if strings.HasSuffix(strings.ToLower(meta), ";base64") {
+		b, err := base64.StdEncoding.DecodeString(payload)
+		if err != nil {
+			return nil, fmt.Errorf("data URL: %w", err)
+		}
+		return b, nil
+	}
+	d, err := url.PathUnescape(payload)
+	if err != nil {
+		return nil, fmt.Errorf("data URL: %w", err)
+	}
+	return []byte(d), nil
+}
+
+// writeFileAtomic streams r into target via a temp file and a rename, so a
+// failed download never leaves a truncated logo behind. Each call gets its
+// own temp file: sponsors sharing a slug may write the same target at once.
+func writeFileAtomic(target string, r io.Reader) error {
+	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
+		return err
+	}
+	out, err := os.CreateTemp(filepath.Dir(target), ".part-*")
+	if err != nil {
+		return err
+	}
+	tmp := out.Name()
+	defer out.Close()
+	if _, err = io.Copy(out, r); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Chmod(0o644); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := out.Close(); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return err
+	}
+	return nil
+}
+
+// ---- logo candidates & scoring ----
+
+// Candidate sources, in rough order of how much they say about "the logo".
+const (
+	srcJSONLD      = "json-ld"
+	srcItemprop    = "itemprop"
+	srcImg         = "img"
+	srcSVG         = "svg"
+	srcIcon        = "icon"
+	srcManifest    = "manifest"
+	srcJSONLDImage = "ld-image"
+	srcOG          = "og:image"
+	srcPath        = "path"
+)
+
+// logoCandidate is one image discoverLogoURL could pick, with the signals used
+// to rank it. Width/Height are declared sizes (attributes, sizes=, og:image:width)
+// and are 0 when unknown.
+type logoCandidate struct {
+	URL      string
+	Source   string
+	Alt      string
+	Logoish  bool // element or an ancestor has class/id/label mentioning "logo"
+	InChrome bool // inside <header> or <nav>
+	Width    int
+	Height   int
+	Measured *imageInfo // the downloaded image; replaces the declared size and format
+	Score    int
+	Reasons  []string
+}
+
+// pageLogoCandidates collects every image on the page that could be the logo.
+func pageLogoCandidates(doc *htmlNode, base *url.URL, raw []byte) []logoCandidate {
+	var out []logoCandidate
+	out = append(out, jsonLDCandidates(parseJSONLD(doc, base))...)
+	out = append(out, itempropCandidates(doc, base)...)
+	out = append(out, imgCandidates(doc, base)...)
+	out = append(out, inlineSVGCandidates(doc, raw)...)
+	out = append(out, iconCandidates(doc, base)...)
+	out = append(out, ogImageCandidates(doc, base)...)
+	return out
+}
+
+// ogImageCandidates returns social preview images: og:image (and its
+// secure_url/url variants) and twitter:image, with og:image:width/height.
+func ogImageCandidates(doc *htmlNode, base *url.URL) []logoCandidate {
+	var out []logoCandidate
+	w, h := 0, 0
+	for _, m := range doc.findAll("meta") {
+		prop := strings.ToLower(strings.TrimSpace(m.attrOr("property", "name")))
+		switch prop {
+		case "og:image:width":
+			w, _ = strconv.Atoi(strings.TrimSpace(m.attr("content")))
+		case "og:image:height":
+			h, _ = strconv.Atoi(strings.TrimSpace(m.attr("content")))
+		case "og:image:secure_url", "og:image", "og:image:url", "twitter:image", "twitter:image:src":
+			if u := toAbsURL(base, m.attr("content")); u != "" {
+				out = append(out, logoCandidate{URL: u, Source: srcOG})
+			}
+		}
+	}
+	// Declared dimensions describe the og:image; apply them to those entries.
+	for i := range out {
+		out[i].Width, out[i].Height = w, h
+	}
+	return out
+}
+
+// itempropCandidates returns schema.org microdata logos (itemprop="logo")
+// given on an <img>, <meta>, or <link>.
+func itempropCandidates(doc *htmlNode, base *url.URL) []logoCandidate {
+	var out []logoCandidate
+	doc.walk(func(n *htmlNode) bool {
+		if !hasToken(n.attr("itemprop"), "logo") {
+			return true
+		}
+		var v string
+		switch n.tag {
+		case "img":
+			v = imgSource(n)
+		case "meta":
+			v = n.attr("content")
+		case "link", "a":
+			v = n.attr("href")
+		}
+		if u := toAbsURL(base, v); u != "" {
+			out = append(out, logoCandidate{URL: u, Source: srcItemprop, Logoish: true, InChrome: inChrome(n)})
+		}
+		return true
+	})
+	return out
+}
+
+// iconCandidates returns <link rel="icon"> and apple-touch-icon entries with
+// their declared sizes.
+func iconCandidates(doc *htmlNode, base *url.URL) []logoCandidate {
+	var out []logoCandidate
+	for _, l := range doc.findAll("link") {
+		rel := strings.ToLower(l.attr("rel"))
+		if !hasToken(rel, "icon") && !hasToken(rel, "apple-touch-icon") && !hasToken(rel, "apple-touch-icon-precomposed") {
+			continue
+		}
+		u := toAbsURL(base, l.attr("href"))
+		if u == "" {
+			continue
+		}
+		w, h := parseSizes(l.attr("sizes"))
+		if w == 0 && strings.Contains(rel, "apple-touch-icon") {
+			w, h = 180, 180 // Apple's default touch icon size
+		}
+		out = append(out, logoCandidate{URL: u, Source: srcIcon, Width: w, Height: h})
+	}
+	return out
+}
+
+// imgCandidates returns every <img> (or <picture><source>) that mentions
+// "logo" in its URL, alt, class, or id, sits inside an element that does, or
+// appears in the header/nav.
+func imgCandidates(doc *htmlNode, base *url.URL) []logoCandidate {
+	var out []logoCandidate
+	doc.walk(func(n *htmlNode) bool {
+		if n.tag != "img" {
+			return true
+		}
+		src := imgSource(n)
+		if src == "" || strings.HasPrefix(src, "data:") {
+			return true
+		}
+		c := logoCandidate{
+			Source:   srcImg,
+			Alt:      n.attr("alt"),
+			Logoish:  isLogoish(n) || n.closest(isLogoish) != nil,
+			InChrome: inChrome(n),
+		}
+		if !c.Logoish && !c.InChrome && !containsLogo(src) && !containsLogo(c.Alt) {
+			return true
+		}
+		c.URL = toAbsURL(base, src)
+		c.Width, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(n.attr("width")), "px"))
+		c.Height, _ = strconv.Atoi(strings.TrimSuffix(strings.TrimSpace(n.attr("height")), "px"))
+		if c.URL != "" {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
+
+// inlineSVGCandidates returns data: URLs for <svg> elements inlined as the
+// site logo: ones marked up as a logo, or the home link inside the header/nav.
+func inlineSVGCandidates(doc *htmlNode, raw []byte) []logoCandidate {
+	var out []logoCandidate
+	doc.walk(func(n *htmlNode) bool {
+		if n.tag != "svg" {
+			return true
+		}
+		chrome := inChrome(n)
+		homeLink := n.closest(func(p *htmlNode) bool {
+			if p.tag != "a" {
+				return false
+			}
+			h := strings.TrimSpace(p.attr("href"))
+			return h == "/" || h == "./" || h == "#"
+		}) != nil
+		logoish := isLogoish(n) || n.closest(isLogoish) != nil
+		if !logoish && !(chrome && homeLink) {
+			return false // skip this subtree; nested <svg> is never the logo
+		}
+		markup := n.outer(raw)
+		// Sprite references and tiny glyphs don't stand alone.
+		if bytes.Contains(markup, []byte("<use")) || len(markup) < 200 {
+			return false
+		}
+		if !bytes.Contains(markup[:bytes.IndexByte(markup, '>')+1], []byte("xmlns")) {
+			markup = append([]byte(`<svg xmlns="http://www.w3.org/2000/svg"`), markup[len("<svg"):]...)
+		}
+		w, h := svgViewBoxSize(n)
+		out = append(out, logoCandidate{
+			URL:      "data:image/svg+xml;base64," + base64.StdEncoding.EncodeToString(markup),
+			Source:   srcSVG,
+			Logoish:  logoish,
+			InChrome: chrome,
+			Width:    w,
+			Height:   h,
+		})
+		return false
+	})
+	return out
+}
+
+// commonPathCandidates returns well-known icon/logo paths on the site root.
+// They are unverified and are HEAD-checked before being chosen.
+func commonPathCandidates(base *url.URL) []logoCandidate {
+	paths := []struct {
+		path string
+		size int
+	}{
+		{"/apple-touch-icon.png", 180},
+		{"/apple-touch-icon-precomposed.png", 180},
+		{"/favicon-196x196.png", 196},
+		{"/favicon-192x192.png", 192},
+		{"/favicon-180x180.png", 180},
+		{"/favicon-152x152.png", 152},
+		{"/favicon-144x144.png", 144},
+		{"/favicon-96x96.png", 96},
+		{"/favicon-64x64.png", 64},
+		{"/favicon-32x32.png", 32},
+		{"/favicon.png", 0},
+		{"/logo.png", 0},
+		{"/logo.jpg", 0},
+		{"/logo.webp", 0},
+		{"/logo.svg", 0},
+		{"/favicon.ico", 0},
+	}
+	out := make([]logoCandidate, 0, len(paths))
+	for _, p := range paths {
+		u := base.ResolveReference(&url.URL{Path: p.path}).String()
+		out = append(out, logoCandidate{URL: u, Source: srcPath, Width: p.size, Height: p.size})
+	}
+	return out
+}
+
+// rankCandidates scores each candidate, drops duplicates (keeping
+// the best-scoring copy), and sorts best first. Ties keep discovery order.
+func rankCandidates(cands []logoCandidate) []logoCandidate {
+	seen := make(map[string]int)
+	var out []logoCandidate
+	for _, c := range cands {
+		scoreCandidate(&c)
+		if i, ok := seen[c.URL]; ok {
+			if c.Score > out[i].Score {
+				out[i] = c
+			}
+			continue
+		}
+		seen[c.URL] = len(out)
+		out = append(out, c)
+	}
+	sort.SliceStable(out, func(i, j int) bool { return out[i].Score > out[j].Score })
+	return out
+}
+
+// scoreCandidate fills in Score and Reasons from the candidate's signals.
+func scoreCandidate(c *logoCandidate) {
+	c.Score, c.Reasons = 0, nil
+	add := func(pts int, why string) {
+		c.Score += pts
+		c.Reasons = append(c.Reasons, fmt.Sprintf("%+d %s", pts, why))
+	}
+
+	switch c.Source {
+	case srcJSONLD:
+		add(45, "json-ld logo")
+	case srcJSONLDImage:
+		add(10, "json-ld business image")
+	case srcItemprop:
+		add(40, "itemprop=logo")
+	case srcImg:
+		add(10, "<img>")
+	case srcSVG:
+		add(15, "inline svg")
+	case srcIcon:
+		add(10, "icon link")
+	case srcManifest:
+		add(15, "manifest/tile icon")
+	case srcOG:
+		add(5, "og/twitter image")
+	}
+
+	lowerPath := strings.ToLower(candidatePath(c.URL))
+	if strings.Contains(lowerPath, "logo") {
+		add(25, `"logo" in path`)
+	}
+	if containsLogo(c.Alt) {
+		add(15, `"logo" in alt`)
+	}
+	if c.Logoish {
+		add(15, "logo class/id")
+	}
+	if c.InChrome {
+		add(15, "in header/nav")
+	}
+	for _, w := range []string{"hero", "banner", "slide", "background", "cover", "photo", "header-bg"} {
+		if strings.Contains(lowerPath, w) {
+			add(-25, fmt.Sprintf("%q in path", w))
+			break
+		}
+	}
+
+	// Size and aspect: measured once downloaded, declared until then.
+	w, h, vector := c.Width, c.Height, false
+	if m := c.Measured; m != nil {
+		w, h, vector = m.Width, m.Height, m.Format == "svg"
+	}
+	if w > 0 && h > 0 {
+		ratio := float64(w) / float64(h)
+		switch {
+		case ratio > 6 || ratio < 1.0/6:
+			add(-20, fmt.Sprintf("extreme aspect %.1f", ratio))
+		case c.Source == srcOG && ratio > 1.7 && ratio < 2.1:
+			add(-15, "share-card aspect")
+		}
+		short := min(w, h)
+		switch {
+		case vector:
+			// A viewBox is not a pixel size; vectors scale.
+		case short < 64:
+			add(-20, fmt.Sprintf("tiny %dx%d", w, h))
+		case short >= 512:
+			add(15, fmt.Sprintf("large %dx%d", w, h))
+		case short >= 180:
+			add(10, fmt.Sprintf("%dx%d", w, h))
+		}
+	}
+
+	// Format: vectors scale cleanly and transparent logos sit on any
+	// background. Until the image is downloaded only the extension is known,
+	// and only JPEG rules out transparency. ICOs are converted to PNG but are
+	// tiny favicons, so only a last resort.
+	ext := candidateExt(c.URL)
+	if ext == ".ico" {
+		add(-60, "ico (last resort)")
+	}
+	if m := c.Measured; m != nil {
+		switch {
+		case m.Format == "svg":
+			add(15, "svg")
+		case m.Alpha:
+			add(10, "transparent")
+		default:
+			add(-10, m.Format+" (opaque)")
+		}
+		return
+	}
+	switch ext {
+	case ".svg":
+		add(15, "svg")
+	case ".jpg", ".jpeg":
+		add(-10, "jpeg (opaque)")
+	}
+}
+
+// ceiling is the score c would get if its download measured as the best
+// possible logo: large, square, and transparent.
+func (c logoCandidate) ceiling() int {
+	c.Measured = &imageInfo{Format: "png", Width: 512, Height: 512, Alpha: true}
+	scoreCandidate(&c)
+	return c.Score
+}
+
+// candidatePath returns the URL path (or "" for data: URLs).
+func candidatePath(u string) string {
+	if strings.HasPrefix(u, "data:") {
+		return ""
+	}
+	if p, err := url.Parse(u); err == nil {
+		return p.Path
+	}
+	return u
+}
+
+// candidateExt guesses the image extension from a URL or data: media type.
+func candidateExt(u string) string {
+	if strings.HasPrefix(u, "data:") {
+		mt := strings.ToLower(strings.Split(strings.TrimPrefix(u, "data:"), ";")[0])
+		return extFromContentType(strings.Split(mt, ",")[0])
+	}
+	ext := strings.ToLower(filepath.Ext(candidatePath(u)))
+	if ext == ".jpeg" {
+		return ".jpg"
+	}
+	return ext
+}
+
+// printRanked writes the ranked candidate list for -explain.
+func printRanked(w io.Writer, ranked []logoCandidate) {
+	fmt.Fprintf(w, "   candidates (%d):\n", len(ranked))
+	for i, c := range ranked {
+		fmt.Fprintf(w, "   %2d. %4d  %-9s %s\n", i+1, c.Score, c.Source, displayURL(c.URL))
+		fmt.Fprintf(w, "              %s\n", strings.Join(c.Reasons, ", "))
+	}
+}
+
+// parseSizes reads a link sizes attribute ("192x192", "16x16 32x32", "any"),
+// returning the largest declared size.
+func parseSizes(s string) (w, h int) {
+	for _, f := range strings.Fields(strings.ToLower(s)) {
+		a, b, ok := strings.Cut(f, "x")
+		if !ok {
+			continue
+		}
+		fw, _ := strconv.Atoi(a)
+		fh, _ := strconv.Atoi(b)
+		if fw*fh > w*h {
+			w, h = fw, fh
+		}
+	}
+	return w, h
+}
+
+// svgViewBoxSize returns the width/height from an <svg>'s viewBox, falling
+// back to its width/height attributes.
+func svgViewBoxSize(n *htmlNode) (w, h int) {
+	if f := strings.FieldsFunc(n.attr("viewbox"), func(r rune) bool { return r == ' ' || r == ',' }); len(f) == 4 {
+		fw, _ := strconv.ParseFloat(f[2], 64)
+		fh, _ := strconv.ParseFloat(f[3], 64)
+		return int(fw), int(fh)
+	}
+	w, _ = strconv.Atoi(strings.TrimSuffix(n.attr("width"), "px"))
+	h, _ = strconv.Atoi(strings.TrimSuffix(n.attr("height"), "px"))
+	return w, h
+}
+
+// inChrome reports whether n sits inside the page <header> or <nav>.
+func inChrome(n *htmlNode) bool {
+	return n.closest(func(p *htmlNode) bool {
+		return p.tag == "header" || p.tag == "nav" || hasToken(p.attr("role"), "banner")
+	}) != nil
+}
+
+// ---- JSON-LD (schema.org structured data) ----
+
+// jsonLDInfo is what we pull out of a page's application/ld+json blocks for
+// the business itself: its logo(s), other images, and sameAs profile links.
+type jsonLDInfo struct {
+	Logos  []string
+	Images []string
+	SameAs []string
+}
+
+// orgTypes are schema.org types (and common LocalBusiness subtypes) whose
+// "image" we trust to depict the business rather than an article or product.
+var orgTypes = map[string]bool{
+	"organization": true, "corporation": true, "localbusiness": true, "ngo": true,
+	"restaurant": true, "foodestablishment": true, "barorpub": true, "brewery": true,
+	"winery": true, "distillery": true, "cafeorcoffeeshop": true, "bakery": true,
+	"icecreamshop": true, "fastfoodrestaurant": true, "store": true, "homeandconstructionbusiness": true,
+	"plumber": true, "professionalservice": true, "realestateagent": true, "healthandbeautybusiness": true,
+	"sportsactivitylocation": true, "lodgingbusiness": true, "entertainmentbusiness": true,
+}
+
+// parseJSONLD collects logo, image, and sameAs values from every JSON-LD
+// block on the page, following @graph arrays and nested objects such as
+// "publisher". Blocks that aren't valid JSON are skipped.
+func parseJSONLD(doc *htmlNode, base *url.URL) jsonLDInfo {
+	var info jsonLDInfo
+	for _, sc := range doc.findAll("script") {
+		if !strings.Contains(strings.ToLower(sc.attr("type")), "ld+json") {
+			continue
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(strings.TrimSpace(sc.text)), &v); err != nil {
+			dbg("json-ld: skipping invalid block: %v", err)
+			continue
+		}
+		collectJSONLD(v, base, &info)
+	}
+	info.Logos = uniqueStrings(info.Logos)
+	info.Images = uniqueStrings(info.Images)
+	info.SameAs = uniqueStrings(info.SameAs)
+	return info
+}
+
+func collectJSONLD(v interface{}, base *url.URL, info *jsonLDInfo) {
+	switch t := v.(type) {
+	case []interface{}:
+		for _, e := range t {
+			collectJSONLD(e, base, info)
+		}
+	case map[string]interface{}:
+		isOrg := false
+		for _, typ := range jsonLDStrings(t["@type"]) {
+			if orgTypes[strings.ToLower(typ)] {
+				isOrg = true
+			}
+		}
+		if logo, ok := t["logo"]; ok && (isOrg || t["@type"] == nil) {
+			for _, u := range jsonLDImageURLs(logo) {
+				if abs := toAbsURL(base, u); abs != "" {
+					info.Logos = append(info.Logos, abs)
+				}
+			}
+		}
+		if isOrg {
+			for _, u := range jsonLDImageURLs(t["image"]) {
+				if abs := toAbsURL(base, u); abs != "" {
+					info.Images = append(info.Images, abs)
+				}
+			}
+			info.SameAs = append(info.SameAs, jsonLDStrings(t["sameAs"])...)
+		}
+		// Map order is random; walk keys sorted so candidates (and their
+		// ranking ties) come out the same on every run.
+		keys := make([]string, 0, len(t))
+		for k := range t {
+			if k != "logo" && k != "image" {
+				keys = append(keys, k)
+			}
+		}
+		sort.Strings(keys)
+		for _, k := range keys {
+			collectJSONLD(t[k], base, info)
+		}
+	}
+}
+
+// jsonLDImageURLs reads an image-valued property: a URL string, an
+// ImageObject ({"url": ...} or {"contentUrl": ...}), or an array of either.
+func jsonLDImageURLs(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var out []string
+		for _, e := range t {
+			out = append(out, jsonLDImageURLs(e)...)
+		}
+		return out
+	case map[string]interface{}:
+		for _, k := range []string{"url", "contentUrl", "@id"} {
+			if s, ok := t[k].(string); ok && s != "" {
+				return []string{s}
+			}
+		}
+	}
+	return nil
+}
+
+// jsonLDStrings reads a property that may be a string or an array of strings.
+func jsonLDStrings(v interface{}) []string {
+	switch t := v.(type) {
+	case string:
+		return []string{t}
+	case []interface{}:
+		var out []string
+		for _, e := range t {
+			if s, ok := e.(string); ok {
+				out = append(out, s)
+			}
+		}
+		return out
+	}
+	return nil
+}
+
+// jsonLDCandidates turns a page's JSON-LD logos and business images into
+// logo candidates.
+func jsonLDCandidates(info jsonLDInfo) []logoCandidate {
+	var out []logoCandidate
+	for _, u := range info.Logos {
+		out = append(out, logoCandidate{URL: u, Source: srcJSONLD})
+	}
+	for _, u := range info.Images {
+		out = append(out, logoCandidate{URL: u, Source: srcJSONLDImage})
+	}
+	return out
+}
+
+// instagramFromSameAs returns the first Instagram profile handle in sameAs.
+func instagramFromSameAs(sameAs []string) string {
+	for _, u := range sameAs {
+		if !strings.Contains(strings.ToLower(u), "instagram.com/") {
+			continue
+		}
+		if h := igHandle(u); h != "" && !strings.Contains(h, "/") {
+			return h
+		}
+	}
+	return ""
+}
+
+// instagramFromPage returns the Instagram handle listed in the homepage's
+// JSON-LD sameAs, if any. The page costs one plain GET (shared with logo
+// discovery), so this runs before any paid search provider.
+func instagramFromPage(page *homePage) string {
+	if page == nil || page.doc == nil {
+		return ""
+	}
+	return instagramFromSameAs(parseJSONLD(page.doc, page.base).SameAs)
+}
+
+func uniqueStrings(in []string) []string {
+	seen := make(map[string]bool, len(in))
+	out := in[:0]
+	for _, s := range in {
+		if s == "" || seen[s] {
+			continue
+		}
+		seen[s] = true
+		out = append(out, s)
+	}
+	return out
+}
+
+// ---- web app manifest & browserconfig ----
+
+// manifestPaths are the conventional manifest locations probed, in order,
+// when the page doesn't link one.
+var manifestPaths = []string{"/site.webmanifest", "/manifest.json", "/manifest.webmanifest"}
+
+// manifestCandidates fetches the site's web app manifest and browserconfig.xml
+// (the linked ones, or the conventional root paths) and returns their icons
+// as sized candidates. These usually list the largest, cleanest brand icons.
+func manifestCandidates(client *http.Client, ua, referer string, doc *htmlNode, base *url.URL) []logoCandidate {
+	var out []logoCandidate
+
+	var manifestURLs []string
+	configURL := base.ResolveReference(&url.URL{Path: "/browserconfig.xml"}).String()
+	if doc != nil {
+		for _, l := range doc.findAll("link") {
+			if hasToken(l.attr("rel"), "manifest") {
+				if u := toAbsURL(base, l.attr("href")); u != "" {
+					manifestURLs = []string{u}
+				}
+			}
+		}
+		for _, m := range doc.findAll("meta") {
+			if strings.EqualFold(m.attr("name"), "msapplication-config") {
+				c := strings.TrimSpace(m.attr("content"))
+				if strings.EqualFold(c, "none") {
+					configURL = ""
+				} else if u := toAbsURL(base, c); u != "" {
+					configURL = u
+				}
+			}
+		}
+	}
+
+	if manifestURLs == nil {
+		for _, p := range manifestPaths {
+			manifestURLs = append(manifestURLs, base.ResolveReference(&url.URL{Path: p}).String())
+		}
+	}
+	for _, manifestURL := range manifestURLs {
+		body, err := httpGET(client, ua, manifestURL, referer)
+		if err != nil {
+			continue
+		}
+		icons, err := parseManifestIcons(body, manifestURL)
+		if err != nil {
+			// Often an HTML "not found" page served with 200; try the next path.
+			dbg("manifest %s: %v", manifestURL, err)
+			continue
+		}
+		out = append(out, icons...)
+		break
+	}
+	if configURL != "" {
+		if body, err := httpGET(client, ua, configURL, referer); err == nil {
+			tiles, err := parseBrowserconfigTiles(body, configURL)
+			if err != nil {
+				dbg("browserconfig %s: %v", configURL, err)
+			}
+			out = append(out, tiles...)
+		}
+	}
+	dbg("manifest/browserconfig: %d icons", len(out))
+	return out
+}
+
+// parseManifestIcons reads the icons array of a web app manifest. Icon src
+// values are resolved against the manifest's own URL, per the spec.
+func parseManifestIcons(body []byte, manifestURL string) ([]logoCandidate, error) {
+	var m struct {
+		Icons []struct {
+			Src     string `json:"src"`
+			Sizes   string `json:"sizes"`
+			Type    string `json:"type"`
+			Purpose string `json:"purpose"`
+		} `json:"icons"`
+	}
+	if err := json.Unmarshal(body, &m); err != nil {
+		return nil, err
+	}
+	base, err := url.Parse(manifestURL)
+	if err != nil {
+		return nil, err
+	}
+	var out []logoCandidate
+	for _, ic := range m.Icons {
+		// Maskable-only icons carry safe-zone padding and often a solid fill.
+		if p := strings.ToLower(ic.Purpose); p != "" && !hasToken(p, "any") && hasToken(p, "maskable") {
+			continue
+		}
+		u := toAbsURL(base, ic.Src)
+		if u == "" {
+			continue
+		}
+		w, h := parseSizes(ic.Sizes)
+		out = append(out, logoCandidate{URL: u, Source: srcManifest, Width: w, Height: h})
+	}
+	return out, nil
+}
+
+// browserconfigTile matches IE/Edge tile element names such as
+// square310x310logo or wide310x150logo.
+var browserconfigTile = regexp.MustCompile(`(?i)^(?:square|wide)(\d+)x(\d+)logo$`)
+
+// parseBrowserconfigTiles reads the tile images from a browserconfig.xml.
+func parseBrowserconfigTiles(body []byte, configURL string) ([]logoCandidate, error) {
+	base, err := url.Parse(configURL)
+	if err != nil {
+		return nil, err
+	}
+	var out []logoCandidate
+	dec := xml.NewDecoder(bytes.NewReader(body))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return out, err
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		var w, h int
+		if m := browserconfigTile.FindStringSubmatch(se.Name.Local); m != nil {
+			w, _ = strconv.Atoi(m[1])
+			h, _ = strconv.Atoi(m[2])
+		} else if !strings.EqualFold(se.Name.Local, "TileImage") {
+			continue
+		} else {
+			w, h = 144, 144
+		}
+		for _, a := range se.Attr {
+			if strings.EqualFold(a.Name.Local, "src") {
+				if u := toAbsURL(base, a.Value); u != "" {
+					out = append(out, logoCandidate{URL: u, Source: srcManifest, Width: w, Height: h})
+				}
+			}
+		}
+	}
+	return out, nil
+}
+
+// ---- image inspection ----
+
+// imageInfo describes downloaded image bytes as sniffed from their content,
+// not from the server's Content-Type. Width/Height are 0 for an SVG without
+// a usable viewBox or size.
+type imageInfo struct {
+	Format string // png | jpeg | gif | webp | avif | svg | ico
+	Width  int
+	Height int
+	Alpha  bool // has an alpha channel or transparent color; always set for SVG
+}
+
+// imageGate is the minimum quality a downloaded logo must meet.
+type imageGate struct {
+	MinSide    int     // shortest side in pixels
+	MinICOSide int     // ICO favicons are tried last and pass down to this size (0 = MinSide)
+	MaxAspect  float64 // long side / short side
+}
+
+// inspectImage identifies the format from magic bytes and reads dimensions.
+func inspectImage(b []byte) (imageInfo, error) {
+	head := b
+	if len(head) > 512 {
+		head = head[:512]
+	}
+	switch {
+	case bytes.HasPrefix(b, []byte("\x89PNG\r\n\x1a\n")),
+		bytes.HasPrefix(b, []byte("\xff\xd8\xff")),
+		bytes.HasPrefix(b, []byte("GIF87a")), bytes.HasPrefix(b, []byte("GIF89a")):
+		cfg, format, err := image.DecodeConfig(bytes.NewReader(b))
+		if err != nil {
+			return imageInfo{}, fmt.Errorf("corrupt image: %w", err)
+		}
+		info := imageInfo{Format: format, Width: cfg.Width, Height: cfg.Height, Alpha: hasAlpha(cfg.ColorModel)}
+		if format == "gif" {
+			// The transparent index is set per frame, past the header.
+			if img, err := gif.Decode(bytes.NewReader(b)); err == nil {
+				info.Alpha = hasAlpha(img.ColorModel())
+			}
+		}
+		return info, nil
+	case len(b) >= 12 && string(b[0:4]) == "RIFF" && string(b[8:12]) == "WEBP":
+		w, h, alpha, err := webpSize(b)
+		return imageInfo{Format: "webp", Width: w, Height: h, Alpha: alpha}, err
+	case len(b) >= 12 && string(b[4:8]) == "ftyp" && (string(b[8:12]) == "avif" || string(b[8:12]) == "avis"):
+		w, h := avifSize(b)
+		return imageInfo{Format: "avif", Width: w, Height: h}, nil
+	case bytes.HasPrefix(b, []byte{0, 0, 1, 0}):
+		return imageInfo{Format: "ico"}, nil
+	}
+
+	text := bytes.ToLower(bytes.TrimSpace(bytes.TrimPrefix(head, []byte("\xef\xbb\xbf"))))
+	if bytes.HasPrefix(text, []byte("<!doctype html")) || bytes.HasPrefix(text, []byte("<html")) {
+		return imageInfo{}, errors.New("HTML page served as an image")
+	}
+	if bytes.Contains(text, []byte("<svg")) {
+		w, h, err := svgSize(b)
+		return imageInfo{Format: "svg", Width: w, Height: h, Alpha: true}, err
+	}
+	return imageInfo{}, errors.New("unrecognized image format")
+}
+
+// hasAlpha reports whether images in color model m can be transparent: an
+// alpha model, or a palette with a transparent entry (PNG tRNS, GIF).
+func hasAlpha(m color.Model) bool {
+	switch m {
+	case color.NRGBAModel, color.NRGBA64Model, color.AlphaModel, color.Alpha16Model:
+		return true
+	}
+	if p, ok := m.(color.Palette); ok {
+		for _, c := range p {
+			if _, _, _, a := c.RGBA(); a != 0xffff {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+// check rejects images that are too small or too stretched to be a logo.
+func (g imageGate) check(info imageInfo) error {
+	if info.Width == 0 || info.Height == 0 {
+		if info.Format == "svg" {
+			return nil // scalable; nothing to measure
+		}
+		return fmt.Errorf("unreadable %s dimensions", info.Format)
+	}
+	short, long := info.Width, info.Height
+	if short > long {
+		short, long = long, short
+	}
+	if g.MinSide > 0 && short < g.MinSide && info.Format != "svg" {
+		return fmt.Errorf("too small: %dx%d (min %dpx)", info.Width, info.Height, g.MinSide)
+	}
+	if g.MaxAspect > 0 && float64(long)/float64(short) > g.MaxAspect {
+		return fmt.Errorf("extreme aspect ratio: %dx%d", info.Width, info.Height)
+	}
+	return nil
+}
+
+// ext returns the file extension for the sniffed format.
+func (info imageInfo) ext() string {
+	switch info.Format {
+	case "jpeg":
+		return ".jpg"
+	case "":
+		return ".png"
+	}
+	return "." + info.Format
+}
+
+// webpSize reads the canvas size and alpha flag from a VP8, VP8L, or VP8X
+// WebP header.
+func webpSize(b []byte) (w, h int, alpha bool, err error) {
+	if len(b) < 30 {
+		return 0, 0, false, errors.New("short webp")
+	}
+	data := b[20:]
+	switch string(b[12:16]) {
+	case "VP8 ":
+		if data[3] != 0x9d || data[4] != 0x01 || data[5] != 0x2a {
+			return 0, 0, false, errors.New("bad VP8 start code")
+		}
+		w = int(binary.LittleEndian.Uint16(data[6:8]) & 0x3fff)
+		h = int(binary.LittleEndian.Uint16(data[8:10]) & 0x3fff)
+	case "VP8L":
+		if data[0] != 0x2f {
+			return 0, 0, false, errors.New("bad VP8L signature")
+		}
+		bits := binary.LittleEndian.Uint32(data[1:5])
+		w = int(bits&0x3fff) + 1
+		h = int((bits>>14)&0x3fff) + 1
+		alpha = bits&(1<<28) != 0
+	case "VP8X":
+		w = int(uint32(data[4])|uint32(data[5])<<8|uint32(data[6])<<16) + 1
+		h = int(uint32(data[7])|uint32(data[8])<<8|uint32(data[9])<<16) + 1
+		alpha = data[0]&0x10 != 0
+	default:
+		return 0, 0, false, fmt.Errorf("unknown webp chunk %q", b[12:16])
+	}
+	return w, h, alpha, nil
+}
+
+// avifSize reads the first 'ispe' (image spatial extents) property, if any.
+func avifSize(b []byte) (w, h int) {
+	i := bytes.Index(b, []byte("ispe"))
+	if i < 0 || i+16 > len(b) {
+		return 0, 0
+	}
+	// box type, then 4 bytes version/flags, then width and height.
+	return int(binary.BigEndian.Uint32(b[i+8 : i+12])), int(binary.BigEndian.Uint32(b[i+12 : i+16]))
+}
+
+// svgSize reads the root element's viewBox, falling back to width/height
+// when they are plain numbers (px). It fails if the root isn't <svg>.
+func svgSize(b []byte) (w, h int, err error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.Strict = false
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			return 0, 0, fmt.Errorf("invalid svg: %w", err)
+		}
+		se, ok := tok.(xml.StartElement)
+		if !ok {
+			continue
+		}
+		if !strings.EqualFold(se.Name.Local, "svg") {
+			return 0, 0, fmt.Errorf("root element is <%s>, not <svg>", se.Name.Local)
+		}
+		var vb, ws, hs string
+		for _, a := range se.Attr {
+			switch strings.ToLower(a.Name.Local) {
+			case "viewbox":
+				vb = a.Value
+			case "width":
+				ws = a.Value
+			case "height":
+				hs = a.Value
+			}
+		}
+		if f := strings.FieldsFunc(vb, func(r rune) bool { return r == ' ' || r == ',' }); len(f) == 4 {
+			fw, _ := strconv.ParseFloat(f[2], 64)
+			fh, _ := strconv.ParseFloat(f[3], 64)
+			return int(math.Round(fw)), int(math.Round(fh)), nil
+		}
+		fw, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(ws), "px"), 64)
+		fh, _ := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(hs), "px"), 64)
+		return int(math.Round(fw)), int(math.Round(fh)), nil
+	}
+}
+
+// ---- ICO decoding ----
+
+// icoToPNG extracts the largest frame from an ICO container and returns it
+// as PNG bytes. Frames may be embedded PNGs (copied as-is) or BMP DIBs.
+func icoToPNG(b []byte) ([]byte, error) {
+	if len(b) < 6 || binary.LittleEndian.Uint16(b[0:2]) != 0 || binary.LittleEndian.Uint16(b[2:4]) != 1 {
+		return nil, errors.New("not an ICO file")
+	}
+	count := int(binary.LittleEndian.Uint16(b[4:6]))
+	if count == 0 || len(b) < 6+16*count {
+		return nil, errors.New("truncated ICO directory")
+	}
+
+	best, bestArea, bestBPP := -1, 0, 0
+	for i := 0; i < count; i++ {
+		e := b[6+16*i:]
+		w, h := int(e[0]), int(e[1])
+		if w == 0 {
+			w = 256
+		}
+		if h == 0 {
+			h = 256
+		}
+		bpp := int(binary.LittleEndian.Uint16(e[6:8]))
+		if w*h > bestArea || (w*h == bestArea && bpp > bestBPP) {
+			best, bestArea, bestBPP = i, w*h, bpp
+		}
+	}
+	e := b[6+16*best:]
+	size := int(binary.LittleEndian.Uint32(e[8:12]))
+	off := int(binary.LittleEndian.Uint32(e[12:16]))
+	if off < 0 || size <= 0 || off+size > len(b) {
+		return nil, errors.New("ICO frame out of range")
+	}
+	frame := b[off : off+size]
+
+	if bytes.HasPrefix(frame, []byte("\x89PNG\r\n\x1a\n")) {
+		return frame, nil
+	}
+	img, err := decodeICOBitmap(frame)
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+// decodeICOBitmap decodes a BMP frame as stored in an ICO: a
+// BITMAPINFOHEADER with doubled height, bottom-up XOR pixel rows, then a
+// 1-bpp AND (transparency) mask. Supports 32, 24, 8, 4, and 1 bpp.
+func decodeICOBitmap(d []byte) (*image.NRGBA, error) {
+	if len(d) < 40 {
+		return nil, errors.New("short ICO bitmap header")
+	}
+	hdr := int(binary.LittleEndian.Uint32(d[0:4]))
+	w := int(int32(binary.LittleEndian.Uint32(d[4:8])))
+	h := int(int32(binary.LittleEndian.Uint32(d[8:12]))) / 2
+	bpp := int(binary.LittleEndian.Uint16(d[14:16]))
+	colors := int(binary.LittleEndian.Uint32(d[32:36]))
+	if w <= 0 || h <= 0 || w > 1024 || h > 1024 || hdr < 40 || hdr > len(d) {
+		return nil, fmt.Errorf("unsupported ICO bitmap %dx%d", w, h)
+	}
+
+	var palette [][4]byte
+	p := hdr
+	if bpp <= 8 {
+		if colors == 0 {
+			colors = 1 << bpp
+		}
+		if p+4*colors > len(d) {
+			return nil, errors.New("truncated ICO palette")
+		}
+		for i := 0; i < colors; i++ {
+			c := d[p+4*i:]
+			palette = append(palette, [4]byte{c[2], c[1], c[0], 255})
+		}
+		p += 4 * colors
+	}
+
+	stride := ((w*bpp + 31) / 32) * 4
+	maskStride := ((w + 31) / 32) * 4
+	if p+stride*h > len(d) {
+		return nil, errors.New("truncated ICO pixels")
+	}
+	hasMask := p+stride*h+maskStride*h <= len(d)
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		row := d[p+(h-1-y)*stride:]
+		for x := 0; x < w; x++ {
+			var c [4]byte
+			switch bpp {
+			case 32:
+				c = [4]byte{row[4*x+2], row[4*x+1], row[4*x], row[4*x+3]}
+			case 24:
+				c = [4]byte{row[3*x+2], row[3*x+1], row[3*x], 255}
+			case 8, 4, 1:
+				bit := x * bpp
+				idx := int(row[bit/8]>>(8-bpp-bit%8)) & (1<<bpp - 1)
+				if idx < len(palette) {
+					c = palette[idx]
+				}
+			default:
+				return nil, fmt.Errorf("unsupported ICO bit depth %d", bpp)
+			}
+			// The AND mask marks transparent pixels for non-alpha depths.
+			if bpp != 32 && hasMask {
+				mrow := d[p+stride*h+(h-1-y)*maskStride:]
+				if mrow[x/8]&(0x80>>(x%8)) != 0 {
+					c[3] = 0
+				}
+			}
+			i := img.PixOffset(x, y)
+			copy(img.Pix[i:i+4], c[:])
+		}
+	}
+	return img, nil
+}
+
+// ---- SVG sanitization ----
+
+// svgElements are the elements a sanitized logo may contain. Anything else
+// (script, foreignObject, editor metadata, unknown tags) is dropped along
+// with its whole subtree.
+var svgElements = map[string]bool{
+	"svg": true, "g": true, "defs": true, "symbol": true, "use": true, "title": true, "desc": true,
+	"path": true, "rect": true, "circle": true, "ellipse": true, "line": true, "polyline": true, "polygon": true,
+	"text": true, "tspan": true, "textPath": true, "image": true, "style": true, "switch": true,
+	"linearGradient": true, "radialGradient": true, "stop": true, "pattern": true,
+	"clipPath": true, "mask": true, "marker": true, "filter": true,
+	"feBlend": true, "feColorMatrix": true, "feComponentTransfer": true, "feComposite": true,
+	"feDropShadow": true, "feFlood": true, "feFuncA": true, "feFuncB": true, "feFuncG": true, "feFuncR": true,
+	"feGaussianBlur": true, "feMerge": true, "feMergeNode": true, "feMorphology": true, "feOffset": true,
+}
+
+// svgAttrs are the attributes kept on those elements: geometry, presentation,
+// and paint-server/filter parameters. Event handlers (on*) never appear here.
+var svgAttrs = map[string]bool{
+	"id": true, "class": true, "style": true, "transform": true, "version": true, "lang": true,
+	"viewBox": true, "preserveAspectRatio": true, "width": true, "height": true, "x": true, "y": true,
+	"x1": true, "y1": true, "x2": true, "y2": true, "cx": true, "cy": true, "r": true, "rx": true, "ry": true,
+	"fx": true, "fy": true, "fr": true, "d": true, "points": true, "pathLength": true, "dx": true, "dy": true,
+	"rotate": true, "textLength": true, "lengthAdjust": true, "startOffset": true,
+	"fill": true, "fill-opacity": true, "fill-rule": true, "clip-rule": true, "clip-path": true, "mask": true,
+	"stroke": true, "stroke-width": true, "stroke-opacity": true, "stroke-linecap": true, "stroke-linejoin": true,
+	"stroke-miterlimit": true, "stroke-dasharray": true, "stroke-dashoffset": true, "vector-effect": true,
+	"opacity": true, "color": true, "display": true, "visibility": true, "overflow": true, "filter": true,
+	"paint-order": true, "shape-rendering": true, "text-rendering": true, "image-rendering": true,
+	"color-interpolation": true, "color-interpolation-filters": true, "mix-blend-mode": true, "isolation": true,
+	"font-family": true, "font-size": true, "font-weight": true, "font-style": true, "font-variant": true,
+	"font-stretch": true, "letter-spacing": true, "word-spacing": true, "text-anchor": true,
+	"text-decoration": true, "dominant-baseline": true, "alignment-baseline": true, "baseline-shift": true,
+	"writing-mode": true, "direction": true, "unicode-bidi": true,
+	"offset": true, "stop-color": true, "stop-opacity": true, "gradientUnits": true, "gradientTransform": true,
+	"spreadMethod": true, "patternUnits": true, "patternContentUnits": true, "patternTransform": true,
+	"clipPathUnits": true, "maskUnits": true, "maskContentUnits": true, "markerWidth": true, "markerHeight": true,
+	"markerUnits": true, "refX": true, "refY": true, "orient": true, "marker-start": true, "marker-mid": true,
+	"marker-end": true, "filterUnits": true, "primitiveUnits": true, "in": true, "in2": true, "result": true,
+	"stdDeviation": true, "mode": true, "operator": true, "k1": true, "k2": true, "k3": true, "k4": true,
+	"type": true, "values": true, "tableValues": true, "slope": true, "intercept": true, "amplitude": true,
+	"exponent": true, "radius": true, "flood-color": true, "flood-opacity": true, "lighting-color": true,
+	"enable-background": true, "href": true, "xlink:href": true, "xml:space": true, "xml:lang": true,
+}
+
+const (
+	svgNS   = "http://www.w3.org/2000/svg"
+	xlinkNS = "http://www.w3.org/1999/xlink"
+)
+
+// sanitizeSVG re-serializes an SVG keeping only allowlisted elements and
+// attributes. Scripts, event handlers, <foreignObject>, and any reference
+// that leaves the document (href, url(), @import) are removed, and a viewBox
+// is added from width/height when missing so the logo scales. It fails when
+// the input isn't a well-formed SVG or has no usable size.
+func sanitizeSVG(b []byte) ([]byte, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.Strict = false
+
+	var root []xml.Attr
+	seenRoot := false
+	var body bytes.Buffer
+	var open []string // elements written and not yet closed
+	skip := 0         // depth inside a dropped subtree
+	usesXlink := false
+	for {
+		tok, err := dec.RawToken()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid svg: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			name := rawName(t.Name)
+			if !seenRoot {
+				if name != "svg" {
+					return nil, fmt.Errorf("root element is <%s>, not <svg>", name)
+				}
+				seenRoot = true
+				root = sanitizeSVGAttrs(name, t.Attr, &usesXlink)
+				open = append(open, name)
+				continue
+			}
+			if len(open) == 0 {
+				return nil, fmt.Errorf("invalid svg: <%s> after the root element", name)
+			}
+			if skip > 0 || !svgElements[name] {
+				if skip == 0 {
+					dbg("svg: dropped <%s>", name)
+				}
+				skip++
+				continue
+			}
+			body.WriteString("<" + name)
+			for _, a := range sanitizeSVGAttrs(name, t.Attr, &usesXlink) {
+				body.WriteString(" " + rawName(a.Name) + `="`)
+				xml.EscapeText(&body, []byte(a.Value))
+				body.WriteString(`"`)
+			}
+			body.WriteString(">")
+			open = append(open, name)
+		case xml.EndElement:
+			if skip > 0 {
+				skip--
+				continue
+			}
+			if len(open) == 0 || open[len(open)-1] != rawName(t.Name) {
+				return nil, fmt.Errorf("invalid svg: unexpected </%s>", rawName(t.Name))
+			}
+			open = open[:len(open)-1]
+			if len(open) > 0 {
+				body.WriteString("</" + rawName(t.Name) + ">")
+			}
+		case xml.CharData:
+			if skip > 0 || len(open) == 0 {
+				continue
+			}
+			text := []byte(t)
+			if open[len(open)-1] == "style" {
+				css, ok := sanitizeCSS(string(t))
+				if !ok {
+					dbg("svg: dropped unsafe <style>")
+					continue
+				}
+				text = []byte(css)
+			}
+			xml.EscapeText(&body, text)
+		}
+		// Comments, processing instructions and DOCTYPEs (with any entity
+		// declarations) are never copied.
+	}
+	if !seenRoot {
+		return nil, errors.New("no <svg> element")
+	}
+	if len(open) > 0 {
+		return nil, fmt.Errorf("invalid svg: <%s> not closed", open[len(open)-1])
+	}
+
+	// Namespaces are rewritten rather than copied: svg always, xlink if used.
+	var attrs []xml.Attr
+	viewBox, w, h := "", "", ""
+	for _, a := range root {
+		switch rawName(a.Name) {
+		case "viewBox":
+			viewBox = a.Value
+		case "width":
+			w = a.Value
+		case "height":
+			h = a.Value
+		}
+		attrs = append(attrs, a)
+	}
+	if len(strings.FieldsFunc(viewBox, func(r rune) bool { return r == ' ' || r == ',' })) != 4 {
+		fw, errW := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(w), "px"), 64)
+		fh, errH := strconv.ParseFloat(strings.TrimSuffix(strings.TrimSpace(h), "px"), 64)
+		if errW != nil || errH != nil || fw <= 0 || fh <= 0 {
+			return nil, errors.New("svg has no viewBox and no pixel width/height to derive one from")
+		}
+		attrs = append(attrs, xml.Attr{Name: xml.Name{Local: "viewBox"},
+			Value: "0 0 " + strconv.FormatFloat(fw, 'f', -1, 64) + " " + strconv.FormatFloat(fh, 'f', -1, 64)})
+	}
+
+	var out bytes.Buffer
+	out.WriteString(`<svg xmlns="` + svgNS + `"`)
+	if usesXlink {
+		out.WriteString(` xmlns:xlink="` + xlinkNS + `"`)
+	}
+	for _, a := range attrs {
+		out.WriteString(" " + rawName(a.Name) + `="`)
+		xml.EscapeText(&out, []byte(a.Value))
+		out.WriteString(`"`)
+	}
+	out.WriteString(">")
+	out.Write(body.Bytes())
+	out.WriteString("</svg>\n")
+	return out.Bytes(), nil
+}
+
+// rawName joins a RawToken name back into prefix:local.
+func rawName(n xml.Name) string {
+	if n.Space == "" {
+		return n.Local
+	}
+	return n.Space + ":" + n.Local
+}
+
+// sanitizeSVGAttrs keeps allowlisted attributes whose values stay inside the
+// document. Links may only point at fragments (#id), except that <image> may
+// embed a raster data: URL.
+func sanitizeSVGAttrs(elem string, in []xml.Attr, usesXlink *bool) []xml.Attr {
+	var out []xml.Attr
+	for _, a := range in {
+		name := rawName(a.Name)
+		if !svgAttrs[name] {
+			if !strings.HasPrefix(name, "xmlns") {
+				dbg("svg: dropped <%s %s>", elem, name)
+			}
+			continue
+		}
+		v := strings.TrimSpace(a.Value)
+		switch name {
+		case "href", "xlink:href":
+			if !strings.HasPrefix(v, "#") && !(elem == "image" && isRasterDataURL(v)) {
+				dbg("svg: dropped <%s %s=%q>", elem, name, truncBytes([]byte(v), 80))
+				continue
+			}
+			if name == "xlink:href" {
+				*usesXlink = true
+			}
+		case "style":
+			css, ok := sanitizeCSS(v)
+			if !ok {
+				dbg("svg: dropped unsafe style on <%s>", elem)
+				continue
+			}
+			v = css
+		default:
+			if _, ok := sanitizeCSS(v); !ok {
+				dbg("svg: dropped <%s %s=%q>", elem, name, truncBytes([]byte(v), 80))
+				continue
+			}
+		}
+		out = append(out, xml.Attr{Name: a.Name, Value: v})
+	}
+	return out
+}
+
+// cssURL matches url(...) references in CSS or presentation attributes.
+var cssURL = regexp.MustCompile(`(?i)url\(\s*['"]?([^'")]*)`)
+
+// sanitizeCSS reports whether a stylesheet or style/presentation value is
+// safe to keep: every url() must be a fragment, nothing may import, script,
+// or bind behavior, no other image function (image-set(), cross-fade(),
+// src(), ...) may appear, and no quoted string may hold a URL. The checks
+// run on the text with comments removed and backslash escapes decoded, so
+// "u\\rl(" and "@im/**/port" don't slip past them.
+func sanitizeCSS(s string) (string, bool) {
+	plain := cssUnescape(cssComment.ReplaceAllString(s, ""))
+	lower := strings.ToLower(plain)
+	for _, bad := range []string{"@import", "javascript:", "expression(", "behavior:", "-moz-binding",
+		"image-set(", "cross-fade(", "image(", "src("} {
+		if strings.Contains(lower, bad) {
+			return "", false
+		}
+	}
+	for _, m := range cssURL.FindAllStringSubmatch(plain, -1) {
+		if !strings.HasPrefix(strings.TrimSpace(m[1]), "#") {
+			return "", false
+		}
+	}
+	for _, str := range cssStrings(plain) {
+		if cssRemoteRef.MatchString(str) {
+			return "", false
+		}
+	}
+	return s, true
+}
+
+// cssRemoteRef matches a string that starts like a URL: a scheme or "//".
+var cssRemoteRef = regexp.MustCompile(`^\s*([a-zA-Z][a-zA-Z0-9+.-]*:|//)`)
+
+// cssStrings returns the contents of the quoted strings in css.
+func cssStrings(css string) []string {
+	var out []string
+	for i := 0; i < len(css); i++ {
+		q := css[i]
+		if q != '"' && q != '\'' {
+			continue
+		}
+		j := i + 1
+		for j < len(css) && css[j] != q {
+			if css[j] == '\\' {
+				j++
+			}
+			j++
+		}
+		out = append(out, css[i+1:min(j, len(css))])
+		i = j
+	}
+	return out
+}
+
+var cssComment = regexp.MustCompile(`(?s)/\*.*?(\*/|$)`)
+
+// cssUnescape decodes CSS backslash escapes: up to six hex digits (and one
+// following space) for a code point, an escaped newline for nothing, and
+// any other escaped character for itself.
+func cssUnescape(s string) string {
+	if !strings.Contains(s, `\`) {
+		return s
+	}
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] != '\\' || i+1 == len(s) {
+			b.WriteByte(s[i])
+			continue
+		}
+		i++
+		j := i
+		for j < len(s) && j-i < 6 && isHexDigit(s[j]) {
+			j++
+		}
+		switch {
+		case j > i:
+			r, _ := strconv.ParseUint(s[i:j], 16, 32)
+			if r == 0 || r > utf8.MaxRune || (r >= 0xD800 && r <= 0xDFFF) {
+				r = utf8.RuneError
+			}
+			b.WriteRune(rune(r))
+			if j < len(s) && isHTMLSpace(s[j]) {
+				j++
+			}
+			i = j - 1
+		case s[i] == '\n' || s[i] == '\f':
+		case s[i] == '\r':
+			if i+1 < len(s) && s[i+1] == '\n' {
+				i++
+			}
+		default:
+			_, size := utf8.DecodeRuneInString(s[i:])
+			b.WriteString(s[i : i+size])
+			i += size - 1
+		}
+	}
+	return b.String()
+}
+
+func isHexDigit(c byte) bool {
+	return (c >= '0' && c <= '9') || (c >= 'a' && c <= 'f') || (c >= 'A' && c <= 'F')
+}
+
+// isRasterDataURL reports whether u is an inline PNG/JPEG/GIF/WebP image.
+func isRasterDataURL(u string) bool {
+	u = strings.ToLower(u)
+	if !strings.HasPrefix(u, "data:") {
+		return false
+	}
+	mt, _, _ := strings.Cut(strings.TrimPrefix(u, "data:"), ";")
+	switch mt {
+	case "image/png", "image/jpeg", "image/gif", "image/webp":
+		return true
+	}
+	return false
+}
+
+// ---- SVG rasterization (PNG companions) ----
+
+// The rasterizer below is deliberately small: it draws what logos are made
+// of (paths and basic shapes with solid fills and strokes, groups,
+// transforms, <use>, and class/id/tag rules from <style>). Gradients are
+// painted with the average of their stops, and text is skipped, since
+// exported logos almost always have their lettering converted to outlines.
+
+// svgNode is one element of a parsed SVG document.
+type svgNode struct {
+	name     string
+	attrs    map[string]string
+	children []*svgNode
+	text     string
+}
+
+// svgPaint is the inherited drawing state while walking the tree.
+type svgPaint struct {
+	fill, stroke               color.NRGBA
+	fillNone, strokeNone       bool
+	fillOpacity, strokeOpacity float64
+	opacity                    float64
+	strokeWidth                float64
+	evenOdd                    bool
+	color                      color.NRGBA // currentColor
+	m                          svgMatrix
+}
+
+// svgMatrix is an affine transform [a c e; b d f].
+type svgMatrix struct{ a, b, c, d, e, f float64 }
+
+var svgIdentity = svgMatrix{a: 1, d: 1}
+
+func (m svgMatrix) mul(n svgMatrix) svgMatrix {
+	return svgMatrix{
+		a: m.a*n.a + m.c*n.b, b: m.b*n.a + m.d*n.b,
+		c: m.a*n.c + m.c*n.d, d: m.b*n.c + m.d*n.d,
+		e: m.a*n.e + m.c*n.f + m.e, f: m.b*n.e + m.d*n.f + m.f,
+	}
+}
+
+func (m svgMatrix) apply(x, y float64) (float64, float64) {
+	return m.a*x + m.c*y + m.e, m.b*x + m.d*y + m.f
+}
+
+// scale is the transform's average linear scale, for stroke widths.
+func (m svgMatrix) scale() float64 {
+	return math.Sqrt(math.Abs(m.a*m.d - m.b*m.c))
+}
+
+// svgRenderer holds one rasterization: the canvas plus document lookups.
+type svgRenderer struct {
+	img   *image.RGBA
+	ids   map[string]*svgNode
+	css   []svgCSSRule
+	depth int // <use> nesting, to stop reference cycles
+}
+
+type svgCSSRule struct {
+	sel   string // ".cls", "#id", or "tag"
+	decls map[string]string
+}
+
+// rasterizeSVG renders an SVG so that its longer side is size pixels. It
+// fails on an SVG it would draw wrongly (see unsupported).
+func rasterizeSVG(b []byte, size int) (*image.RGBA, error) {
+	if size <= 0 {
+		return nil, fmt.Errorf("invalid render size %d", size)
+	}
+	root, r, err := loadSVG(b)
+	if err != nil {
+		return nil, err
+	}
+	minX, minY, vw, vh := 0.0, 0.0, 0.0, 0.0
+	if f := strings.FieldsFunc(root.attrs["viewBox"], func(r rune) bool { return r == ' ' || r == ',' }); len(f) == 4 {
+		minX, _ = strconv.ParseFloat(f[0], 64)
+		minY, _ = strconv.ParseFloat(f[1], 64)
+		vw, _ = strconv.ParseFloat(f[2], 64)
+		vh, _ = strconv.ParseFloat(f[3], 64)
+	} else {
+		vw = svgLength(root.attrs["width"])
+		vh = svgLength(root.attrs["height"])
+	}
+	if vw <= 0 || vh <= 0 {
+		return nil, errors.New("svg has no viewBox or size to render at")
+	}
+	scale := float64(size) / math.Max(vw, vh)
+	w := int(math.Max(1, math.Round(vw*scale)))
+	h := int(math.Max(1, math.Round(vh*scale)))
+	r.img = image.NewRGBA(image.Rect(0, 0, w, h))
+
+	paint := svgPaint{fill: color.NRGBA{A: 255}, strokeNone: true, fillOpacity: 1, strokeOpacity: 1,
+		opacity: 1, strokeWidth: 1, color: color.NRGBA{A: 255},
+		m: svgMatrix{a: scale, d: scale, e: -minX * scale, f: -minY * scale}}
+	r.renderChildren(root, paint)
+	return r.img, nil
+}
+
+// loadSVG parses b and indexes its ids and stylesheets for rendering. It
+// fails if the renderer can't draw the SVG faithfully.
+func loadSVG(b []byte) (*svgNode, *svgRenderer, error) {
+	root, err := parseSVGTree(b)
+	if err != nil {
+		return nil, nil, err
+	}
+	r := &svgRenderer{ids: make(map[string]*svgNode)}
+	var index func(n *svgNode)
+	index = func(n *svgNode) {
+		if id := n.attrs["id"]; id != "" {
+			r.ids[id] = n
+		}
+		if n.name == "style" {
+			r.css = append(r.css, parseSVGCSS(n.text)...)
+		}
+		for _, c := range n.children {
+			index(c)
+		}
+	}
+	index(root)
+	if err := r.unsupported(root); err != nil {
+		return nil, nil, err
+	}
+	return root, r, nil
+}
+
+// unsupported returns an error for the first element the renderer would
+// draw wrongly: text (word marks would come out blank) and anything clipped
+// or masked (it would be drawn whole).
+func (r *svgRenderer) unsupported(n *svgNode) error {
+	switch n.name {
+	case "text", "textPath", "tspan":
+		return fmt.Errorf("svg has <%s>, which the PNG renderer can't draw", n.name)
+	}
+	for _, prop := range []string{"clip-path", "mask"} {
+		if v, ok := r.style(n, prop); ok && v != "" && v != "none" {
+			return fmt.Errorf("svg uses %s on <%s>, which the PNG renderer can't apply", prop, n.name)
+		}
+	}
+	for _, c := range n.children {
+		if err := r.unsupported(c); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// parseSVGTree reads b into an element tree rooted at <svg>.
+func parseSVGTree(b []byte) (*svgNode, error) {
+	dec := xml.NewDecoder(bytes.NewReader(b))
+	dec.Strict = false
+	var stack []*svgNode
+	var root *svgNode
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("invalid svg: %w", err)
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			n := &svgNode{name: t.Name.Local, attrs: make(map[string]string, len(t.Attr))}
+			for _, a := range t.Attr {
+				// xlink:href and href are the same thing to us.
+				n.attrs[a.Name.Local] = a.Value
+			}
+			if len(stack) > 0 {
+				p := stack[len(stack)-1]
+				p.children = append(p.children, n)
+			} else if root == nil {
+				root = n
+			}
+			stack = append(stack, n)
+		case xml.EndElement:
+			if len(stack) > 0 {
+				stack = stack[:len(stack)-1]
+			}
+		case xml.CharData:
+			if len(stack) > 0 {
+				stack[len(stack)-1].text += string(t)
+			}
+		}
+	}
+	if root == nil || root.name != "svg" {
+		return nil, errors.New("no <svg> root element")
+	}
+	return root, nil
+}
+
+// parseSVGCSS reads simple rules ("sel, sel { prop: val; }") from a
+// stylesheet. Only single class, id, and type selectors are understood.
+func parseSVGCSS(s string) []svgCSSRule {
+	var out []svgCSSRule
+	for {
+		open := strings.IndexByte(s, '{')
+		if open < 0 {
+			return out
+		}
+		end := strings.IndexByte(s[open:], '}')
+		if end < 0 {
+			return out
+		}
+		decls := parseSVGDecls(s[open+1 : open+end])
+		for _, sel := range strings.Split(s[:open], ",") {
+			sel = strings.TrimSpace(sel)
+			if sel != "" && !strings.ContainsAny(sel, " >+~:[") && strings.Count(sel, ".")+strings.Count(sel, "#") <= 1 {
+				out = append(out, svgCSSRule{sel: sel, decls: decls})
+			}
+		}
+		s = s[open+end+1:]
+	}
+}
+
+// parseSVGDecls reads "prop: val; prop: val" declarations.
+func parseSVGDecls(s string) map[string]string {
+	out := make(map[string]string)
+	for _, d := range strings.Split(s, ";") {
+		if k, v, ok := strings.Cut(d, ":"); ok {
+			out[strings.ToLower(strings.TrimSpace(k))] = strings.TrimSpace(strings.TrimSuffix(strings.TrimSpace(v), "!important"))
+		}
+	}
+	return out
+}
+
+// style resolves a property for n: presentation attribute, then stylesheet
+// rules (type < class < id), then the style attribute.
+func (r *svgRenderer) style(n *svgNode, prop string) (string, bool) {
+	v, ok := n.attrs[prop]
+	classes := strings.Fields(n.attrs["class"])
+	for _, pass := range []func(sel string) bool{
+		func(sel string) bool { return sel == n.name },
+		func(sel string) bool {
+			for _, c := range classes {
+				if sel == "."+c {
+					return true
+				}
+			}
+			return false
+		},
+		func(sel string) bool { return n.attrs["id"] != "" && sel == "#"+n.attrs["id"] },
+	} {
+		for _, rule := range r.css {
+			if pv, has := rule.decls[prop]; has && pass(rule.sel) {
+				v, ok = pv, true
+			}
+		}
+	}
+	if sv, has := parseSVGDecls(n.attrs["style"])[prop]; has {
+		v, ok = sv, true
+	}
+	return strings.TrimSpace(v), ok && strings.TrimSpace(v) != "inherit"
+}
+
+// inherit applies n's own paint properties and transform on top of p.
+func (r *svgRenderer) inherit(n *svgNode, p svgPaint) (svgPaint, bool) {
+	if v, _ := r.style(n, "display"); v == "none" {
+		return p, false
+	}
+	if v, ok := r.style(n, "color"); ok {
+		if c, ok := r.parseColor(v, p); ok {
+			p.color = c
+		}
+	}
+	if v, ok := r.style(n, "fill"); ok {
+		p.fillNone = v == "none" || v == "transparent"
+		if c, ok := r.parseColor(v, p); ok {
+			p.fill = c
+		}
+	}
+	if v, ok := r.style(n, "stroke"); ok {
+		p.strokeNone = v == "none" || v == "transparent"
+		if c, ok := r.parseColor(v, p); ok {
+			p.stroke = c
+		}
+	}
+	if v, ok := r.style(n, "fill-opacity"); ok {
+		p.fillOpacity = svgOpacity(v)
+	}
+	if v, ok := r.style(n, "stroke-opacity"); ok {
+		p.strokeOpacity = svgOpacity(v)
+	}
+	if v, ok := r.style(n, "opacity"); ok {
+		// Group opacity is approximated per shape.
+		p.opacity *= svgOpacity(v)
+	}
+	if v, ok := r.style(n, "stroke-width"); ok {
+		p.strokeWidth = svgLength(v)
+	}
+	if v, ok := r.style(n, "fill-rule"); ok {
+		p.evenOdd = v == "evenodd"
+	}
+	if v, _ := r.style(n, "visibility"); v == "hidden" || v == "collapse" {
+		p.fillNone, p.strokeNone = true, true
+	}
+	if t := n.attrs["transform"]; t != "" {
+		p.m = p.m.mul(parseSVGTransform(t))
+	}
+	return p, true
+}
+
+func (r *svgRenderer) renderChildren(n *svgNode, p svgPaint) {
+	for _, c := range n.children {
+		r.render(c, p)
+	}
+}
+
+func (r *svgRenderer) render(n *svgNode, p svgPaint) {
+	switch n.name {
+	case "defs", "symbol", "clipPath", "mask", "pattern", "marker", "style", "title", "desc",
+		"linearGradient", "radialGradient", "filter", "metadata":
+		return
+	}
+	p, visible := r.inherit(n, p)
+	if !visible {
+		return
+	}
+	switch n.name {
+	case "svg":
+		p.m = p.m.mul(svgMatrix{a: 1, d: 1, e: svgLength(n.attrs["x"]), f: svgLength(n.attrs["y"])})
+		r.renderChildren(n, p)
+	case "g", "a", "switch":
+		r.renderChildren(n, p)
+	case "use":
+		ref := r.ids[strings.TrimPrefix(n.attrs["href"], "#")]
+		if ref == nil || r.depth > 8 {
+			return
+		}
+		p.m = p.m.mul(svgMatrix{a: 1, d: 1, e: svgLength(n.attrs["x"]), f: svgLength(n.attrs["y"])})
+		r.depth++
+		if ref.name == "symbol" {
+			if rp, ok := r.inherit(ref, p); ok {
+				r.renderChildren(ref, rp)
+			}
+		} else {
+			r.render(ref, p)
+		}
+		r.depth--
+	default:
+		if subs := svgShape(n); len(subs) > 0 {
+			r.draw(subs, p)
+		}
+	}
+}
+
+// svgShape returns the outline of a basic shape or path in user space, as
+// subpaths of flattened points. Curves are flattened later, once the device
+// scale is known, so here they are kept as path commands.
+func svgShape(n *svgNode) []svgSubpath {
+	num := func(k string) float64 { return svgLength(n.attrs[k]) }
+	var d string
+	switch n.name {
+	case "path":
+		d = n.attrs["d"]
+	case "rect":
+		x, y, w, h := num("x"), num("y"), num("width"), num("height")
+		if w <= 0 || h <= 0 {
+			return nil
+		}
+		rx, ry := num("rx"), num("ry")
+		if rx == 0 {
+			rx = ry
+		}
+		if ry == 0 {
+			ry = rx
+		}
+		rx, ry = math.Min(rx, w/2), math.Min(ry, h/2)
+		if rx > 0 {
+			d = fmt.Sprintf("M%g %gH%gA%g %g 0 0 1 %g %gV%gA%g %g 0 0 1 %g %gH%gA%g %g 0 0 1 %g %gV%gA%g %g 0 0 1 %g %gZ",
+				x+rx, y, x+w-rx, rx, ry, x+w, y+ry, y+h-ry, rx, ry, x+w-rx, y+h, x+rx, rx, ry, x, y+h-ry, y+ry, rx, ry, x+rx, y)
+		} else {
+			d = fmt.Sprintf("M%g %gH%gV%gH%gZ", x, y, x+w, y+h, x)
+		}
+	case "circle", "ellipse":
+		cx, cy := num("cx"), num("cy")
+		rx, ry := num("rx"), num("ry")
+		if n.name == "circle" {
+			rx, ry = num("r"), num("r")
+		}
+		if rx <= 0 || ry <= 0 {
+			return nil
+		}
+		d = fmt.Sprintf("M%g %gA%g %g 0 1 0 %g %gA%g %g 0 1 0 %g %gZ", cx-rx, cy, rx, ry, cx+rx, cy, rx, ry, cx-rx, cy)
+	case "line":
+		d = fmt.Sprintf("M%g %gL%g %g", num("x1"), num("y1"), num("x2"), num("y2"))
+	case "polyline", "polygon":
+		d = "M" + n.attrs["points"]
+		if n.name == "polygon" {
+			d += "Z"
+		}
+	default:
+		return nil
+	}
+	return parseSVGPath(d)
+}
+
+// svgSubpath is one subpath as a list of segments from its start point.
+type svgSubpath struct {
+	segs   []svgSeg
+	closed bool
+}
+
+// svgSeg is a line (len(pts) == 1) or cubic Bézier (len(pts) == 3) ending
+// at its last point, starting at the previous segment's end (or the
+// subpath's move-to, stored as the first segment).
+type svgSeg struct{ pts [][2]float64 }
+
+// parseSVGPath parses path data into subpaths. Quadratic curves and arcs are
+// converted to cubics; malformed data ends the path at the error, as
+// browsers do.
+func parseSVGPath(d string) []svgSubpath {
+	toks := tokenizeSVGPath(d)
+	var subs []svgSubpath
+	var cur *svgSubpath
+	var x, y, sx, sy float64 // current and subpath-start points
+	var cx, cy float64       // last control point, for S/T reflection
+	var prev byte            // previous command, for reflection
+	i := 0
+	nums := func(k int) ([]float64, bool) {
+		if i+k > len(toks) {
+			return nil, false
+		}
+		out := make([]float64, k)
+		for j := 0; j < k; j++ {
+			v, err := strconv.ParseFloat(toks[i+j], 64)
+			if err != nil {
+				return nil, false
+			}
+			out[j] = v
+		}
+		i += k
+		return out, true
+	}
+	start := func() {
+		subs = append(subs, svgSubpath{segs: []svgSeg{{pts: [][2]float64{{x, y}}}}})
+		cur = &subs[len(subs)-1]
+	}
+	cubic := func(x1, y1, x2, y2, x3, y3 float64) {
+		if cur == nil {
+			start()
+		}
+		cur.segs = append(cur.segs, svgSeg{pts: [][2]float64{{x1, y1}, {x2, y2}, {x3, y3}}})
+		cx, cy, x, y = x2, y2, x3, y3
+	}
+	var cmd byte
+	for i < len(toks) {
+		if t := toks[i]; len(t) == 1 && isASCIILetter(t[0]) {
+			cmd = t[0]
+			i++
+		} else if cmd == 0 {
+			return subs
+		}
+		rel := cmd >= 'a'
+		ox, oy := 0.0, 0.0
+		if rel {
+			ox, oy = x, y
+		}
+		switch cmd | 0x20 {
+		case 'm':
+			v, ok := nums(2)
+			if !ok {
+				return subs
+			}
+			x, y = ox+v[0], oy+v[1]
+			sx, sy = x, y
+			start()
+			// Further coordinate pairs are implicit line-tos.
+			prev, cmd = cmd, 'L'|(cmd&0x20)
+			continue
+		case 'l':
+			v, ok := nums(2)
+			if !ok {
+				return subs
+			}
+			x, y = ox+v[0], oy+v[1]
+		case 'h':
+			v, ok := nums(1)
+			if !ok {
+				return subs
+			}
+			x = ox + v[0]
+		case 'v':
+			v, ok := nums(1)
+			if !ok {
+				return subs
+			}
+			y = oy + v[0]
+		case 'c':
+			v, ok := nums(6)
+			if !ok {
+				return subs
+			}
+			cubic(ox+v[0], oy+v[1], ox+v[2], oy+v[3], ox+v[4], oy+v[5])
+		case 's':
+			v, ok := nums(4)
+			if !ok {
+				return subs
+			}
+			x1, y1 := x, y
+			if p := prev | 0x20; p == 'c' || p == 's' {
+				x1, y1 = 2*x-cx, 2*y-cy
+			}
+			cubic(x1, y1, ox+v[0], oy+v[1], ox+v[2], oy+v[3])
+		case 'q', 't':
+			var qx, qy, ex, ey float64
+			if cmd|0x20 == 'q' {
+				v, ok := nums(4)
+				if !ok {
+					return subs
+				}
+				qx, qy, ex, ey = ox+v[0], oy+v[1], ox+v[2], oy+v[3]
+			} else {
+				v, ok := nums(2)
+				if !ok {
+					return subs
+				}
+				qx, qy = x, y
+				if p := prev | 0x20; p == 'q' || p == 't' {
+					qx, qy = 2*x-cx, 2*y-cy
+				}
+				ex, ey = ox+v[0], oy+v[1]
+			}
+			x0, y0 := x, y
+			cubic(x0+2.0/3*(qx-x0), y0+2.0/3*(qy-y0), ex+2.0/3*(qx-ex), ey+2.0/3*(qy-ey), ex, ey)
+			cx, cy = qx, qy // reflection uses the quadratic control point
+		case 'a':
+			v, ok := nums(7)
+			if !ok {
+				return subs
+			}
+			for _, c := range arcToCubics(x, y, v[0], v[1], v[2], v[3] != 0, v[4] != 0, ox+v[5], oy+v[6]) {
+				cubic(c[0], c[1], c[2], c[3], c[4], c[5])
+			}
+			x, y = ox+v[5], oy+v[6]
+		case 'z':
+			if cur != nil {
+				cur.closed = true
+			}
+			x, y = sx, sy
+			cur = nil
+			prev = cmd
+			continue
+		default:
+			return subs
+		}
+		if c := cmd | 0x20; c == 'l' || c == 'h' || c == 'v' {
+			if cur == nil {
+				start()
+			}
+			cur.segs = append(cur.segs, svgSeg{pts: [][2]float64{{x, y}}})
+		}
+		prev = cmd
+	}
+	return subs
+}
+
+// tokenizeSVGPath splits path data into command letters and numbers,
+// handling the compact forms exporters emit ("1.5.5", "-1-2", "1e-3", and
+// arc flags written without separators).
+func tokenizeSVGPath(d string) []string {
+	var out []string
+	arcArg := -1 // position within an arc's 7 arguments, or -1
+	for i := 0; i < len(d); {
+		c := d[i]
+		switch {
+		case c == ' ' || c == ',' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case isASCIILetter(c) && c != 'e' && c != 'E':
+			out = append(out, string(c))
+			if c == 'a' || c == 'A' {
+				arcArg = 0
+			} else {
+				arcArg = -1
+			}
+			i++
+		default:
+			if arcArg == 3 || arcArg == 4 {
+				// A flag is a single 0 or 1 digit.
+				out = append(out, string(c))
+				i++
+				arcArg++
+				continue
+			}
+			j := i
+			if j < len(d) && (d[j] == '-' || d[j] == '+') {
+				j++
+			}
+			dot := false
+			for j < len(d) && ((d[j] >= '0' && d[j] <= '9') || (d[j] == '.' && !dot)) {
+				if d[j] == '.' {
+					dot = true
+				}
+				j++
+			}
+			if j < len(d) && (d[j] == 'e' || d[j] == 'E') {
+				j++
+				if j < len(d) && (d[j] == '-' || d[j] == '+') {
+					j++
+				}
+				for j < len(d) && d[j] >= '0' && d[j] <= '9' {
+					j++
+				}
+			}
+			if j == i {
+				j++ // unparseable byte; let the parser reject it
+			}
+			out = append(out, d[i:j])
+			i = j
+			if arcArg >= 0 {
+				arcArg = (arcArg + 1) % 7
+			}
+		}
+	}
+	return out
+}
+
+// arcToCubics converts an SVG elliptical arc to cubic Bézier segments
+// (x1 y1 x2 y2 x y each), per the SVG implementation notes (F.6).
+func arcToCubics(x0, y0, rx, ry, phiDeg float64, large, sweep bool, x, y float64) [][6]float64 {
+	if x0 == x && y0 == y {
+		return nil
+	}
+	rx, ry = math.Abs(rx), math.Abs(ry)
+	if rx == 0 || ry == 0 {
+		return [][6]float64{{x0, y0, x, y, x, y}}
+	}
+	phi := phiDeg * math.Pi / 180
+	cos, sin := math.Cos(phi), math.Sin(phi)
+	dx, dy := (x0-x)/2, (y0-y)/2
+	x1p, y1p := cos*dx+sin*dy, -sin*dx+cos*dy
+	if l := x1p*x1p/(rx*rx) + y1p*y1p/(ry*ry); l > 1 {
+		rx, ry = rx*math.Sqrt(l), ry*math.Sqrt(l)
+	}
+	num := rx*rx*ry*ry - rx*rx*y1p*y1p - ry*ry*x1p*x1p
+	den := rx*rx*y1p*y1p + ry*ry*x1p*x1p
+	co := math.Sqrt(math.Max(0, num/den))
+	if large == sweep {
+		co = -co
+	}
+	cxp, cyp := co*rx*y1p/ry, -co*ry*x1p/rx
+	cx := cos*cxp - sin*cyp + (x0+x)/2
+	cy := sin*cxp + cos*cyp + (y0+y)/2
+
+	angle := func(ux, uy, vx, vy float64) float64 {
+		return math.Atan2(ux*vy-uy*vx, ux*vx+uy*vy)
+	}
+	t1 := angle(1, 0, (x1p-cxp)/rx, (y1p-cyp)/ry)
+	dt := angle((x1p-cxp)/rx, (y1p-cyp)/ry, (-x1p-cxp)/rx, (-y1p-cyp)/ry)
+	if !sweep && dt > 0 {
+		dt -= 2 * math.Pi
+	} else if sweep && dt < 0 {
+		dt += 2 * math.Pi
+	}
+
+	n := int(math.Ceil(math.Abs(dt) / (math.Pi / 2)))
+	step := dt / float64(n)
+	k := 4.0 / 3 * math.Tan(step/4)
+	pt := func(t float64) (px, py, tx, ty float64) {
+		ex, ey := rx*math.Cos(t), ry*math.Sin(t)
+		dex, dey := -rx*math.Sin(t), ry*math.Cos(t)
+		return cos*ex - sin*ey + cx, sin*ex + cos*ey + cy, cos*dex - sin*dey, sin*dex + cos*dey
+	}
+	out := make([][6]float64, 0, n)
+	for i := 0; i < n; i++ {
+		a, b := t1+float64(i)*step, t1+float64(i+1)*step
+		ax, ay, atx, aty := pt(a)
+		bx, by, btx, bty := pt(b)
+		if i == n-1 {
+			bx, by = x, y
+		}
+		out = append(out, [6]float64{ax + k*atx, ay + k*aty, bx - k*btx, by - k*bty, bx, by})
+	}
+	return out
+}
+
+// parseSVGTransform reads a transform list such as
+// "translate(10 5) rotate(45) matrix(1 0 0 1 0 0)".
+func parseSVGTransform(s string) svgMatrix {
+	m := svgIdentity
+	for {
+		open := strings.IndexByte(s, '(')
+		end := strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return m
+		}
+		name := strings.TrimSpace(strings.Trim(s[:open], " ,\t\n"))
+		var v []float64
+		for _, f := range strings.FieldsFunc(s[open+1:end], func(r rune) bool { return r == ' ' || r == ',' || r == '\t' || r == '\n' }) {
+			x, _ := strconv.ParseFloat(f, 64)
+			v = append(v, x)
+		}
+		at := func(i int, def float64) float64 {
+			if i < len(v) {
+				return v[i]
+			}
+			return def
+		}
+		var t svgMatrix
+		switch name {
+		case "matrix":
+			t = svgMatrix{at(0, 1), at(1, 0), at(2, 0), at(3, 1), at(4, 0), at(5, 0)}
+		case "translate":
+			t = svgMatrix{a: 1, d: 1, e: at(0, 0), f: at(1, 0)}
+		case "scale":
+			sx := at(0, 1)
+			t = svgMatrix{a: sx, d: at(1, sx)}
+		case "rotate":
+			rad := at(0, 0) * math.Pi / 180
+			cx, cy := at(1, 0), at(2, 0)
+			rot := svgMatrix{a: math.Cos(rad), b: math.Sin(rad), c: -math.Sin(rad), d: math.Cos(rad)}
+			t = svgMatrix{a: 1, d: 1, e: cx, f: cy}.mul(rot).mul(svgMatrix{a: 1, d: 1, e: -cx, f: -cy})
+		case "skewX":
+			t = svgMatrix{a: 1, c: math.Tan(at(0, 0) * math.Pi / 180), d: 1}
+		case "skewY":
+			t = svgMatrix{a: 1, b: math.Tan(at(0, 0) * math.Pi / 180), d: 1}
+		default:
+			t = svgIdentity
+		}
+		m = m.mul(t)
+		s = s[end+1:]
+	}
+}
+
+// svgLength parses a number with an optional px unit; other units read as 0.
+func svgLength(s string) float64 {
+	s = strings.TrimSuffix(strings.TrimSpace(s), "px")
+	v, err := strconv.ParseFloat(s, 64)
+	if err != nil {
+		return 0
+	}
+	return v
+}
+
+func svgOpacity(s string) float64 {
+	s = strings.TrimSpace(s)
+	var v float64
+	var err error
+	if strings.HasSuffix(s, "%") {
+		v, err = strconv.ParseFloat(strings.TrimSuffix(s, "%"), 64)
+		v /= 100
+	} else {
+		v, err = strconv.ParseFloat(s, 64)
+	}
+	if err != nil {
+		return 1
+	}
+	return math.Max(0, math.Min(1, v))
+}
+
+// svgNamedColors covers the keywords seen in exported logos; anything
+// unrecognized is left as the inherited color.
+var svgNamedColors = map[string]color.NRGBA{
+	"black": {0, 0, 0, 255}, "white": {255, 255, 255, 255}, "red": {255, 0, 0, 255},
+	"green": {0, 128, 0, 255}, "blue": {0, 0, 255, 255}, "yellow": {255, 255, 0, 255},
+	"orange": {255, 165, 0, 255}, "purple": {128, 0, 128, 255}, "gray": {128, 128, 128, 255},
+	"grey": {128, 128, 128, 255}, "silver": {192, 192, 192, 255}, "maroon": {128, 0, 0, 255},
+	"navy": {0, 0, 128, 255}, "teal": {0, 128, 128, 255}, "olive": {128, 128, 0, 255},
+	"lime": {0, 255, 0, 255}, "aqua": {0, 255, 255, 255}, "cyan": {0, 255, 255, 255},
+	"fuchsia": {255, 0, 255, 255}, "magenta": {255, 0, 255, 255}, "gold": {255, 215, 0, 255},
+	"brown": {165, 42, 42, 255}, "pink": {255, 192, 203, 255},
+}
+
+// parseColor reads a paint value: hex, rgb()/rgba(), a named color,
+// currentColor, or url(#gradient) (painted as the average of its stops).
+func (r *svgRenderer) parseColor(s string, p svgPaint) (color.NRGBA, bool) {
+	s = strings.ToLower(strings.TrimSpace(s))
+	if strings.HasPrefix(s, "url(") {
+		id := strings.Trim(strings.TrimSuffix(strings.TrimPrefix(strings.Fields(s)[0], "url("), ")"), `'"#`)
+		return r.gradientColor(id, 0)
+	}
+	if s == "currentcolor" {
+		return p.color, true
+	}
+	if c, ok := svgNamedColors[s]; ok {
+		return c, true
+	}
+	if strings.HasPrefix(s, "#") {
+		h := s[1:]
+		if len(h) == 3 || len(h) == 4 {
+			var e strings.Builder
+			for _, ch := range h {
+				e.WriteRune(ch)
+				e.WriteRune(ch)
+			}
+			h = e.String()
+		}
+		if len(h) != 6 && len(h) != 8 {
+			return color.NRGBA{}, false
+		}
+		v, err := strconv.ParseUint(h, 16, 32)
+		if err != nil {
+			return color.NRGBA{}, false
+		}
+		if len(h) == 6 {
+			v = v<<8 | 0xff
+		}
+		return color.NRGBA{uint8(v >> 24), uint8(v >> 16), uint8(v >> 8), uint8(v)}, true
+	}
+	if strings.HasPrefix(s, "rgb") {
+		open, end := strings.IndexByte(s, '('), strings.IndexByte(s, ')')
+		if open < 0 || end < open {
+			return color.NRGBA{}, false
+		}
+		f := strings.FieldsFunc(s[open+1:end], func(r rune) bool { return r == ',' || r == ' ' || r == '/' })
+		if len(f) < 3 {
+			return color.NRGBA{}, false
+		}
+		var ch [4]uint8
+		ch[3] = 255
+		for i := 0; i < len(f) && i < 4; i++ {
+			v, pct := f[i], strings.HasSuffix(f[i], "%")
+			x, err := strconv.ParseFloat(strings.TrimSuffix(v, "%"), 64)
+			if err != nil {
+				return color.NRGBA{}, false
+			}
+			switch {
+			case i == 3 && !pct:
+				x *= 255
+			case pct:
+				x *= 2.55
+			}
+			ch[i] = uint8(math.Max(0, math.Min(255, math.Round(x))))
+		}
+		return color.NRGBA{ch[0], ch[1], ch[2], ch[3]}, true
+	}
+	return color.NRGBA{}, false
+}
+
+// gradientColor averages a gradient's stop colors, following href to a
+// template gradient when it has no stops of its own.
+func (r *svgRenderer) gradientColor(id string, depth int) (color.NRGBA, bool) {
+	g := r.ids[id]
+	if g == nil || depth > 4 {
+		return color.NRGBA{}, false
+	}
+	var sr, sg, sb, sa, n float64
+	for _, st := range g.children {
+		if st.name != "stop" {
+			continue
+		}
+		v, ok := r.style(st, "stop-color")
+		if !ok {
+			v = "black"
+		}
+		c, ok := r.parseColor(v, svgPaint{})
+		if !ok {
+			continue
+		}
+		a := float64(c.A)
+		if o, ok := r.style(st, "stop-opacity"); ok {
+			a *= svgOpacity(o)
+		}
+		sr, sg, sb, sa, n = sr+float64(c.R), sg+float64(c.G), sb+float64(c.B), sa+a, n+1
+	}
+	if n == 0 {
+		if href := strings.TrimPrefix(g.attrs["href"], "#"); href != "" {
+			return r.gradientColor(href, depth+1)
+		}
+		return color.NRGBA{}, false
+	}
+	return color.NRGBA{uint8(sr / n), uint8(sg / n), uint8(sb / n), uint8(sa / n)}, true
+}
+
+// draw fills and strokes subpaths with p.
+func (r *svgRenderer) draw(subs []svgSubpath, p svgPaint) {
+	polys := flattenSubpaths(subs, p.m)
+	if !p.fillNone {
+		c := p.fill
+		c.A = uint8(float64(c.A) * p.fillOpacity * p.opacity)
+		fillPolygons(r.img, polys, c, p.evenOdd)
+	}
+	if !p.strokeNone && p.strokeWidth > 0 {
+		c := p.stroke
+		c.A = uint8(float64(c.A) * p.strokeOpacity * p.opacity)
+		w := p.strokeWidth * p.m.scale()
+		var outline [][][2]float64
+		for i, poly := range polys {
+			outline = append(outline, strokeOutline(poly, subs[i].closed, w)...)
+		}
+		fillPolygons(r.img, outline, c, false)
+	}
+}
+
+// flattenSubpaths transforms subpaths to device space and approximates each
+// cubic with line segments about a pixel long.
+func flattenSubpaths(subs []svgSubpath, m svgMatrix) [][][2]float64 {
+	out := make([][][2]float64, 0, len(subs))
+	for _, sp := range subs {
+		var poly [][2]float64
+		for _, seg := range sp.segs {
+			switch len(seg.pts) {
+			case 1:
+				x, y := m.apply(seg.pts[0][0], seg.pts[0][1])
+				poly = append(poly, [2]float64{x, y})
+			case 3:
+				p0 := poly[len(poly)-1]
+				x1, y1 := m.apply(seg.pts[0][0], seg.pts[0][1])
+				x2, y2 := m.apply(seg.pts[1][0], seg.pts[1][1])
+				x3, y3 := m.apply(seg.pts[2][0], seg.pts[2][1])
+				l := math.Hypot(x1-p0[0], y1-p0[1]) + math.Hypot(x2-x1, y2-y1) + math.Hypot(x3-x2, y3-y2)
+				n := int(math.Max(2, math.Min(200, math.Ceil(l/1.5))))
+				for i := 1; i <= n; i++ {
+					t := float64(i) / float64(n)
+					u := 1 - t
+					a, b, c, d := u*u*u, 3*u*u*t, 3*u*t*t, t*t*t
+					poly = append(poly, [2]float64{a*p0[0] + b*x1 + c*x2 + d*x3, a*p0[1] + b*y1 + c*y2 + d*y3})
+				}
+			}
+		}
+		out = append(out, poly)
+	}
+	return out
+}
+
+// strokeOutline returns polygons covering a stroke of width w along poly:
+// one quad per segment plus a round-ish cap at each vertex for joins. All
+// are wound the same way so a nonzero fill unions them.
+func strokeOutline(poly [][2]float64, closed bool, w float64) [][][2]float64 {
+	if closed && len(poly) > 1 {
+		poly = append(poly, poly[0])
+	}
+	hw := w / 2
+	var out [][][2]float64
+	for i := 1; i < len(poly); i++ {
+		a, b := poly[i-1], poly[i]
+		dx, dy := b[0]-a[0], b[1]-a[1]
+		l := math.Hypot(dx, dy)
+		if l == 0 {
+			continue
+		}
+		nx, ny := -dy/l*hw, dx/l*hw
+		out = append(out, orient([][2]float64{{a[0] + nx, a[1] + ny}, {b[0] + nx, b[1] + ny}, {b[0] - nx, b[1] - ny}, {a[0] - nx, a[1] - ny}}))
+	}
+	if hw >= 0.75 {
+		for i := 1; i < len(poly)-1; i++ {
+			var disc [][2]float64
+			for k := 0; k < 8; k++ {
+				t := float64(k) * math.Pi / 4
+				disc = append(disc, [2]float64{poly[i][0] + hw*math.Cos(t), poly[i][1] + hw*math.Sin(t)})
+			}
+			out = append(out, orient(disc))
+		}
+	}
+	return out
+}
+
+// orient returns poly wound counter-clockwise (positive signed area).
+func orient(poly [][2]float64) [][2]float64 {
+	area := 0.0
+	for i := range poly {
+		j := (i + 1) % len(poly)
+		area += poly[i][0]*poly[j][1] - poly[j][0]*poly[i][1]
+	}
+	if area < 0 {
+		for i, j := 0, len(poly)-1; i < j; i, j = i+1, j-1 {
+			poly[i], poly[j] = poly[j], poly[i]
+		}
+	}
+	return poly
+}
+
+// svgSubsamples is the number of scanlines sampled per pixel row.
+const svgSubsamples = 4
+
+// fillPolygons composites c over img wherever the polygons cover, using the
+// nonzero or even-odd rule, antialiased with vertical subsampling and exact
+// horizontal span coverage.
+func fillPolygons(img *image.RGBA, polys [][][2]float64, c color.NRGBA, evenOdd bool) {
+	if c.A == 0 {
+		return
+	}
+	type edge struct{ x0, y0, x1, y1 float64 }
+	var edges []edge
+	minY, maxY := math.Inf(1), math.Inf(-1)
+	for _, poly := range polys {
+		for i := range poly {
+			a, b := poly[i], poly[(i+1)%len(poly)]
+			if a[1] == b[1] {
+				continue
+			}
+			edges = append(edges, edge{a[0], a[1], b[0], b[1]})
+			minY, maxY = math.Min(minY, math.Min(a[1], b[1])), math.Max(maxY, math.Max(a[1], b[1]))
+		}
+	}
+	if len(edges) == 0 {
+		return
+	}
+	bounds := img.Bounds()
+	w := bounds.Dx()
+	y0 := int(math.Max(0, math.Floor(minY)))
+	y1 := int(math.Min(float64(bounds.Dy()), math.Ceil(maxY)))
+	cover := make([]float64, w+1)
+	type hit struct {
+		x   float64
+		dir int
+	}
+	var hits []hit
+	for py := y0; py < y1; py++ {
+		for i := range cover {
+			cover[i] = 0
+		}
+		for s := 0; s < svgSubsamples; s++ {
+			sy := float64(py) + (float64(s)+0.5)/svgSubsamples
+			hits = hits[:0]
+			for _, e := range edges {
+				lo, hi, dir := e.y0, e.y1, 1
+				if lo > hi {
+					lo, hi, dir = hi, lo, -1
+				}
+				if sy < lo || sy >= hi {
+					continue
+				}
+				hits = append(hits, hit{e.x0 + (sy-e.y0)/(e.y1-e.y0)*(e.x1-e.x0), dir})
+			}
+			sort.Slice(hits, func(i, j int) bool { return hits[i].x < hits[j].x })
+			wind := 0
+			for i := 0; i+1 < len(hits); i++ {
+				wind += hits[i].dir
+				inside := wind != 0
+				if evenOdd {
+					inside = (i+1)%2 == 1
+				}
+				if inside {
+					addSpan(cover, hits[i].x, hits[i+1].x, 1.0/svgSubsamples)
+				}
+			}
+		}
+		for px := 0; px < w; px++ {
+			a := math.Min(1, cover[px])
+			if a <= 0 {
+				continue
+			}
+			blendOver(img, px, py, c, a)
+		}
+	}
+}
+
+// addSpan adds weight times the covered fraction of each pixel in [x0, x1).
+func addSpan(cover []float64, x0, x1, weight float64) {
+	w := float64(len(cover) - 1)
+	x0, x1 = math.Max(0, x0), math.Min(w, x1)
+	if x1 <= x0 {
+		return
+	}
+	i0, i1 := int(x0), int(x1)
+	if i0 == i1 {
+		cover[i0] += (x1 - x0) * weight
+		return
+	}
+	cover[i0] += (float64(i0+1) - x0) * weight
+	for i := i0 + 1; i < i1; i++ {
+		cover[i] += weight
+	}
+	cover[i1] += (x1 - float64(i1)) * weight
+}
+
+// blendOver composites c at coverage a over the premultiplied pixel (x, y).
+func blendOver(img *image.RGBA, x, y int, c color.NRGBA, a float64) {
+	sa := float64(c.A) / 255 * a
+	i := img.PixOffset(x, y)
+	px := img.Pix[i : i+4 : i+4]
+	inv := 1 - sa
+	px[0] = uint8(float64(c.R)*sa + float64(px[0])*inv + 0.5)
+	px[1] = uint8(float64(c.G)*sa + float64(px[1])*inv + 0.5)
+	px[2] = uint8(float64(c.B)*sa + float64(px[2])*inv + 0.5)
+	px[3] = uint8(255*sa + float64(px[3])*inv + 0.5)
+}
+
+// ---- logo normalization ----
+
+// normalizeConfig controls the optional post-download stage that trims,
+// scales, pads, and re-encodes logos so the sponsor grid looks even.
+type normalizeConfig struct {
+	Enable    bool
+	MaxWidth  int    // downscale wider logos to this width (0 = no limit)
+	BoxW      int    // pad onto a BoxW x BoxH canvas (0 = no padding)
+	BoxH      int    //
+	Tolerance int    // per-channel difference still treated as border
+	Format    string // png | jpg | webp
+	Originals string // where untouched downloads are kept
+}
+
+// originalsDirName is where normalizing keeps untouched downloads, beside
+// the logo directory.
+const originalsDirName = "originals"
+
+// normalizeLogo runs the normalization stage on the logo at path and returns
+// the path of the result (the extension may change with Format). The source
+// is always the stored original, so re-running with different settings
+// starts from the real download rather than a previous result. When fresh is
+// true, path is a new download and replaces any stored original.
+func normalizeLogo(path string, cfg normalizeConfig, fresh bool) (string, error) {
+	if err := checkNormalizable(path); err != nil {
+		return path, err
+	}
+	orig, err := stashOriginal(path, cfg.Originals, fresh)
+	if err != nil {
+		return path, err
+	}
+	if err := checkNormalizable(orig); err != nil {
+		return path, err
+	}
+	b, err := os.ReadFile(orig)
+	if err != nil {
+		return path, err
+	}
+	src, _, err := image.Decode(bytes.NewReader(b))
+	if err != nil {
+		return path, err
+	}
+
+	img := toRGBA(src)
+	img = trimBorder(img, cfg.Tolerance)
+	img = fitWithin(img, cfg.MaxWidth, cfg.BoxW, cfg.BoxH)
+	opaque := cfg.Format == "jpg"
+	if cfg.BoxW > 0 && cfg.BoxH > 0 {
+		img = padTo(img, cfg.BoxW, cfg.BoxH, opaque)
+	}
+
+	var buf bytes.Buffer
+	out := replaceExt(path, "."+cfg.Format)
+	switch cfg.Format {
+	case "png":
+		err = (&png.Encoder{CompressionLevel: png.BestCompression}).Encode(&buf, img)
+	case "jpg":
+		err = jpeg.Encode(&buf, flattenOnWhite(img), &jpeg.Options{Quality: 88})
+	case "webp":
+		err = encodeWebP(&buf, img, 88)
+	default:
+		err = fmt.Errorf("unsupported output format %q (png, jpg, or webp)", cfg.Format)
+	}
+	if err != nil {
+		return path, err
+	}
+	if err := writeFileAtomic(out, &buf); err != nil {
+		return path, err
+	}
+	if out != path {
+		// The pre-normalization file is safe in the originals directory.
+		os.Remove(path)
+	}
+	dbg("normalized %s → %s (%dx%d)", orig, out, img.Bounds().Dx(), img.Bounds().Dy())
+	return out, nil
+}
+
+// cwebpTool is libwebp's encoder, which WebP output is handed to: the
+// standard library has no WebP encoder.
+const cwebpTool = "cwebp"
+
+// encodeWebP writes img to w as a lossy WebP of the given quality, with
+// lossless alpha, by running cwebpTool on a PNG of it.
+func encodeWebP(w io.Writer, img image.Image, quality int) error {
+	bin, err := exec.LookPath(cwebpTool)
+	if err != nil {
+		return fmt.Errorf("webp output needs %s on PATH: %w", cwebpTool, err)
+	}
+	dir, err := os.MkdirTemp("", "sonofest-webp-")
+	if err != nil {
+		return err
+	}
+	defer os.RemoveAll(dir)
+	in, out := filepath.Join(dir, "in.png"), filepath.Join(dir, "out.webp")
+	var src bytes.Buffer
+	if err := png.Encode(&src, img); err != nil {
+		return err
+	}
+	if err := os.WriteFile(in, src.Bytes(), 0o644); err != nil {
+		return err
+	}
+	cmd := exec.Command(bin, "-quiet", "-q", strconv.Itoa(quality), "-alpha_q", "100", in, "-o", out)
+	if msg, err := cmd.CombinedOutput(); err != nil {
+		return fmt.Errorf("%s: %v: %s", cwebpTool, err, bytes.TrimSpace(msg))
+	}
+	b, err := os.ReadFile(out)
+	if err != nil {
+		return err
+	}
+	_, err = w.Write(b)
+	return err
+}
+
+// errSkipNormalize marks logos the stage leaves untouched on purpose.
+var errSkipNormalize = errors.New("normalization skipped")
+
+// checkNormalizable returns errSkipNormalize for formats the stage can't or
+// shouldn't re-encode: vectors don't need it, and WebP/AVIF can't be decoded
+// with the standard library.
+func checkNormalizable(path string) error {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return err
+	}
+	info, err := inspectImage(b)
+	if err != nil {
+		return err
+	}
+	switch info.Format {
+	case "png", "jpeg", "gif":
+		return nil
+	}
+	return fmt.Errorf("%w: %s", errSkipNormalize, info.Format)
+}
+
+// stashOriginal makes sure dir holds the source for path and returns its
+// location. Originals are matched by file stem, since normalizing may have
+// changed the extension of the live copy.
+func stashOriginal(path, dir string, fresh bool) (string, error) {
+	stem := strings.TrimSuffix(filepath.Base(path), filepath.Ext(path))
+	existing, _ := filepath.Glob(filepath.Join(dir, globEscape(stem)+".*"))
+	if !fresh && len(existing) > 0 {
+		return existing[0], nil
+	}
+	for _, e := range existing {
+		os.Remove(e)
+	}
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return "", err
+	}
+	dst := filepath.Join(dir, filepath.Base(path))
+	if err := writeFileAtomic(dst, bytes.NewReader(b)); err != nil {
+		return "", err
+	}
+	return dst, nil
+}
+
+// globEscape quotes glob metacharacters in a literal path element.
+func globEscape(s string) string {
+	r := strings.NewReplacer(`*`, `\*`, `?`, `\?`, `[`, `\[`, `\`, `\\`)
+	return r.Replace(s)
+}
+
+func toRGBA(src image.Image) *image.RGBA {
+	b := src.Bounds()
+	dst := image.NewRGBA(image.Rect(0, 0, b.Dx(), b.Dy()))
+	draw.Draw(dst, dst.Bounds(), src, b.Min, draw.Src)
+	return dst
+}
+
+// trimBorder crops away a uniform border: rows and columns whose pixels are
+// all within tol of the top-left corner color.
+func trimBorder(img *image.RGBA, tol int) *image.RGBA {
+	b := img.Bounds()
+	if b.Empty() {
+		return img
+	}
+	bg := img.RGBAAt(b.Min.X, b.Min.Y)
+	differs := func(x, y int) bool {
+		c := img.RGBAAt(x, y)
+		return absDiff(c.R, bg.R) > tol || absDiff(c.G, bg.G) > tol ||
+			absDiff(c.B, bg.B) > tol || absDiff(c.A, bg.A) > tol
+	}
+	minX, minY, maxX, maxY := b.Max.X, b.Max.Y, b.Min.X-1, b.Min.Y-1
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			if differs(x, y) {
+				if x < minX {
+					minX = x
+				}
+				if x > maxX {
+					maxX = x
+				}
+				if y < minY {
+					minY = y
+				}
+				if y > maxY {
+					maxY = y
+				}
+			}
+		}
+	}
+	if maxX < minX || maxY < minY {
+		return img // entirely uniform; nothing sensible to trim to
+	}
+	crop := image.Rect(minX, minY, maxX+1, maxY+1)
+	if crop == b {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, crop.Dx(), crop.Dy()))
+	draw.Draw(dst, dst.Bounds(), img, crop.Min, draw.Src)
+	return dst
+}
+
+func absDiff(a, b uint8) int {
+	if a > b {
+		return int(a - b)
+	}
+	return int(b - a)
+}
+
+// fitWithin downscales img (never upscales) so it is at most maxW wide and
+// fits inside a boxW x boxH box when one is given.
+func fitWithin(img *image.RGBA, maxW, boxW, boxH int) *image.RGBA {
+	w, h := img.Bounds().Dx(), img.Bounds().Dy()
+	scale := 1.0
+	if maxW > 0 && w > maxW {
+		scale = math.Min(scale, float64(maxW)/float64(w))
+	}
+	if boxW > 0 && boxH > 0 {
+		scale = math.Min(scale, math.Min(float64(boxW)/float64(w), float64(boxH)/float64(h)))
+	}
+	if scale >= 1 {
+		return img
+	}
+	nw := int(math.Max(1, math.Round(float64(w)*scale)))
+	nh := int(math.Max(1, math.Round(float64(h)*scale)))
+	return resizeArea(img, nw, nh)
+}
+
+// resizeArea downsamples by averaging the source pixels covered by each
+// destination pixel. image.RGBA is alpha-premultiplied, so a plain average is
+// correct at transparent edges.
+func resizeArea(src *image.RGBA, nw, nh int) *image.RGBA {
+	sw, sh := src.Bounds().Dx(), src.Bounds().Dy()
+	dst := image.NewRGBA(image.Rect(0, 0, nw, nh))
+	for y := 0; y < nh; y++ {
+		y0 := y * sh / nh
+		y1 := (y + 1) * sh / nh
+		if y1 <= y0 {
+			y1 = y0 + 1
+		}
+		for x := 0; x < nw; x++ {
+			x0 := x * sw / nw
+			x1 := (x + 1) * sw / nw
+			if x1 <= x0 {
+				x1 = x0 + 1
+			}
+			var r, g, b, a, n int
+			for sy := y0; sy < y1; sy++ {
+				i := src.PixOffset(x0, sy)
+				for sx := x0; sx < x1; sx++ {
+					r += int(src.Pix[i])
+					g += int(src.Pix[i+1])
+					b += int(src.Pix[i+2])
+					a += int(src.Pix[i+3])
+					i += 4
+					n++
+				}
+			}
+			j := dst.PixOffset(x, y)
+			dst.Pix[j] = uint8(r / n)
+			dst.Pix[j+1] = uint8(g / n)
+			dst.Pix[j+2] = uint8(b / n)
+			dst.Pix[j+3] = uint8(a / n)
+		}
+	}
+	return dst
+}
+
+// padTo centers img on a w x h canvas, transparent or (for opaque formats) white.
+func padTo(img *image.RGBA, w, h int, opaque bool) *image.RGBA {
+	iw, ih := img.Bounds().Dx(), img.Bounds().Dy()
+	if iw == w && ih == h {
+		return img
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	if opaque {
+		draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	}
+	off := image.Pt((w-iw)/2, (h-ih)/2)
+	draw.Draw(dst, image.Rectangle{Min: off, Max: off.Add(image.Pt(iw, ih))}, img, image.Point{}, draw.Over)
+	return dst
+}
+
+// flattenOnWhite composites img over white for formats without alpha.
+func flattenOnWhite(img *image.RGBA) *image.RGBA {
+	dst := image.NewRGBA(img.Bounds())
+	draw.Draw(dst, dst.Bounds(), image.White, image.Point{}, draw.Src)
+	draw.Draw(dst, dst.Bounds(), img, img.Bounds().Min, draw.Over)
+	return dst
+}
+
+// parseBox reads a "WxH" flag value; "" means no box.
+func parseBox(s string) (w, h int, err error) {
+	s = strings.TrimSpace(strings.ToLower(s))
+	if s == "" {
+		return 0, 0, nil
+	}
+	ws, hs, ok := strings.Cut(s, "x")
+	w, err1 := strconv.Atoi(ws)
+	h, err2 := strconv.Atoi(hs)
+	if !ok || err1 != nil || err2 != nil || w <= 0 || h <= 0 {
+		return 0, 0, fmt.Errorf("invalid box %q (want WxH, e.g. 400x200)", s)
+	}
+	return w, h, nil
+}
+
+// ---- duplicate logos ----
+
+// logoFile is one file in the logo directory with its fingerprints and the
+// sponsors whose logo it is.
+type logoFile struct {
+	Path     string
+	SHA256   string
+	DHash    uint64
+	HasDHash bool  // false for formats we can't decode (WebP, AVIF)
+	Sponsors []int // indexes into sponsors, in site order
+}
+
+// scanLogoFiles fingerprints every file directly in logoDir, skipping temp
+// files and the PNG companions of SVG logos (which would always match).
+func scanLogoFiles(logoDir string, sponsors []Sponsor, publicDir string) ([]*logoFile, error) {
+	entries, err := os.ReadDir(logoDir)
+	if err != nil {
+		return nil, err
+	}
+	owners := make(map[string][]int)
+	companions := make(map[string]bool)
+	for i, s := range sponsors {
+		if strings.TrimSpace(s.Logo) != "" {
+			p := filepath.Clean(desiredLocalLogoPath(publicDir, logoDir, s))
+			owners[p] = append(owners[p], i)
+		}
+		if strings.TrimSpace(s.LogoPng) != "" {
+			companions[filepath.Clean(desiredLocalLogoPath(publicDir, logoDir, Sponsor{Logo: s.LogoPng}))] = true
+		}
+	}
+	var out []*logoFile
+	for _, e := range entries {
+		if e.IsDir() || strings.HasPrefix(e.Name(), ".") {
+			continue
+		}
+		path := filepath.Clean(filepath.Join(logoDir, e.Name()))
+		if companions[path] && len(owners[path]) == 0 {
+			continue
+		}
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		sum := sha256.Sum256(b)
+		f := &logoFile{Path: path, SHA256: hex.EncodeToString(sum[:]), Sponsors: owners[path]}
+		if img, err := decodeForHash(b); err == nil {
+			f.DHash, f.HasDHash = dHash(img)
+		} else {
+			dbg("dhash %s: %v", path, err)
+		}
+		out = append(out, f)
+	}
+	return out, nil
+}
+
+// decodeForHash decodes raster logos with the standard library and renders
+// SVGs, so vector and raster copies of a logo can be compared.
+func decodeForHash(b []byte) (image.Image, error) {
+	info, err := inspectImage(b)
+	if err != nil {
+		return nil, err
+	}
+	switch info.Format {
+	case "svg":
+		return rasterizeSVG(b, 256)
+	case "ico":
+		if b, err = icoToPNG(b); err != nil {
+			return nil, err
+		}
+	case "png", "jpeg", "gif":
+	default:
+		return nil, fmt.Errorf("can't decode %s", info.Format)
+	}
+	img, _, err := image.Decode(bytes.NewReader(b))
+	return img, err
+}
+
+// dHash is a 64-bit difference hash: the image is flattened on mid-gray (so
+// white-on-transparent logos don't vanish), trimmed, shrunk to 9x8
+// grayscale, and each bit says whether a pixel is brighter than its right
+// neighbor. Re-encoded, rescaled, or re-padded copies of a logo land within a
+// few bits of each other. It returns false for a blank (uniform) image, whose
+// hash would match every other blank one.
+func dHash(img image.Image) (uint64, bool) {
+	src := toRGBA(img)
+	flat := image.NewRGBA(src.Bounds())
+	draw.Draw(flat, flat.Bounds(), image.NewUniform(color.RGBA{128, 128, 128, 255}), image.Point{}, draw.Src)
+	draw.Draw(flat, flat.Bounds(), src, src.Bounds().Min, draw.Over)
+	trimmed := trimBorder(flat, 8)
+	if trimmed == flat && isUniform(flat, 8) {
+		return 0, false
+	}
+	small := resizeArea(trimmed, 9, 8)
+	var h uint64
+	for y := 0; y < 8; y++ {
+		for x := 0; x < 8; x++ {
+			l, r := small.RGBAAt(x, y), small.RGBAAt(x+1, y)
+			if luma(l) > luma(r) {
+				h |= 1 << (y*8 + x)
+			}
+		}
+	}
+	return h, true
+}
+
+// isUniform reports whether every pixel is within tol of the top-left one.
+func isUniform(img *image.RGBA, tol int) bool {
+	b := img.Bounds()
+	bg := img.RGBAAt(b.Min.X, b.Min.Y)
+	for y := b.Min.Y; y < b.Max.Y; y++ {
+		for x := b.Min.X; x < b.Max.X; x++ {
+			c := img.RGBAAt(x, y)
+			if absDiff(c.R, bg.R) > tol || absDiff(c.G, bg.G) > tol || absDiff(c.B, bg.B) > tol {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+func luma(c color.RGBA) int {
+	return 299*int(c.R) + 587*int(c.G) + 114*int(c.B)
+}
+
+// similarPair is two different files whose perceptual hashes are close.
+type similarPair struct {
+	A, B     *logoFile
+	Distance int
+}
+
+// findDuplicates groups files with identical bytes and pairs up distinct
+// files whose dHash differs in at most maxBits bits.
+func findDuplicates(files []*logoFile, maxBits int) (identical [][]*logoFile, similar []similarPair) {
+	bySHA := make(map[string][]*logoFile)
+	var order []string
+	for _, f := range files {
+		if len(bySHA[f.SHA256]) == 0 {
+			order = append(order, f.SHA256)
+		}
+		bySHA[f.SHA256] = append(bySHA[f.SHA256], f)
+	}
+	var uniq []*logoFile
+	for _, sum := range order {
+		if g := bySHA[sum]; len(g) > 1 {
+			identical = append(identical, g)
+		}
+		uniq = append(uniq, bySHA[sum][0])
+	}
+	if maxBits < 0 {
+		return identical, nil
+	}
+	for i, a := range uniq {
+		for _, b := range uniq[i+1:] {
+			if !a.HasDHash || !b.HasDHash {
+				continue
+			}
+			if d := bits.OnesCount64(a.DHash ^ b.DHash); d <= maxBits {
+				similar = append(similar, similarPair{a, b, d})
+			}
+		}
+	}
+	sort.SliceStable(similar, func(i, j int) bool { return similar[i].Distance < similar[j].Distance })
+	return identical, similar
+}
+
+// reportDuplicates prints identical and near-identical logo files and
+// sponsors sharing an Instagram handle. With share, sponsors in each
+// identical group are pointed at the file of the first of them in site
+// order; the other copies are left for -prune. It reports whether any
+// sponsor changed.
+func reportDuplicates(w io.Writer, sponsors []Sponsor, files []*logoFile, maxBits int, share bool) bool {
+	identical, similar := findDuplicates(files, maxBits)
+	who := func(f *logoFile) string {
+		if len(f.Sponsors) == 0 {
+			return "(no sponsor)"
+		}
+		names := make([]string, len(f.Sponsors))
+		for i, si := range f.Sponsors {
+			names[i] = sponsors[si].Name
+		}
+		return strings.Join(names, ", ")
+	}
+
+	changed := false
+	fmt.Fprintf(w, "🧬 Identical logo files: %d groups\n", len(identical))
+	for _, g := range identical {
+		// The first sponsor in site order keeps its file.
+		sort.SliceStable(g, func(i, j int) bool { return firstSponsor(g[i]) < firstSponsor(g[j]) })
+		for _, f := range g {
+			fmt.Fprintf(w, "   %-40s ← %s\n", rel(f.Path), who(f))
+		}
+		if !share || len(g[0].Sponsors) == 0 {
+			continue
+		}
+		keep := sponsors[g[0].Sponsors[0]]
+		for _, f := range g[1:] {
+			for _, si := range f.Sponsors {
+				s := &sponsors[si]
+				fmt.Fprintf(w, "   🔗 %s now shares %s\n", s.Name, keep.Logo)
+				s.Logo, s.LogoPng = keep.Logo, keep.LogoPng
+				changed = true
+			}
+		}
+	}
+
+	if maxBits >= 0 {
+		fmt.Fprintf(w, "🧬 Near-identical logos (≤ %d of 64 bits differ): %d pairs\n", maxBits, len(similar))
+		for _, p := range similar {
+			fmt.Fprintf(w, "   %2d  %s (%s) ~ %s (%s)\n", p.Distance, rel(p.A.Path), who(p.A), rel(p.B.Path), who(p.B))
+		}
+	}
+
+	byIG := make(map[string][]string)
+	var handles []string
+	for _, s := range sponsors {
+		h := strings.ToLower(igHandle(s.Instagram))
+		if h == "" {
+			continue
+		}
+		if len(byIG[h]) == 0 {
+			handles = append(handles, h)
+		}
+		byIG[h] = append(byIG[h], s.Name)
+	}
+	shared := 0
+	for _, h := range handles {
+		if len(byIG[h]) > 1 {
+			if shared == 0 {
+				fmt.Fprintln(w, "📸 Sponsors sharing an Instagram handle:")
+			}
+			shared++
+			fmt.Fprintf(w, "   @%-28s %s\n", h, strings.Join(byIG[h], ", "))
+		}
+	}
+	return changed
+}
+
+// firstSponsor is the site-order position of f's first sponsor; files no
+// sponsor uses sort last.
+func firstSponsor(f *logoFile) int {
+	if len(f.Sponsors) == 0 {
+		return math.MaxInt
+	}
+	return f.Sponsors[0]
+}
+
+// ---- orphaned logos ----
+
+// brokenRef is a sponsor logo path that points at no file.
+type brokenRef struct {
+	Sponsor string
+	Field   string // logo | logoPng
+	Path    string // local path that's missing
+	Hint    string // a same-named file with another extension, if any
+}
+
+// findOrphans cross-references the logo directory with every sponsor's logo
+// and logoPng. Orphans are files no sponsor points at (including leftover
+// .part-* downloads); broken references are sponsor paths with no file.
+func findOrphans(logoDir, publicDir string, sponsors []Sponsor) (orphans []string, broken []brokenRef, err error) {
+	used := make(map[string]bool)
+	for _, s := range sponsors {
+		for _, ref := range []struct{ field, val string }{{"logo", s.Logo}, {"logoPng", s.LogoPng}} {
+			if strings.TrimSpace(ref.val) == "" {
+				continue
+			}
+			p := filepath.Clean(desiredLocalLogoPath(publicDir, logoDir, Sponsor{Logo: ref.val}))
+			used[p] = true
+			if fileExists(p) {
+				continue
+			}
+			broken = append(broken, brokenRef{Sponsor: s.Name, Field: ref.field, Path: p, Hint: sameStemFile(p)})
+		}
+	}
+
+	entries, err := os.ReadDir(logoDir)
+	if err != nil {
+		return nil, nil, err
+	}
+	for _, e := range entries {
+		name := e.Name()
+		if e.IsDir() || (strings.HasPrefix(name, ".") && !strings.HasPrefix(name, ".part-")) {
+			continue
+		}
+		if p := filepath.Clean(filepath.Join(logoDir, name)); !used[p] {
+			orphans = append(orphans, p)
+		}
+	}
+	return orphans, broken, nil
+}
+
+// sameStemFile returns a file named like missing p but with another
+// extension (or none), if there is one.
+func sameStemFile(p string) string {
+	stem := strings.TrimSuffix(p, filepath.Ext(p))
+	if m, _ := filepath.Glob(globEscape(stem) + ".*"); len(m) > 0 {
+		return m[0]
+	}
+	if fileExists(stem) {
+		return stem
+	}
+	return ""
+}
+
+// moveToTrash moves files into a timestamped folder under trashDir, so a
+// prune can be undone by moving them back. It returns the folder used.
+func moveToTrash(files []string, trashDir string) (string, error) {
+	dir := filepath.Join(trashDir, time.Now().Format("20060102-150405"))
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return "", err
+	}
+	for _, f := range files {
+		if err := os.Rename(f, filepath.Join(dir, filepath.Base(f))); err != nil {
+			return dir, err
+		}
+	}
+	return dir, nil
+}
+
+// reportOrphans prints the prune report.
+func reportOrphans(w io.Writer, orphans []string, broken []brokenRef) {
+	fmt.Fprintf(w, "🗑  Unreferenced logo files: %d\n", len(orphans))
+	for _, p := range orphans {
+		note := ""
+		if filepath.Ext(p) == "" {
+			note = "  (no extension)"
+		}
+		fmt.Fprintf(w, "   %s%s\n", rel(p), note)
+	}
+	fmt.Fprintf(w, "💔 Broken sponsor references: %d\n", len(broken))
+	for _, b := range broken {
+		fmt.Fprintf(w, "   %-30s %s → %s missing", b.Sponsor, b.Field, rel(b.Path))
+		if b.Hint != "" {
+			fmt.Fprintf(w, " (found %s)", rel(b.Hint))
+		}
+		fmt.Fprintln(w)
+	}
+}
+
+// ---- sponsor normalization ----
+
+// normalizeRules is the mapping file read by "sponsors normalize": the
+// canonical form of known type and category spellings. Keys match after
+// trimming and lowercasing; mapping to "" drops the value.
+type normalizeRules struct {
+	Types      map[string]string `json:"types"`
+	Categories map[string]string `json:"categories"`
+}
+
+func loadNormalizeRules(path string) (normalizeRules, error) {
+	var r normalizeRules
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return r, err
+	}
+	if err := json.Unmarshal(b, &r); err != nil {
+		return r, err
+	}
+	r.Types = lowerKeys(r.Types)
+	r.Categories = lowerKeys(r.Categories)
+	return r, nil
+}
+
+func lowerKeys(m map[string]string) map[string]string {
+	out := make(map[string]string, len(m))
+	for k, v := range m {
+		out[strings.ToLower(strings.TrimSpace(k))] = v
+	}
+	return out
+}
+
+// canonical returns v trimmed and lowercased, replaced by its mapping if
+// there is one.
+func (r normalizeRules) canonical(m map[string]string, v string) string {
+	v = strings.ToLower(strings.TrimSpace(v))
+	if to, ok := m[v]; ok {
+		return to
+	}
+	return v
+}
+
+// normalizeSponsor returns s in canonical form, with one line per change.
+// Strings are trimmed, Instagram values become bare handles, types and
+// categories are lowercased and mapped through r, and categories are
+// deduped. Fields left empty are removed when written with dropEmpty.
+func normalizeSponsor(s Sponsor, r normalizeRules) (Sponsor, []string) {
+	var changes []string
+	set := func(key string, field *string, v string) {
+		if v == *field {
+			return
+		}
+		if v == "" {
+			changes = append(changes, fmt.Sprintf("%s: %q removed", key, *field))
+		} else {
+			changes = append(changes, fmt.Sprintf("%s: %q → %q", key, *field, v))
+		}
+		*field = v
+	}
+	set("name", &s.Name, strings.TrimSpace(s.Name))
+	set("href", &s.Href, strings.TrimSpace(s.Href))
+	set("instagram", &s.Instagram, igHandle(s.Instagram))
+	set("logo", &s.Logo, strings.TrimSpace(s.Logo))
+	set("type", &s.Type, r.canonical(r.Types, s.Type))
+
+	if s.Category != nil {
+		cats := make([]string, 0, len(s.Category))
+		seen := make(map[string]bool)
+		for _, c := range s.Category {
+			c = r.canonical(r.Categories, c)
+			if c == "" || seen[c] {
+				continue
+			}
+			seen[c] = true
+			cats = append(cats, c)
+		}
+		if !reflect.DeepEqual(cats, s.Category) {
+			changes = append(changes, fmt.Sprintf("category: %q → %q", s.Category, cats))
+			s.Category = cats
+		}
+	}
+	return s, changes
+}
+
+// emptySponsorFields lists, per sponsor in doc, the string fields that are
+// present but "".
+func emptySponsorFields(doc []byte) ([][]string, error) {
+	fields, err := sponsorFields(Sponsor{})
+	if err != nil {
+		return nil, err
+	}
+	isString := make(map[string]bool)
+	for _, f := range fields {
+		_, isString[f.key] = f.v.(string)
+	}
+	root, err := content.Root(doc)
+	if err != nil {
+		return nil, err
+	}
+	top, err := content.Members(doc, root)
+	if err != nil {
+		return nil, err
+	}
+	var elems []content.Span
+	for _, m := range top {
+		if m.Key == "sponsors" {
+			if elems, err = content.Elems(doc, m.Val); err != nil {
+				return nil, err
+			}
+		}
+	}
+	out := make([][]string, len(elems))
+	for i, el := range elems {
+		members, err := content.Members(doc, el)
+		if err != nil {
+			return nil, fmt.Errorf("sponsor %d: %w", i, err)
+		}
+		for _, m := range members {
+			if isString[m.Key] && string(doc[m.Val.Start:m.Val.End]) == `""` {
+				out[i] = append(out[i], m.Key)
+			}
+		}
+	}
+	return out, nil
+}
+
+// ---- link checking ----
+
+// linkGroup is how a checked link is reported.
+type linkGroup int
+
+const (
+	linkOK       linkGroup = iota
+	linkSkipped            // a site route, not a file under public/
+	linkRedirect           // works, through redirects worth updating
+	linkSoft404            // answers 200, but with a not-found page
+	linkBlocked            // turns checkers away (401/403/429/999); check by hand
+	linkDead               // error status, network failure, or missing file
+)
+
+// linkResult is one distinct URL in the content files and what checking it
+// found.
+type linkResult struct {
+	URL    string
+	Where  []string // e.g. site.json sponsors[3] "Acme Tacos".href
+	Group  linkGroup
+	Status int
+	Hops   []int  // statuses of the redirects followed, in order
+	Final  string // URL after redirects
+	Detail string
+}
+
+// collectLinks gathers the links of site.json at sitePath and of the other
+// content files beside it, one result per distinct URL, in document order.
+func collectLinks(sitePath string) ([]*linkResult, error) {
+	site, _, err := content.LoadSite(sitePath)
+	if err != nil {
+		return nil, err
+	}
+	models := []interface{}{site}
+	names := []string{filepath.Base(sitePath)}
+	dir := filepath.Dir(sitePath)
+	for _, f := range []struct {
+		name string
+		load func(string) (interface{}, error)
+	}{
+		{filepath.Base(content.TicketsFile), func(p string) (interface{}, error) { v, _, err := content.LoadTickets(p); return v, err }},
+		{filepath.Base(content.FAQFile), func(p string) (interface{}, error) { v, _, err := content.LoadFAQ(p); return v, err }},
+		{filepath.Base(content.InstagramFile), func(p string) (interface{}, error) { v, _, err := content.LoadInstagram(p); return v, err }},
+	} {
+		v, err := f.load(filepath.Join(dir, f.name))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		models = append(models, v)
+		names = append(names, f.name)
+	}
+
+	var results []*linkResult
+	byURL := make(map[string]*linkResult)
+	for i, m := range models {
+		for _, l := range content.FindLinks(m) {
+			r := byURL[l.URL]
+			if r == nil {
+				r = &linkResult{URL: l.URL}
+				byURL[l.URL] = r
+				results = append(results, r)
+			}
+			r.Where = append(r.Where, names[i]+" "+l.Path)
+		}
+	}
+	return results, nil
+}
+
+// maxLinkHops caps the redirects followed for one link.
+const maxLinkHops = 10
+
+// checkLinks checks every link, concurrency at a time, filling in its result.
+func checkLinks(client *http.Client, links []*linkResult, publicDir string, concurrency int) {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	jobs := make(chan *linkResult)
+	var wg sync.WaitGroup
+	for w := 0; w < concurrency; w++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for r := range jobs {
+				if strings.HasPrefix(r.URL, "/") {
+					checkLocalLink(r, publicDir)
+				} else {
+					checkRemoteLink(client, r)
+				}
+			}
+		}()
+	}
+	for _, r := range links {
+		jobs <- r
+	}
+	close(jobs)
+	wg.Wait()
+}
+
+// checkLocalLink checks a /path on the site. Paths under /docs/ and /images/
+// must be files in publicDir; anything else is a route the app serves.
+func checkLocalLink(r *linkResult, publicDir string) {
+	u, err := url.Parse(r.URL)
+	if err != nil {
+		r.Group, r.Detail = linkDead, err.Error()
+		return
+	}
+	if !strings.HasPrefix(u.Path, "/docs/") && !strings.HasPrefix(u.Path, "/images/") {
+		r.Group = linkSkipped
+		return
+	}
+	p := filepath.Join(publicDir, filepath.FromSlash(strings.TrimPrefix(u.Path, "/")))
+	if fileExists(p) {
+		r.Group = linkOK
+		return
+	}
+	r.Group, r.Detail = linkDead, rel(p)+" does not exist"
+	if hint := sameStemFile(p); hint != "" {
+		r.Detail += " (found " + rel(hint) + ")"
+	}
+}
+
+// checkRemoteLink GETs an absolute URL, following redirects, and sorts it
+// into a group by where it ends up.
+func checkRemoteLink(client *http.Client, r *linkResult) {
+	if !isAbsoluteURL(r.URL) {
+		r.Group, r.Detail = linkDead, "not an absolute http(s) URL"
+		return
+	}
+	c := *client
+	c.CheckRedirect = func(req *http.Request, via []*http.Request) error {
+		if len(via) > maxLinkHops {
+			return fmt.Errorf("more than %d redirects", maxLinkHops)
+		}
+		r.Hops = append(r.Hops, req.Response.StatusCode)
+		return nil
+	}
+	req, err := http.NewRequest("GET", r.URL, nil)
+	if err != nil {
+		r.Group, r.Detail = linkDead, err.Error()
+		return
+	}
+	req.Header.Set("User-Agent", userAgent)
+	resp, err := c.Do(req)
+	if err != nil {
+		r.Group, r.Detail = linkDead, err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	r.Status, r.Final = resp.StatusCode, resp.Request.URL.String()
+
+	switch resp.StatusCode {
+	case http.StatusUnauthorized, http.StatusForbidden, http.StatusTooManyRequests, 999:
+		r.Group = linkBlocked
+		return
+	}
+	if resp.StatusCode >= 400 {
+		r.Group = linkDead
+		return
+	}
+	var body []byte
+	if strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
+		body, _ = io.ReadAll(io.LimitReader(resp.Body, 512<<10))
+	}
+	if why := soft404(r.URL, resp.Request.URL, body); why != "" {
+		r.Group, r.Detail = linkSoft404, why
+		return
+	}
+	if len(r.Hops) > 0 && movedFor(r.URL, r.Final) {
+		r.Group = linkRedirect
+		return
+	}
+	r.Group = linkOK
+}
+
+// movedFor reports whether a link to from that lands on to is worth
+// updating: anything but an http→https upgrade or a trailing slash.
+func movedFor(from, to string) bool {
+	a, err1 := url.Parse(from)
+	b, err2 := url.Parse(to)
+	if err1 != nil || err2 != nil {
+		return true
+	}
+	return !strings.EqualFold(a.Host, b.Host) ||
+		strings.TrimSuffix(a.Path, "/") != strings.TrimSuffix(b.Path, "/") ||
+		a.RawQuery != b.RawQuery
+}
+
+// notFoundPhrases mark pages that answer 200 but say the thing is gone.
+var notFoundPhrases = []string{
+	"page not found",
+	"404 not found",
+	"error 404",
+	"page doesn't exist",
+	"page does not exist",
+	"page isn't available",
+	"content isn't available",
+	"no longer accepting responses",
+	"this form is no longer",
+}
+
+var reNotFoundPath = regexp.MustCompile(`(?i)(^|/)(404|not[-_]?found|page[-_]?not[-_]?found)(\.html?)?/?$`)
+
+// soft404 explains why a 200 answer to link, landing on final with HTML
+// body, is a not-found page, or returns "".
+func soft404(link string, final *url.URL, body []byte) string {
+	if u, err := url.Parse(link); err == nil && strings.Trim(u.Path, "/") != "" &&
+		strings.Trim(final.Path, "/") == "" && final.RawQuery == "" {
+		return "redirects to the home page"
+	}
+	if reNotFoundPath.MatchString(final.Path) {
+		return "lands on " + final.Path
+	}
+	if len(body) == 0 {
+		return ""
+	}
+	title := ""
+	if t := parseHTML(body).findAll("title"); len(t) > 0 {
+		title = strings.ToLower(strings.TrimSpace(html.UnescapeString(t[0].text)))
+	}
+	if strings.HasPrefix(title, "404") || strings.Contains(title, "not found") {
+		return fmt.Sprintf("title %q", title)
+	}
+	text := strings.ToLower(string(body))
+	for _, p := range notFoundPhrases {
+		if strings.Contains(text, p) {
+			return fmt.Sprintf("page says %q", p)
+		}
+	}
+	return ""
+}
+
+// reportLinks prints the links that need attention by group, each with where
+// it's used, and a summary. It reports whether any link is dead.
+func reportLinks(w io.Writer, links []*linkResult) bool {
+	groups := []struct {
+		group linkGroup
+		title string
+	}{
+		{linkDead, "💀 Dead links"},
+		{linkSoft404, "👻 Soft 404s (200, but a not-found page)"},
+		{linkRedirect, "↪️  Redirects to update"},
+		{linkBlocked, "🚧 Blocked by the site (check by hand)"},
+	}
+	counts := make(map[linkGroup]int)
+	for _, r := range links {
+		counts[r.Group]++
+	}
+	for _, g := range groups {
+		if counts[g.group] == 0 {
+			continue
+		}
+		fmt.Fprintf(w, "%s: %d\n", g.title, counts[g.group])
+		for _, r := range links {
+			if r.Group != g.group {
+				continue
+			}
+			fmt.Fprintf(w, "   %s\n", r.URL)
+			switch {
+			case r.Group == linkRedirect:
+				fmt.Fprintf(w, "      → %s (%s)\n", r.Final, hopString(r.Hops))
+			case r.Detail != "":
+				fmt.Fprintf(w, "      %s\n", r.Detail)
+			case r.Status != 0:
+				fmt.Fprintf(w, "      status %d\n", r.Status)
+			}
+			for _, at := range r.Where {
+				fmt.Fprintf(w, "      in %s\n", at)
+			}
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "Summary: %d ok, %d dead, %d soft 404, %d to update, %d blocked, %d site routes skipped\n",
+		counts[linkOK], counts[linkDead], counts[linkSoft404], counts[linkRedirect], counts[linkBlocked], counts[linkSkipped])
+	return counts[linkDead] > 0
+}
+
+func hopString(hops []int) string {
+	s := make([]string, len(hops))
+	for i, h := range hops {
+		s[i] = strconv.Itoa(h)
+	}
+	return strings.Join(s, " → ")
+}
+
+// ---- content validation ----
+
+type ruleKind int
+
+const (
+	ruleString ruleKind = iota
+	ruleBool
+	ruleInt
+	ruleNumber
+	ruleURL    // absolute http(s) URL
+	ruleLink   // absolute URL or a path on the site
+	ruleEmail  // bare address
+	ruleDate   // RFC 3339 timestamp
+	ruleHandle // Instagram handle, without @ or URL
+	ruleAsset  // site path of a file that must exist under the public directory
+	ruleObject
+	ruleArray
+)
+
+// contentRule describes a value in a content file. Rules are built from the
+// content models (see ruleFor). Optional strings may be empty; enum values
+// may not.
+type contentRule struct {
+	kind     ruleKind
+	required bool
+	enum     []string
+	fields   map[string]*contentRule // ruleObject; other keys are reported
+	elem     *contentRule            // ruleArray
+}
+
+var siteRule = ruleFor(reflect.TypeOf(content.SiteContent{}), content.Field{})
+
+// stringRules maps the content tag kinds to rule kinds.
+var stringRules = map[string]ruleKind{
+	"url":    ruleURL,
+	"link":   ruleLink,
+	"email":  ruleEmail,
+	"date":   ruleDate,
+	"handle": ruleHandle,
+	"asset":  ruleAsset,
+}
+
+// ruleFor returns the rule for values of type t, held by field f.
+func ruleFor(t reflect.Type, f content.Field) *contentRule {
+	r := &contentRule{required: f.Required}
+	switch t.Kind() {
+	case reflect.Ptr:
+		return ruleFor(t.Elem(), f)
+	case reflect.Struct:
+		r.kind = ruleObject
+		r.fields = make(map[string]*contentRule)
+		for _, sub := range content.Fields(t) {
+			r.fields[sub.Key] = ruleFor(sub.Type, sub)
+		}
+	case reflect.Slice:
+		r.kind = ruleArray
+		r.elem = ruleFor(t.Elem(), content.Field{Kind: f.Kind, Enum: f.Enum})
+	case reflect.Bool:
+		r.kind = ruleBool
+	case reflect.Int:
+		r.kind = ruleInt
+	case reflect.Float64:
+		r.kind = ruleNumber
+	default:
+		r.kind = stringRules[f.Kind] // ruleString for none
+		r.enum = f.Enum
+	}
+	return r
+}
+
+var (
+	reHandle = regexp.MustCompile(content.HandlePattern)
+	reEmail  = regexp.MustCompile(content.EmailPattern)
+)
+
+// contentProblem is one validation failure, located by line.
+type contentProblem struct {
+	Line int
+	Path string // e.g. sponsors[3] "Acme Tacos".type
+	Msg  string
+}
+
+func (p contentProblem) String() string {
+	return fmt.Sprintf("%d: %s: %s", p.Line, p.Path, p.Msg)
+}
+
+// validateContent checks doc against rule. Asset paths are looked up under
+// publicDir.
+func validateContent(doc []byte, rule *contentRule, publicDir string) ([]contentProblem, error) {
+	root, err := content.Root(doc)
+	if err != nil {
+		return nil, err
+	}
+	v := &validator{doc: doc, publicDir: publicDir}
+	v.check(rule, "", root)
+	sort.SliceStable(v.problems, func(i, j int) bool { return v.problems[i].Line < v.problems[j].Line })
+	return v.problems, v.err
+}
+
+type validator struct {
+	doc       []byte
+	publicDir string
+	problems  []contentProblem
+	err       error
+}
+
+func (v *validator) report(at int, path, format string, args ...interface{}) {
+	line := content.Line(v.doc, at)
+	if path == "" {
+		path = "(top level)"
+	}
+	v.problems = append(v.problems, contentProblem{Line: line, Path: path, Msg: fmt.Sprintf(format, args...)})
+}
+
+func (v *validator) check(r *contentRule, path string, span content.Span) {
+	raw := v.doc[span.Start:span.End]
+	switch r.kind {
+	case ruleObject:
+		if raw[0] != '{' {
+			v.report(span.Start, path, "want an object, got %s", truncBytes(raw, 40))
+			return
+		}
+		members, err := content.Members(v.doc, span)
+		if err != nil {
+			v.err = err
+			return
+		}
+		seen := make(map[string]bool, len(members))
+		for _, m := range members {
+			seen[m.Key] = true
+			sub := path + "." + m.Key
+			if path == "" {
+				sub = m.Key
+			}
+			fr := r.fields[m.Key]
+			if fr == nil {
+				v.report(m.KeyStart, path, "unknown field %q", m.Key)
+				continue
+			}
+			v.check(fr, sub, m.Val)
+		}
+		for _, k := range sortedKeys(r.fields) {
+			if r.fields[k].required && !seen[k] {
+				v.report(span.Start, path, "missing required field %q", k)
+			}
+		}
+	case ruleArray:
+		if raw[0] != '[' {
+			v.report(span.Start, path, "want an array, got %s", truncBytes(raw, 40))
+			return
+		}
+		elems, err := content.Elems(v.doc, span)
+		if err != nil {
+			v.err = err
+			return
+		}
+		seen := make(map[string]bool)
+		for i, el := range elems {
+			label := fmt.Sprintf("%s[%d]", path, i)
+			var named struct{ Name string }
+			if json.Unmarshal(v.doc[el.Start:el.End], &named) == nil && named.Name != "" {
+				label += fmt.Sprintf(" %q", named.Name)
+			}
+			if r.elem.enum != nil {
+				if k := string(v.doc[el.Start:el.End]); seen[k] {
+					v.report(el.Start, label, "duplicate %s", k)
+				} else {
+					seen[k] = true
+				}
+			}
+			v.check(r.elem, label, el)
+		}
+	case ruleBool:
+		if string(raw) != "true" && string(raw) != "false" {
+			v.report(span.Start, path, "want true or false, got %s", truncBytes(raw, 40))
+		}
+	case ruleInt:
+		if _, err := strconv.Atoi(string(raw)); err != nil {
+			v.report(span.Start, path, "want a whole number, got %s", truncBytes(raw, 40))
+		}
+	case ruleNumber:
+		if _, err := strconv.ParseFloat(string(raw), 64); err != nil {
+			v.report(span.Start, path, "want a number, got %s", truncBytes(raw, 40))
+		}
+	default:
+		var s string
+		if err := json.Unmarshal(raw, &s); err != nil {
+			v.report(span.Start, path, "want a string, got %s", truncBytes(raw, 40))
+			return
+		}
+		if msg := v.checkString(r, s); msg != "" {
+			v.report(span.Start, path, "%s", msg)
+		}
+	}
+}
+
+// checkString explains what's wrong with s under r, or returns "".
+func (v *validator) checkString(r *contentRule, s string) string {
+	if r.enum != nil {
+		for _, e := range r.enum {
+			if s == e {
+				return ""
+			}
+		}
+		return fmt.Sprintf("%q is not one of %s", s, strings.Join(r.enum, ", "))
+	}
+	if strings.TrimSpace(s) == "" {
+		if r.required {
+			return "required, but empty"
+		}
+		return ""
+	}
+	if strings.TrimSpace(s) != s {
+		return fmt.Sprintf("%q has leading or trailing spaces", s)
+	}
+	switch r.kind {
+	case ruleURL:
+		if !isAbsoluteURL(s) {
+			return fmt.Sprintf("%q is not an http(s) URL", s)
+		}
+	case ruleLink:
+		if !isAbsoluteURL(s) && !strings.HasPrefix(s, "/") {
+			return fmt.Sprintf("%q is neither an http(s) URL nor a /path on the site", s)
+		}
+	case ruleEmail:
+		if !reEmail.MatchString(s) {
+			return fmt.Sprintf("%q is not an email address", s)
+		}
+	case ruleDate:
+		if _, err := time.Parse(time.RFC3339, s); err != nil {
+			return fmt.Sprintf("%q is not an RFC 3339 date (2006-01-02T15:04:05-07:00)", s)
+		}
+	case ruleHandle:
+		if !reHandle.MatchString(s) {
+			return fmt.Sprintf("%q is not an Instagram handle (no @ or URL)", s)
+		}
+	case ruleAsset:
+		if !strings.HasPrefix(s, "/") {
+			return fmt.Sprintf("%q is not a /path on the site", s)
+		}
+		p := filepath.Join(v.publicDir, filepath.FromSlash(strings.TrimPrefix(s, "/")))
+		if !fileExists(p) {
+			if hint := sameStemFile(p); hint != "" {
+				return fmt.Sprintf("%s does not exist (found %s)", rel(p), rel(hint))
+			}
+			return fmt.Sprintf("%s does not exist", rel(p))
+		}
+	}
+	return ""
+}
+
+func isAbsoluteURL(s string) bool {
+	u, err := url.Parse(s)
+	return err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != ""
+}
+
+func sortedKeys(m map[string]*contentRule) []string {
+	ks := make([]string, 0, len(m))
+	for k := range m {
+		ks = append(ks, k)
+	}
+	sort.Strings(ks)
+	return ks
+}
+
+// ---- HTML parsing ----
+
+// htmlNode is one element in the lightweight parse tree built by parseHTML.
+// Text is only kept for raw-text elements (script, style) where callers need
+// it; start/end are byte offsets of the element's markup in the source.
+type htmlNode struct {
+	tag      string // lowercased; "" for the document root
+	attrs    []htmlAttr
+	text     string
+	parent   *htmlNode
+	children []*htmlNode
+	start    int
+	end      int
+}
+
+type htmlAttr struct {
+	key string // lowercased
+	val string // entity-decoded
+}
+
+var voidElements = map[string]bool{
+	"area": true, "base": true, "br": true, "col": true, "embed": true, "hr": true, "img": true,
+	"input": true, "link": true, "meta": true, "param": true, "source": true, "track": true, "wbr": true,
+}
+
+var rawTextElements = map[string]bool{"script": true, "style": true, "textarea": true, "title": true}
+
+// parseHTML tokenizes src and builds a forgiving element tree. It accepts
+// attributes in any order, quoted or unquoted, and recovers from stray or
+// missing end tags by closing up to the nearest matching open element.
+func parseHTML(src []byte) *htmlNode {
+	root := &htmlNode{end: len(src)}
+	cur := root
+	i := 0
+	for i < len(src) {
+		lt := bytes.IndexByte(src[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt
+		rest := src[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			if j := bytes.Index(rest[4:], []byte("-->")); j >= 0 {
+				i += 4 + j + 3
+			} else {
+				i = len(src)
+			}
+		case len(rest) > 1 && (rest[1] == '!' || rest[1] == '?'):
+			i = skipPast(src, i, '>')
+		case len(rest) > 2 && rest[1] == '/' && isASCIILetter(rest[2]):
+			name, _ := readTagName(src, i+2)
+			i = skipPast(src, i, '>')
+			for n := cur; n != root; n = n.parent {
+				if n.tag == name {
+					for c := cur; c != n.parent; c = c.parent {
+						c.end = i
+					}
+					cur = n.parent
+					break
+				}
+			}
+		case len(rest) > 1 && isASCIILetter(rest[1]):
+			n, next, selfClosing := readStartTag(src, i)
+			n.parent = cur
+			cur.children = append(cur.children, n)
+			i = next
+			switch {
+			case rawTextElements[n.tag]:
+				end := indexFold(src[i:], "</"+n.tag)
+				if end < 0 {
+					end = len(src) - i
+				}
+				n.text = string(src[i : i+end])
+				i = skipPast(src, i+end, '>')
+				n.end = i
+			case selfClosing || voidElements[n.tag]:
+				n.end = i
+			default:
+				cur = n
+			}
+		default:
+			i++
+		}
+	}
+	for c := cur; c != root; c = c.parent {
+		c.end = len(src)
+	}
+	return root
+}
+
+// readStartTag parses "<name attr=val ...>" starting at src[i] == '<'.
+func readStartTag(src []byte, i int) (n *htmlNode, next int, selfClosing bool) {
+	n = &htmlNode{start: i}
+	n.tag, i = readTagName(src, i+1)
+	for i < len(src) {
+		for i < len(src) && isHTMLSpace(src[i]) {
+			i++
+		}
+		if i >= len(src) {
+			break
+		}
+		if src[i] == '>' {
+			return n, i + 1, selfClosing
+		}
+		if src[i] == '/' {
+			selfClosing = true
+			i++
+			continue
+		}
+		selfClosing = false
+		j := i
+		for j < len(src) && !isHTMLSpace(src[j]) && src[j] != '=' && src[j] != '>' && (src[j] != '/' || j == i) {
+			j++
+		}
+		key := strings.ToLower(string(src[i:j]))
+		i = j
+		for i < len(src) && isHTMLSpace(src[i]) {
+			i++
+		}
+		val := ""
+		if i < len(src) && src[i] == '=' {
+			i++
+			for i < len(src) && isHTMLSpace(src[i]) {
+				i++
+			}
+			if i < len(src) && (src[i] == '"' || src[i] == '\'') {
+				q := src[i]
+				end := bytes.IndexByte(src[i+1:], q)
+				if end < 0 {
+					end = len(src) - i - 1
+				}
+				val = string(src[i+1 : i+1+end])
+				i += end + 2
+			} else {
+				j = i
+				for j < len(src) && !isHTMLSpace(src[j]) && src[j] != '>' {
+					j++
+				}
+				val = string(src[i:j])
+				i = j
+			}
+		}
+		if key != "" {
+			n.attrs = append(n.attrs, htmlAttr{key: key, val: html.UnescapeString(val)})
+		}
+	}
+	return n, len(src), selfClosing
+}
+
+func readTagName(src []byte, i int) (string, int) {
+	j := i
+	for j < len(src) && !isHTMLSpace(src[j]) && src[j] != '>' && src[j] != '/' {
+		j++
+	}
+	return strings.ToLower(string(src[i:j])), j
+}
+
+func skipPast(src []byte, i int, c byte) int {
+	if j := bytes.IndexByte(src[i:], c); j >= 0 {
+		return i + j + 1
+	}
+	return len(src)
+}
+
+// indexFold is a case-insensitive bytes.Index for an ASCII needle.
+func indexFold(s []byte, needle string) int {
+	n := len(needle)
+	for i := 0; i+n <= len(s); i++ {
+		if strings.EqualFold(string(s[i:i+n]), needle) {
+			return i
+		}
+	}
+	return -1
+}
+
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
+
+// attr returns the value of key, or "".
+func (n *htmlNode) attr(key string) string {
+	for _, a := range n.attrs {
+		if a.key == key {
+			return a.val
+		}
+	}
+	return ""
+}
+
+// attrOr returns the first non-empty value among keys.
+func (n *htmlNode) attrOr(keys ...string) string {
+	for _, k := range keys {
+		if v := strings.TrimSpace(n.attr(k)); v != "" {
+			return v
+		}
+	}
+	return ""
+}
+
+// walk visits n's descendants depth-first in document order. Returning false
+// from fn skips that node's children.
+func (n *htmlNode) walk(fn func(*htmlNode) bool) {
+	for _, c := range n.children {
+		if fn(c) {
+			c.walk(fn)
+		}
+	}
+}
+
+// findAll returns every descendant with the given tag.
+func (n *htmlNode) findAll(tag string) []*htmlNode {
+	var out []*htmlNode
+	n.walk(func(c *htmlNode) bool {
+		if c.tag == tag {
+			out = append(out, c)
+		}
+		return true
+	})
+	return out
+}
+
+// closest returns the nearest ancestor matching pred, or nil.
+func (n *htmlNode) closest(pred func(*htmlNode) bool) *htmlNode {
+	for p := n.parent; p != nil && p.tag != ""; p = p.parent {
+		if pred(p) {
+			return p
+		}
+	}
+	return nil
+}
+
+// outer returns a copy of the element's source markup.
+func (n *htmlNode) outer(src []byte) []byte {
+	return append([]byte(nil), src[n.start:n.end]...)
+}
+
+// baseURL applies the document's <base href>, if any, to the page URL.
+func (n *htmlNode) baseURL(page *url.URL) *url.URL {
+	for _, b := range n.findAll("base") {
+		if h := strings.TrimSpace(b.attr("href")); h != "" {
+			if u, err := url.Parse(h); err == nil {
+				return page.ResolveReference(u)
+			}
+		}
+	}
+	return page
+}
+
+// ---- HTTP client (politeness + retries) ----
+
+// newHTTPClient returns the client shared by every outbound call. Its
+// transport spaces requests to the same host at most perHostRate per second
+// and retries 429/5xx responses and timeouts with exponential backoff
+// (GET/HEAD only; see shouldRetry).
+func newHTTPClient(perHostRate float64, retries int) *http.Client {
+	base := &http.Transport{
+		Proxy: http.ProxyFromEnvironment,
+		DialContext: (&net.Dialer{
+			Timeout:   15 * time.Second,
+			KeepAlive: 30 * time.Second,
+		}).DialContext,
+		TLSHandshakeTimeout:   15 * time.Second,
+		ResponseHeaderTimeout: 30 * time.Second,
+		MaxIdleConnsPerHost:   4,
+		IdleConnTimeout:       90 * time.Second,
+	}
+	var interval time.Duration
+	if perHostRate > 0 {
+		interval = time.Duration(float64(time.Second) / perHostRate)
+	}
+	if retries < 0 {
+		retries = 0
+	}
+	return &http.Client{
+		// Overall cap per call, including retries and backoff.
+		Timeout: 2 * time.Minute,
+		Transport: &politeTransport{
+			base:     base,
+			interval: interval,
+			retries:  retries,
+			next:     make(map[string]time.Time),
+		},
+	}
+}
+
+// politeTransport is an http.RoundTripper that rate-limits per host and
+// retries transient failures.
+type politeTransport struct {
+	base     http.RoundTripper
+	interval time.Duration // minimum spacing between requests to one host
+	retries  int
+
+	mu   sync.Mutex
+	next map[string]time.Time // host → earliest time the next request may start
+}
+
+const (
+	backoffBase   = 500 * time.Millisecond
+	backoffMax    = 15 * time.Second
+	retryAfterMax = 60 * time.Second
+)
+
+func (t *politeTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	host := strings.ToLower(req.URL.Host)
+	for attempt := 0; ; attempt++ {
+		if err := t.wait(req.Context(), host); err != nil {
+			return nil, err
+		}
+		if attempt > 0 {
+			// Bodies are consumed by the previous attempt; rewind if possible.
+			if req.Body != nil && req.GetBody == nil {
+				return nil, fmt.Errorf("retry %s: request body not rewindable", req.URL)
+			}
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(req.Context())
+				req.Body = body
+			}
+		}
+
+		resp, err := t.base.RoundTrip(req)
+		if attempt >= t.retries || !shouldRetry(req, resp, err) {
+			return resp, err
+		}
+
+		delay := backoffDelay(attempt)
+		if resp != nil {
+			if ra, ok := retryAfter(resp.Header.Get("Retry-After")); ok {
+				delay = ra
+			}
+			// Drain so the connection can be reused.
+			io.Copy(io.Discard, io.LimitReader(resp.Body, 64<<10))
+			resp.Body.Close()
+			dbg("retry %s %s: status=%d attempt=%d wait=%s", req.Method, req.URL, resp.StatusCode, attempt+1, delay)
+		} else {
+			dbg("retry %s %s: %v attempt=%d wait=%s", req.Method, req.URL, err, attempt+1, delay)
+		}
+		// Push the whole host back so other workers don't pile on meanwhile.
+		t.holdOff(host, delay)
 	}
-	dbg("%s", b.String())
 }
 
-func main() {
-	// Flags
-	envFile := flag.String("env-file", ".env", "Path to .env file to load (optional)")
-	siteJSONPath := flag.String("site", "app/content/site.json", "Path to site.json")
-	publicDir := flag.String("public", "public", "Public directory (where images/ lives)")
-	onlyActive := flag.Bool("only-active", false, "Process only active sponsors")
-	cats := flag.String("categories", "", "Comma-separated category filter (e.g. sponsor,chili)")
-	dryRun := flag.Bool("dry-run", false, "Run without downloading or writing changes")
-	enrich := flag.Bool("enrich-missing", false, "Discover missing sponsor href/instagram via search")
-	searchProvider := flag.String("search-provider", "hybrid", "Search provider: serpapi|openai|hybrid")
-	serpAPIKey := flag.String("serpapi-key", "", "SerpAPI key (or SERPAPI_KEY env / .env)")
-	openAIKey := flag.String("openai-key", "", "OpenAI API key (or OPENAI_API_KEY env / .env)")
-	debugFlag := flag.Bool("debug", false, "Verbose debug logging")
-	flag.Parse()
-
-	debug = *debugFlag
-	if debug {
-		fmt.Println("   • DEBUG MODE ON")
+// wait blocks until host may be contacted again and reserves the next slot.
+func (t *politeTransport) wait(ctx context.Context, host string) error {
+	t.mu.Lock()
+	now := time.Now()
+	start := t.next[host]
+	if start.Before(now) {
+		start = now
 	}
+	t.next[host] = start.Add(t.interval)
+	t.mu.Unlock()
 
-	// Load .env first (if present), then backfill empty key flags from env
-	if err := loadDotEnv(*envFile); err == nil {
-		fmt.Printf("   • Loaded env: %s\n", *envFile)
-	}
-	if *serpAPIKey == "" {
-		if v := os.Getenv("SERPAPI_KEY"); v != "" {
-			*serpAPIKey = v
-		}
+	d := time.Until(start)
+	if d <= 0 {
+		return nil
 	}
-	if *openAIKey == "" {
-		if v := os.Getenv("OPENAI_API_KEY"); v != "" {
-			*openAIKey = v
-		}
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
 	}
+}
 
-	logoDir := filepath.Join(*publicDir, "images", "logos")
-	if err := os.MkdirAll(logoDir, 0o755); err != nil {
-		fatal("creating logo dir", err)
+// holdOff moves the host's next slot at least d into the future.
+func (t *politeTransport) holdOff(host string, d time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if until := time.Now().Add(d); t.next[host].Before(until) {
+		t.next[host] = until
 	}
+}
 
-	root, sponsors, raw, err := readSite(*siteJSONPath)
-	if err != nil {
-		fatal("reading site.json", err)
+// shouldRetry reports whether a response or error is worth another attempt.
+// GET and HEAD are retried on 429, any 5xx, or a network timeout. Other
+// methods (the paid POSTs) may already have been carried out and billed, so
+// they are retried only when the server asks to be retried later: a 429 or
+// 503 carrying Retry-After.
+func shouldRetry(req *http.Request, resp *http.Response, err error) bool {
+	if req.Method != "" && req.Method != http.MethodGet && req.Method != http.MethodHead {
+		return err == nil &&
+			(resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode == http.StatusServiceUnavailable) &&
+			resp.Header.Get("Retry-After") != ""
 	}
-
-	var catFilter map[string]bool
-	if strings.TrimSpace(*cats) != "" {
-		catFilter = make(map[string]bool)
-		for _, c := range strings.Split(*cats, ",") {
-			c = strings.TrimSpace(c)
-			if c != "" {
-				catFilter[strings.ToLower(c)] = true
-			}
-		}
+	if err != nil {
+		var ne net.Error
+		return errors.As(err, &ne) && ne.Timeout()
 	}
+	return resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500
+}
 
-	client := &http.Client{Timeout: 30 * time.Second}
-	ua := "Mozilla/5.0 (Macintosh; Intel Mac OS X 14_6) AppleWebKit/537.36 (KHTML, like Gecko) Chrome/128 Safari/537.36"
-
-	econf := EnrichConfig{
-		Enable:     *enrich,
-		Provider:   strings.ToLower(strings.TrimSpace(*searchProvider)),
-		SerpAPIKey: strings.TrimSpace(*serpAPIKey),
-		OpenAIKey:  strings.TrimSpace(*openAIKey),
+// backoffDelay is exponential backoff with full jitter on top of the base step.
+func backoffDelay(attempt int) time.Duration {
+	d := backoffBase << attempt
+	if d > backoffMax || d <= 0 {
+		d = backoffMax
 	}
+	return d/2 + time.Duration(rand.Int63n(int64(d/2)+1))
+}
 
-	// fmt.Println(econf.OpenAIKey)
-
-	if econf.Enable {
-		fmt.Printf("   • Enrichment: provider=%s\n", econf.Provider)
+// retryAfter parses a Retry-After header given in seconds or as an HTTP date.
+func retryAfter(v string) (time.Duration, bool) {
+	v = strings.TrimSpace(v)
+	if v == "" {
+		return 0, false
 	}
-
-	fmt.Printf("🔎 Scanning %d sponsors in %s\n", len(sponsors), *siteJSONPath)
-	if *onlyActive {
-		fmt.Println("   • Filter: only active")
+	var d time.Duration
+	if secs, err := strconv.Atoi(v); err == nil {
+		d = time.Duration(secs) * time.Second
+	} else if at, err := http.ParseTime(v); err == nil {
+		d = time.Until(at)
+	} else {
+		return 0, false
 	}
-	if len(catFilter) > 0 {
-		fmt.Printf("   • Filter: categories in %v\n", keys(catFilter))
+	if d < 0 {
+		d = 0
 	}
-	if *dryRun {
-		fmt.Println("   • DRY RUN (no downloads / no file writes)")
+	if d > retryAfterMax {
+		d = retryAfterMax
 	}
-	fmt.Println()
-
-	success, skipped, fail := 0, 0, 0
-	updatedJSON := false
-
-	for i := range sponsors {
-		s := sponsors[i]
+	return d, true
+}
 
-		sponsorStart := time.Now()
-		dbg("sponsor=%q href=%q logo=%q active=%v cats=%v", s.Name, s.Href, s.Logo, s.Active, s.Category)
+// ---- on-disk cache ----
 
-		// Filters
-		if *onlyActive && !s.Active {
-			continue
-		}
-		if len(catFilter) > 0 && !overlapsLower(s.Category, catFilter) {
-			continue
-		}
+// Cache kinds, one subdirectory each.
+const (
+	cacheSerp    = "serpapi"
+	cacheOpenAI  = "openai"
+	cacheHTML    = "html"
+	cacheLogoURL = "logo-url"
+)
 
-		// Enrich website automatically when missing/bad; Instagram always set if missing and discovered
-		needHref := strings.TrimSpace(s.Href) == ""
-		needIG := strings.TrimSpace(s.Instagram) == ""
-		shouldEnrich := needHref || needIG
-		if shouldEnrich {
-			// Use the configured provider for website lookup even if --enrich-missing is off
-			eff := econf
-			tEnrich := time.Now()
-			fmt.Printf("enrich start: provider=%s needHref=%v needIG=%v\n", eff.Provider, needHref, needIG)
-			foundHref, foundIG, err := enrichSponsor(client, ua, &s, eff)
-			fmt.Printf("enrich done in %s → href=%q ig=%q err=%v\n", time.Since(tEnrich), foundHref, foundIG, err)
-			if err != nil {
-				fmt.Printf("   (enrich warn) %s: %v\n", s.Name, err)
-			}
-			changed := false
-			if needHref && strings.TrimSpace(foundHref) != "" {
-				sponsors[i].Href = foundHref
-				changed = true
-				fmt.Printf("   🔗 set href → %s\n", foundHref)
-			}
-			// Always set Instagram if it's missing and we discovered one (store handle)
-			if needIG && strings.TrimSpace(foundIG) != "" {
-				sponsors[i].Instagram = igHandle(foundIG)
-				changed = true
-				fmt.Printf("   📸 set instagram → @%s\n", sponsors[i].Instagram)
-			}
-			if changed {
-				updatedJSON = true
-			}
-		}
+// cacheTTL is how long an entry of each kind is used without asking again.
+// Stale HTML is revalidated with its ETag / Last-Modified rather than refetched.
+var cacheTTL = map[string]time.Duration{
+	cacheSerp:    30 * 24 * time.Hour,
+	cacheOpenAI:  30 * 24 * time.Hour,
+	cacheHTML:    24 * time.Hour,
+	cacheLogoURL: 7 * 24 * time.Hour,
+}
 
-		// Determine intended local path
-		localPath := desiredLocalLogoPath(*publicDir, logoDir, s)
+// maxCachedPage is the largest HTML body kept; getHTML reads at most 2MB.
+const maxCachedPage = 2 << 20
 
-		// If logo path exists & file present → skip
-		if strings.TrimSpace(s.Logo) != "" && fileExists(localPath) {
-			fmt.Printf("✅ %-30s logo OK → %s\n", s.Name, rel(localPath))
-			skipped++
-			dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
-			continue
-		}
+var errOffline = errors.New("not cached (offline)")
 
-		if strings.TrimSpace(s.Href) == "" {
-			fmt.Printf("⚠️  %-30s no href to discover logo; logo path set=%t\n", s.Name, strings.TrimSpace(s.Logo) != "")
-			fail++
-			dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
-			continue
-		}
+// diskCache keeps search results, LLM answers, fetched pages, and resolved
+// logo URLs under dir, one JSON file per entry, so re-runs skip the network
+// and the paid providers. A nil *diskCache is a disabled cache.
+type diskCache struct {
+	dir     string
+	refresh bool // ignore existing entries; fresh results still overwrite them
+	offline bool // never go to the network; stale entries are served as is
+}
 
-		fmt.Printf("→ %-30s discovering logo from %s\n", s.Name, s.Href)
-		if *dryRun {
-			fmt.Println("   (dry-run) skipping discovery/download")
-			dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
-			continue
-		}
+type cacheEntry struct {
+	Key    string          `json:"key"`
+	Stored time.Time       `json:"stored"`
+	Value  json.RawMessage `json:"value"`
+}
 
-		imgURL, ext, err := discoverLogoURL(client, ua, s.Href)
-		if err != nil {
-			fmt.Printf("   ✖ discovery failed: %v\n", err)
-			fail++
-			dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
-			continue
-		}
+func (c *diskCache) path(kind, key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, kind, hex.EncodeToString(sum[:12])+".json")
+}
 
-		// Choose final target path
-		var target string
-		if strings.TrimSpace(s.Logo) != "" {
-			// Keep chosen basename but fix extension
-			target = replaceExt(localPath, ext)
-		} else {
-			filename := slugify(s.Name) + ext
-			target = filepath.Join(logoDir, filename)
-			sitePath := "/images/logos/" + filename
-			sponsors[i].Logo = sitePath
-			updatedJSON = true
-		}
+func (c *diskCache) isOffline() bool {
+	return c != nil && c.offline
+}
 
-		if err := downloadImage(client, ua, s.Href, imgURL, target); err != nil {
-			fmt.Printf("   ✖ download failed: %v\n", err)
-			fail++
-			dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
-			continue
-		}
-		fmt.Printf("   ✅ saved: %s\n", rel(target))
-		success++
-		dbg("sponsor=%q done in %s", s.Name, time.Since(sponsorStart))
+// entry reads the kind/key entry whatever its age. Nothing is read in
+// refresh mode.
+func (c *diskCache) entry(kind, key string) (*cacheEntry, bool) {
+	if c == nil || c.refresh {
+		return nil, false
 	}
-
-	fmt.Printf("\nSummary: %d saved, %d ok, %d failed\n", success, skipped, fail)
-
-	if updatedJSON && !*dryRun {
-		if err := writeSite(*siteJSONPath, raw, root, sponsors); err != nil {
-			fatal("writing updated site.json", err)
-		}
-		fmt.Printf("📝 Updated only 'sponsors' in %s (backup created; other fields preserved).\n", *siteJSONPath)
+	b, err := os.ReadFile(c.path(kind, key))
+	if err != nil {
+		return nil, false
 	}
+	var e cacheEntry
+	if json.Unmarshal(b, &e) != nil || e.Key != key {
+		return nil, false
+	}
+	return &e, true
 }
 
-// ---- site.json helpers ----
+func (e *cacheEntry) fresh(kind string) bool {
+	return time.Since(e.Stored) < cacheTTL[kind]
+}
 
-func readSite(path string) (root map[string]json.RawMessage, sponsors []Sponsor, raw []byte, err error) {
-	b, e := os.ReadFile(path)
-	if e != nil {
-		return nil, nil, nil, e
-	}
-	var m map[string]json.RawMessage
-	if e = json.Unmarshal(b, &m); e != nil {
-		return nil, nil, nil, e
+// lookup decodes the kind/key entry into v if it is fresh (or, offline, if
+// it exists at all).
+func (c *diskCache) lookup(kind, key string, v interface{}) bool {
+	e, ok := c.entry(kind, key)
+	if !ok || !(c.offline || e.fresh(kind)) {
+		return false
 	}
-	var sp []Sponsor
-	if spRaw, ok := m["sponsors"]; ok && len(spRaw) > 0 {
-		if e = json.Unmarshal(spRaw, &sp); e != nil {
-			return nil, nil, nil, fmt.Errorf("unmarshal sponsors: %w", e)
-		}
+	if json.Unmarshal(e.Value, v) != nil {
+		return false
 	}
-	return m, sp, b, nil
+	dbg("cache hit %s %s", kind, key)
+	return true
 }
 
-func writeSite(path string, original []byte, root map[string]json.RawMessage, updatedSponsors []Sponsor) error {
-	backup := fmt.Sprintf("%s.bak-%d", path, time.Now().Unix())
-	if err := os.WriteFile(backup, original, 0o644); err != nil {
-		return fmt.Errorf("backup write: %w", err)
+// store saves v as the kind/key entry. Failures only cost a future lookup,
+// so they are logged and otherwise ignored.
+func (c *diskCache) store(kind, key string, v interface{}) {
+	if c == nil {
+		return
 	}
-
-	spBytes, err := json.Marshal(updatedSponsors)
-	if err != nil {
-		return fmt.Errorf("marshal sponsors: %w", err)
+	val, err := json.Marshal(v)
+	if err == nil {
+		var b []byte
+		b, err = json.MarshalIndent(cacheEntry{Key: key, Stored: time.Now(), Value: val}, "", "  ")
+		if err == nil {
+			err = writeFileAtomic(c.path(kind, key), bytes.NewReader(b))
+		}
 	}
-	root["sponsors"] = spBytes
-
-	pretty, err := json.MarshalIndent(root, "", "  ")
 	if err != nil {
-		return fmt.Errorf("marshal root: %w", err)
+		dbg("cache store %s %s: %v", kind, key, err)
 	}
-	return os.WriteFile(path, pretty, 0o644)
 }
 
-// ---- discovery & downloading ----
+// cachedPage is a cached HTML response or redirect, with the validators used
+// to revalidate it once stale.
+type cachedPage struct {
+	Status       int    `json:"status"`
+	ContentType  string `json:"contentType,omitempty"`
+	Location     string `json:"location,omitempty"`
+	ETag         string `json:"etag,omitempty"`
+	LastModified string `json:"lastModified,omitempty"`
+	Body         []byte `json:"body,omitempty"`
+}
 
-func discoverLogoURL(client *http.Client, ua, href string) (imgURL, ext string, err error) {
-	dbg("discoverLogoURL: href=%s", href)
-	tDisc := time.Now()
-	base, err := url.Parse(href)
-	if err != nil || base.Scheme == "" || base.Host == "" {
-		return "", "", fmt.Errorf("invalid href: %s", href)
-	}
-
-	// 1) Fetch HTML and look for OG image / icons / logo-ish <img>
-	html, err := getHTML(client, ua, href, href)
-	if err == nil && len(html) > 0 {
-		if u := findOGImage(html, base); u != "" && !isICO(u) {
-			dbg("discoverLogoURL: og:image %s in %s", u, time.Since(tDisc))
-			return u, extFromURLOrHead(client, ua, href, u), nil
-		}
-		if u := findIconLink(html, base); u != "" && !isICO(u) {
-			dbg("discoverLogoURL: <link rel=icon> %s in %s", u, time.Since(tDisc))
-			return u, extFromURLOrHead(client, ua, href, u), nil
-		}
-		if u := findLogoImg(html, base); u != "" && !isICO(u) {
-			dbg("discoverLogoURL: <img ...logo...> %s in %s", u, time.Since(tDisc))
-			return u, extFromURLOrHead(client, ua, href, u), nil
-		}
-	}
-
-	// 2) Try common non-ICO icon paths (PNG/JPG/WEBP/SVG)
-	candidates := []string{
-		"/apple-touch-icon.png",
-		"/apple-touch-icon-precomposed.png",
-		"/favicon-196x196.png",
-		"/favicon-192x192.png",
-		"/favicon-180x180.png",
-		"/favicon-152x152.png",
-		"/favicon-144x144.png",
-		"/favicon-96x96.png",
-		"/favicon-64x64.png",
-		"/favicon-32x32.png",
-		"/favicon.png",
-		"/logo.png",
-		"/logo.jpg",
-		"/logo.webp",
-		"/logo.svg",
-	}
-	dbg("discoverLogoURL: trying %d common candidates", len(candidates))
-	for _, c := range candidates {
-		u := base.ResolveReference(&url.URL{Path: c}).String()
-		tHead := time.Now()
-		dbg("HEAD %s", u)
-		if headOKNonICO(client, ua, href, u) {
-			dbg("candidate OK %s in %s", u, time.Since(tHead))
-			return u, extFromURLOrHead(client, ua, href, u), nil
+func (p cachedPage) response(req *http.Request) *http.Response {
+	h := make(http.Header)
+	for k, v := range map[string]string{"Content-Type": p.ContentType, "Location": p.Location, "ETag": p.ETag, "Last-Modified": p.LastModified} {
+		if v != "" {
+			h.Set(k, v)
 		}
 	}
-	dbg("discoverLogoURL: no image found after %s", time.Since(tDisc))
-	return "", "", errors.New("no viable image found")
+	return &http.Response{
+		Status:        fmt.Sprintf("%d %s", p.Status, http.StatusText(p.Status)),
+		StatusCode:    p.Status,
+		Proto:         "HTTP/1.1",
+		ProtoMajor:    1,
+		ProtoMinor:    1,
+		Header:        h,
+		Body:          io.NopCloser(bytes.NewReader(p.Body)),
+		ContentLength: int64(len(p.Body)),
+		Request:       req,
+	}
 }
 
-func getHTML(client *http.Client, ua, referer, pageURL string) ([]byte, error) {
-	req, _ := http.NewRequest("GET", pageURL, nil)
-	req.Header.Set("User-Agent", ua)
-	if referer != "" {
-		req.Header.Set("Referer", referer)
+// cacheTransport serves GETs of HTML pages (and the redirects leading to
+// them) from the cache, revalidating stale ones. Offline, anything not
+// cached fails with errOffline.
+type cacheTransport struct {
+	base  http.RoundTripper
+	cache *diskCache
+}
+
+func (t *cacheTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if req.Method != http.MethodGet {
+		if t.cache.offline {
+			return nil, fmt.Errorf("%s %s: %w", req.Method, req.URL, errOffline)
+		}
+		return t.base.RoundTrip(req)
 	}
-	dbgDumpReq(req)
-	t := time.Now()
-	dbg("GET %s (referer=%s)", pageURL, referer)
-	resp, err := client.Do(req)
+	key := req.URL.String()
+	var page cachedPage
+	e, ok := t.cache.entry(cacheHTML, key)
+	if ok && json.Unmarshal(e.Value, &page) != nil {
+		ok = false
+	}
+	if ok && (t.cache.offline || e.fresh(cacheHTML)) {
+		dbg("cache hit html %s", key)
+		return page.response(req), nil
+	}
+	if t.cache.offline {
+		return nil, fmt.Errorf("GET %s: %w", key, errOffline)
+	}
+	if ok && (page.ETag != "" || page.LastModified != "") {
+		req = req.Clone(req.Context())
+		if page.ETag != "" {
+			req.Header.Set("If-None-Match", page.ETag)
+		}
+		if page.LastModified != "" {
+			req.Header.Set("If-Modified-Since", page.LastModified)
+		}
+	}
+	resp, err := t.base.RoundTrip(req)
 	if err != nil {
 		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK || !strings.Contains(strings.ToLower(resp.Header.Get("Content-Type")), "text/html") {
-		return nil, fmt.Errorf("status %d or non-html", resp.StatusCode)
+	if ok && resp.StatusCode == http.StatusNotModified {
+		dbg("cache revalidated html %s", key)
+		resp.Body.Close()
+		t.cache.store(cacheHTML, key, page) // restarts the TTL
+		return page.response(req), nil
 	}
-	dbg("GET %s status=%d ct=%q in %s", pageURL, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
-	// 2MB cap
-	b, err := io.ReadAll(io.LimitReader(resp.Body, 2<<20))
-	return b, err
+	return t.keep(key, resp)
 }
 
-func findOGImage(html []byte, base *url.URL) string {
-	re := regexp.MustCompile(`(?i)<meta[^>]+property=["']og:image["'][^>]+content=["']([^"']+)["']`)
-	m := re.FindSubmatch(html)
-	if len(m) > 1 {
-		return toAbsURL(base, string(m[1]))
+// keep caches resp if it is an HTML page or a redirect, and returns it with
+// its body intact.
+func (t *cacheTransport) keep(key string, resp *http.Response) (*http.Response, error) {
+	page := cachedPage{
+		Status:       resp.StatusCode,
+		ContentType:  resp.Header.Get("Content-Type"),
+		Location:     resp.Header.Get("Location"),
+		ETag:         resp.Header.Get("ETag"),
+		LastModified: resp.Header.Get("Last-Modified"),
 	}
-	return ""
+	switch {
+	case resp.StatusCode >= 300 && resp.StatusCode < 400 && page.Location != "":
+		t.cache.store(cacheHTML, key, page)
+		return resp, nil
+	case resp.StatusCode == http.StatusOK && strings.Contains(strings.ToLower(page.ContentType), "text/html"):
+	default:
+		return resp, nil
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxCachedPage+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
+	}
+	if len(body) > maxCachedPage {
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
+	}
+	resp.Body.Close()
+	page.Body = body
+	t.cache.store(cacheHTML, key, page)
+	resp.Body = io.NopCloser(bytes.NewReader(body))
+	return resp, nil
 }
 
-func findIconLink(html []byte, base *url.URL) string {
-	re := regexp.MustCompile(`(?i)<link[^>]+rel=["'][^"']*(apple-touch-icon|icon)[^"']*["'][^>]*href=["']([^"']+)["']`)
-	for _, m := range re.FindAllSubmatch(html, -1) {
-		u := toAbsURL(base, string(m[2]))
-		if !isICO(u) {
-			return u
-		}
-	}
-	return ""
+// ---- HTTP record / replay ----
+
+// exchange is one recorded request and the response it got. Text bodies are
+// kept readable; binary ones (images) are base64.
+type exchange struct {
+	Method     string      `json:"method"`
+	URL        string      `json:"url"`
+	BodySHA256 string      `json:"bodySha256,omitempty"` // of the request body
+	Status     int         `json:"status"`
+	Header     http.Header `json:"header"`
+	Body       string      `json:"body,omitempty"`
+	BodyBase64 []byte      `json:"bodyBase64,omitempty"`
 }
 
-func findLogoImg(html []byte, base *url.URL) string {
-	re := regexp.MustCompile(`(?i)<img[^>]+(?:src|data-src|data-original)=["']([^"']+logo[^"']*\.(?:png|jpe?g|webp|svg))["']`)
-	m := re.FindSubmatch(html)
-	if len(m) > 1 {
-		return toAbsURL(base, string(m[1]))
-	}
-	return ""
+// recordTransport writes every exchange to dir (with base set) or answers
+// requests from a directory written that way (base nil), with no network.
+// Repeated identical requests are numbered, so a replay returns responses in
+// recorded order; past the last one, the last one repeats.
+type recordTransport struct {
+	base http.RoundTripper
+	dir  string
+
+	mu   sync.Mutex
+	seen map[string]int // exchange file prefix → requests so far
 }
 
-func headOKNonICO(client *http.Client, ua, referer, u string) bool {
-	t := time.Now()
-	req, _ := http.NewRequest("HEAD", u, nil)
-	req.Header.Set("User-Agent", ua)
-	if referer != "" {
-		req.Header.Set("Referer", referer)
+func newRecordTransport(base http.RoundTripper, dir string) *recordTransport {
+	return &recordTransport{base: base, dir: dir, seen: make(map[string]int)}
+}
+
+func (t *recordTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	var reqBody []byte
+	if req.Body != nil {
+		b, err := io.ReadAll(req.Body)
+		req.Body.Close()
+		if err != nil {
+			return nil, err
+		}
+		reqBody = b
+		req = req.Clone(req.Context())
+		req.Body = io.NopCloser(bytes.NewReader(b))
+		req.GetBody = func() (io.ReadCloser, error) { return io.NopCloser(bytes.NewReader(b)), nil }
 	}
-	dbgDumpReq(req)
-	resp, err := client.Do(req)
+	u := redactURL(req.URL)
+	prefix := exchangePrefix(req.Method, u, reqBody)
+	t.mu.Lock()
+	n := t.seen[prefix]
+	t.seen[prefix]++
+	t.mu.Unlock()
+
+	if t.base == nil {
+		return t.replay(req, u, prefix, n)
+	}
+	resp, err := t.base.RoundTrip(req)
 	if err != nil {
-		return false
+		return nil, err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		dbg("HEAD %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
-		return false
+	body, err := io.ReadAll(io.LimitReader(resp.Body, maxImageBytes+1))
+	if err != nil {
+		resp.Body.Close()
+		return nil, err
 	}
-	ct := strings.ToLower(resp.Header.Get("Content-Type"))
-	if !strings.HasPrefix(ct, "image/") {
-		dbg("HEAD %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
-		return false
+	if len(body) > maxImageBytes {
+		// Too big to keep (and too big for downloadImage): pass it through
+		// unrecorded, so a replay fails on it instead of replaying a stub.
+		fmt.Fprintf(os.Stderr, "⚠️  not recording %s %s: body over %dMB\n", req.Method, u, maxImageBytes>>20)
+		resp.Body = struct {
+			io.Reader
+			io.Closer
+		}{io.MultiReader(bytes.NewReader(body), resp.Body), resp.Body}
+		return resp, nil
 	}
-	dbg("HEAD %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
-	return !strings.Contains(ct, "x-icon") && !strings.Contains(ct, "vnd.microsoft.icon")
-}
+	resp.Body.Close()
+	resp.Body = io.NopCloser(bytes.NewReader(body))
 
-func extFromURLOrHead(client *http.Client, ua, referer, u string) string {
-	ext := strings.ToLower(filepath.Ext(strings.Split(u, "?")[0]))
-	if ext == "" || ext == ".ico" {
-		t := time.Now()
-		dbg("HEAD (extFromURLOrHead) %s", u)
-		req, _ := http.NewRequest("HEAD", u, nil)
-		req.Header.Set("User-Agent", ua)
-		if referer != "" {
-			req.Header.Set("Referer", referer)
-		}
-		dbgDumpReq(req)
-		resp, err := client.Do(req)
-		if err == nil {
-			defer resp.Body.Close()
-			dbg("HEAD (extFromURLOrHead) %s status=%d ct=%q in %s", u, resp.StatusCode, resp.Header.Get("Content-Type"), time.Since(t))
-			ct := strings.ToLower(resp.Header.Get("Content-Type"))
-			switch {
-			case strings.HasPrefix(ct, "image/png"):
-				return ".png"
-			case strings.HasPrefix(ct, "image/webp"):
-				return ".webp"
-			case strings.HasPrefix(ct, "image/avif"):
-				return ".avif"
-			case strings.HasPrefix(ct, "image/jpeg"):
-				return ".jpg"
-			case strings.HasPrefix(ct, "image/svg"):
-				return ".svg"
-			}
-		}
+	x := exchange{Method: req.Method, URL: u, Status: resp.StatusCode, Header: resp.Header.Clone()}
+	x.Header.Del("Set-Cookie")
+	if len(reqBody) > 0 {
+		sum := sha256.Sum256(reqBody)
+		x.BodySHA256 = hex.EncodeToString(sum[:])
 	}
-	if ext == "" || ext == ".ico" {
-		return ".png"
+	if utf8.Valid(body) {
+		x.Body = string(body)
+	} else {
+		x.BodyBase64 = body
 	}
-	return ext
+	b, err := json.MarshalIndent(x, "", "  ")
+	if err == nil {
+		err = writeFileAtomic(filepath.Join(t.dir, fmt.Sprintf("%s-%d.json", prefix, n)), bytes.NewReader(b))
+	}
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "⚠️  recording %s %s: %v\n", req.Method, u, err)
+	}
+	return resp, nil
 }
 
-func downloadImage(client *http.Client, ua, referer, imgURL, target string) error {
-	t := time.Now()
-	dbg("GET image %s (referer=%s)", imgURL, referer)
-	req, _ := http.NewRequest("GET", imgURL, nil)
-	req.Header.Set("User-Agent", ua)
-	if referer != "" {
-		req.Header.Set("Referer", referer)
+func (t *recordTransport) replay(req *http.Request, u, prefix string, n int) (*http.Response, error) {
+	for ; n >= 0; n-- {
+		b, err := os.ReadFile(filepath.Join(t.dir, fmt.Sprintf("%s-%d.json", prefix, n)))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return nil, err
+		}
+		var x exchange
+		if err := json.Unmarshal(b, &x); err != nil {
+			return nil, fmt.Errorf("replay %s %s: %w", req.Method, u, err)
+		}
+		body := x.BodyBase64
+		if x.Body != "" {
+			body = []byte(x.Body)
+		}
+		dbg("replay %s %s → %d", req.Method, u, x.Status)
+		return &http.Response{
+			Status:        fmt.Sprintf("%d %s", x.Status, http.StatusText(x.Status)),
+			StatusCode:    x.Status,
+			Proto:         "HTTP/1.1",
+			ProtoMajor:    1,
+			ProtoMinor:    1,
+			Header:        x.Header,
+			Body:          io.NopCloser(bytes.NewReader(body)),
+			ContentLength: int64(len(body)),
+			Request:       req,
+		}, nil
 	}
-	dbgDumpReq(req)
-	resp, err := client.Do(req)
+	return nil, fmt.Errorf("replay: no recording of %s %s", req.Method, u)
+}
+
+// clearRecordings removes the exchanges of an earlier -record into dir, so a
+// replay only sees the new session.
+func clearRecordings(dir string) error {
+	old, err := filepath.Glob(filepath.Join(dir, "*.json"))
 	if err != nil {
 		return err
 	}
-	defer resp.Body.Close()
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("bad status %d for %s", resp.StatusCode, imgURL)
-	}
-	ct := strings.ToLower(resp.Header.Get("Content-Type"))
-	if !strings.HasPrefix(ct, "image/") {
-		return fmt.Errorf("not an image: %s", ct)
-	}
-	if strings.Contains(ct, "x-icon") || strings.Contains(ct, "vnd.microsoft.icon") {
-		return fmt.Errorf("ICO not allowed")
+	for _, f := range old {
+		if err := os.Remove(f); err != nil {
+			return err
+		}
 	}
-	dbg("GET image %s status=%d ct=%q len=%s in %s", imgURL, resp.StatusCode, ct, resp.Header.Get("Content-Length"), time.Since(t))
+	return nil
+}
 
-	// Ensure extension matches content-type
-	target = replaceExt(target, extFromContentType(ct))
-	tmp := target + ".part"
-	if err := os.MkdirAll(filepath.Dir(target), 0o755); err != nil {
-		return err
-	}
-	out, err := os.Create(tmp)
-	if err != nil {
-		return err
+// exchangePrefix names an exchange's files: the host (to find a sponsor's
+// requests by eye), the method, and a hash of the method, URL, and body.
+func exchangePrefix(method, u string, body []byte) string {
+	h := sha256.New()
+	io.WriteString(h, method+" "+u+"\n")
+	h.Write(body)
+	host := "unknown"
+	if p, err := url.Parse(u); err == nil && p.Host != "" {
+		host = strings.NewReplacer(":", "_", "/", "_").Replace(strings.ToLower(p.Host))
 	}
-	defer out.Close()
-	if _, err = io.Copy(out, io.LimitReader(resp.Body, 50<<20)); err != nil {
-		return err
+	return fmt.Sprintf("%s-%s-%s", host, strings.ToLower(method), hex.EncodeToString(h.Sum(nil)[:6]))
+}
+
+// redactURL drops the SerpAPI key, so recordings can be shared and replayed
+// with any key.
+func redactURL(u *url.URL) string {
+	q := u.Query()
+	if !q.Has("api_key") {
+		return u.String()
 	}
-	out.Close()
-	return os.Rename(tmp, target)
+	q.Del("api_key")
+	r := *u
+	r.RawQuery = q.Encode()
+	return r.String()
 }
 
 // ---- utils ----
@@ -552,6 +6987,16 @@ func slugify(s string) string {
 	return s
 }
 
+// isNewer reports whether a exists and was modified no earlier than b.
+func isNewer(a, b string) bool {
+	fa, err := os.Stat(a)
+	if err != nil {
+		return false
+	}
+	fb, err := os.Stat(b)
+	return err == nil && !fa.ModTime().Before(fb.ModTime())
+}
+
 func fileExists(p string) bool {
 	if fi, err := os.Stat(p); err == nil && !fi.IsDir() {
 		return true
@@ -599,8 +7044,19 @@ func toAbsURL(base *url.URL, found string) string {
 	return u.String()
 }
 
+// displayURL shortens data: URLs for console output.
+func displayURL(u string) string {
+	if strings.HasPrefix(u, "data:") {
+		return fmt.Sprintf("<inline %s, %d bytes>", strings.TrimPrefix(candidateExt(u), "."), len(u))
+	}
+	return u
+}
+
 func rel(p string) string {
-	r, _ := filepath.Rel(".", p)
+	r, err := filepath.Rel(".", p)
+	if err != nil {
+		return p
+	}
 	return r
 }
 
@@ -633,16 +7089,29 @@ func isICO(u string) bool {
 
 // ---- enrichment (discover website / instagram) ----
 
-func enrichSponsor(client *http.Client, ua string, s *Sponsor, cfg EnrichConfig) (website, instagram string, err error) {
+// enrichSponsor looks up s's missing website and Instagram. page is s's
+// homepage if the caller already fetched it; nil fetches it when needed.
+func enrichSponsor(client *http.Client, ua string, s *Sponsor, page *homePage, cfg EnrichConfig) (website, instagram string, err error) {
 	name := strings.TrimSpace(s.Name)
 	if name == "" {
 		return "", "", errors.New("empty sponsor name")
 	}
+	// The sponsor's own structured data is free and authoritative; when it
+	// supplies the only missing field, skip the paid providers entirely.
+	if strings.TrimSpace(s.Href) != "" && strings.TrimSpace(s.Instagram) == "" {
+		if page == nil || page.href != s.Href {
+			page = fetchHomePage(client, ua, s.Href)
+		}
+		if h := instagramFromPage(page); h != "" {
+			dbg("enrich: instagram %q from JSON-LD sameAs on %s", h, s.Href)
+			return "", "https://www.instagram.com/" + h + "/", nil
+		}
+	}
 	switch cfg.Provider {
 	case "serpapi":
-		return enrichViaSerpAPI(client, ua, name, cfg.SerpAPIKey)
+		return enrichViaSerpAPI(client, ua, name, cfg)
 	case "openai":
-		return enrichViaOpenAI(client, ua, name, cfg.OpenAIKey)
+		return enrichViaOpenAI(client, ua, name, cfg)
 	case "hybrid":
 		return enrichViaHybrid(client, ua, name, cfg)
 	default:
@@ -761,12 +7230,26 @@ func looksLikeOfficial(name, u string) bool {
 }
 
 // Collect multiple candidates from SerpAPI for retrieval-augmented selection.
-func serpCandidates(client *http.Client, ua, name, key string) (webCandidates []string, igCandidates []string, err error) {
+// Each search is one paid call against cfg.Budget. The result is cached only
+// if every search succeeded, so a transient failure isn't remembered as "no
+// results".
+func serpCandidates(client *http.Client, ua, name string, cfg EnrichConfig) (webCandidates []string, igCandidates []string, err error) {
+	var hit serpResult
+	if cfg.Cache.lookup(cacheSerp, name, &hit) {
+		return hit.Web, hit.Instagram, nil
+	}
+	if cfg.Cache.isOffline() {
+		return nil, nil, errOffline
+	}
+	key := cfg.SerpAPIKey
 	if strings.TrimSpace(key) == "" {
 		return nil, nil, errors.New("SerpAPI key missing")
 	}
+	if err := cfg.Budget.spend("serpapi"); err != nil {
+		return nil, nil, err
+	}
 	// General web search (bias to San Diego to disambiguate)
-	base := "https://serpapi.com/search.json?q=" + url.QueryEscape(name+" san diego") + "&engine=google&num=10&api_key=" + url.QueryEscape(key)
+	base := cfg.serpAPIURL() + "?q=" + url.QueryEscape(name+" san diego") + "&engine=google&num=10&api_key=" + url.QueryEscape(key)
 	body, err := httpGET(client, ua, base, "")
 	if err != nil {
 		return nil, nil, err
@@ -781,11 +7264,15 @@ func serpCandidates(client *http.Client, ua, name, key string) (webCandidates []
 			webCandidates = append(webCandidates, trimURL(link))
 		}
 	}
+	complete := true
 	// Fallback: broader query if none found
-	if len(webCandidates) == 0 {
-		base2 := "https://serpapi.com/search.json?q=" + url.QueryEscape(name) + "&engine=google&num=10&api_key=" + url.QueryEscape(key)
+	if len(webCandidates) == 0 && cfg.Budget.spend("serpapi") == nil {
+		base2 := cfg.serpAPIURL() + "?q=" + url.QueryEscape(name) + "&engine=google&num=10&api_key=" + url.QueryEscape(key)
 		body2, err2 := httpGET(client, ua, base2, "")
-		if err2 == nil {
+		if err2 != nil {
+			dbg("serpapi broader query for %q: %v", name, err2)
+			complete = false
+		} else {
 			for _, m := range re.FindAllSubmatch(body2, -1) {
 				link := string(m[1])
 				if isOpenAIDocsURL(link) {
@@ -799,8 +7286,15 @@ func serpCandidates(client *http.Client, ua, name, key string) (webCandidates []
 	}
 
 	// Instagram-focused search
-	igQ := "https://serpapi.com/search.json?q=" + url.QueryEscape(name+" instagram san diego") + "&engine=google&num=10&api_key=" + url.QueryEscape(key)
-	igBody, _ := httpGET(client, ua, igQ, "")
+	if cfg.Budget.spend("serpapi") != nil {
+		return webCandidates, nil, nil
+	}
+	igQ := cfg.serpAPIURL() + "?q=" + url.QueryEscape(name+" instagram san diego") + "&engine=google&num=10&api_key=" + url.QueryEscape(key)
+	igBody, err := httpGET(client, ua, igQ, "")
+	if err != nil {
+		dbg("serpapi instagram query for %q: %v", name, err)
+		complete = false
+	}
 	reIG := regexp.MustCompile(`https?://(?:www\.)?instagram\.com/([A-Za-z0-9_.]+)/?`)
 	for _, mm := range reIG.FindAllSubmatch(igBody, -1) {
 		h := string(mm[1])
@@ -809,11 +7303,26 @@ func serpCandidates(client *http.Client, ua, name, key string) (webCandidates []
 		}
 		igCandidates = append(igCandidates, "https://www.instagram.com/"+h+"/")
 	}
+	if complete {
+		cfg.Cache.store(cacheSerp, name, serpResult{Web: webCandidates, Instagram: igCandidates})
+	}
 	return webCandidates, igCandidates, nil
 }
 
-func enrichViaSerpAPI(client *http.Client, ua, name, key string) (website, instagram string, err error) {
-	webs, igs, err := serpCandidates(client, ua, name, key)
+// serpResult is a cached serpCandidates result.
+type serpResult struct {
+	Web       []string `json:"web"`
+	Instagram []string `json:"instagram"`
+}
+
+// openAIAnswer is a cached answer from enrichViaOpenAI or chooseViaOpenAI.
+type openAIAnswer struct {
+	Website   string `json:"website"`
+	Instagram string `json:"instagram"`
+}
+
+func enrichViaSerpAPI(client *http.Client, ua, name string, cfg EnrichConfig) (website, instagram string, err error) {
+	webs, igs, err := serpCandidates(client, ua, name, cfg)
 	if err != nil {
 		return "", "", err
 	}
@@ -837,10 +7346,22 @@ func enrichViaSerpAPI(client *http.Client, ua, name, key string) (website, insta
 }
 
 // Optional: OpenAI (LLM guess). This may be less reliable; used only if explicitly set.
-func enrichViaOpenAI(client *http.Client, ua, name, key string) (website, instagram string, err error) {
+func enrichViaOpenAI(client *http.Client, ua, name string, cfg EnrichConfig) (website, instagram string, err error) {
+	cacheKey := "lookup\n" + name
+	var hit openAIAnswer
+	if cfg.Cache.lookup(cacheOpenAI, cacheKey, &hit) {
+		return hit.Website, hit.Instagram, nil
+	}
+	if cfg.Cache.isOffline() {
+		return "", "", errOffline
+	}
+	key := cfg.OpenAIKey
 	if strings.TrimSpace(key) == "" {
 		return "", "", errors.New("OpenAI key missing")
 	}
+	if err := cfg.Budget.spend("openai"); err != nil {
+		return "", "", err
+	}
 	// JSON-only Chat Completions request using strict schema and low temperature
 	dbg("OpenAI lookup %q", name)
 
@@ -877,7 +7398,7 @@ func enrichViaOpenAI(client *http.Client, ua, name, key string) (website, instag
 	}
 
 	b, _ := json.Marshal(payloadObj)
-	endpoint := "https://api.openai.com/v1/chat/completions"
+	endpoint := cfg.chatCompletionsURL()
 	if debug {
 		dbg("OpenAI POST %s", endpoint)
 		dbg("OpenAI payload: %s", string(b))
@@ -960,14 +7481,27 @@ func enrichViaOpenAI(client *http.Client, ua, name, key string) (website, instag
 			}
 		}
 	}
+	cfg.Cache.store(cacheOpenAI, cacheKey, openAIAnswer{Website: website, Instagram: instagram})
 	return website, instagram, nil
 }
 
 // chooseViaOpenAI asks the LLM to select the OFFICIAL website/instagram from candidate lists.
-func chooseViaOpenAI(name string, webCandidates, igCandidates []string, key string) (website, instagram string, err error) {
+func chooseViaOpenAI(client *http.Client, name string, webCandidates, igCandidates []string, cfg EnrichConfig) (website, instagram string, err error) {
+	cacheKey := "choose\n" + name + "\n" + strings.Join(webCandidates, "\n") + "\n" + strings.Join(igCandidates, "\n")
+	var hit openAIAnswer
+	if cfg.Cache.lookup(cacheOpenAI, cacheKey, &hit) {
+		return hit.Website, hit.Instagram, nil
+	}
+	if cfg.Cache.isOffline() {
+		return "", "", errOffline
+	}
+	key := cfg.OpenAIKey
 	if strings.TrimSpace(key) == "" {
 		return "", "", errors.New("OpenAI key missing")
 	}
+	if err := cfg.Budget.spend("openai"); err != nil {
+		return "", "", err
+	}
 
 	type reqMsg struct {
 		Role    string `json:"role"`
@@ -997,12 +7531,11 @@ func chooseViaOpenAI(name string, webCandidates, igCandidates []string, key stri
 	}
 
 	b, _ := json.Marshal(payloadObj)
-	req, _ := http.NewRequest("POST", "https://api.openai.com/v1/chat/completions", bytes.NewReader(b))
+	req, _ := http.NewRequest("POST", cfg.chatCompletionsURL(), bytes.NewReader(b))
 	req.Header.Set("Authorization", "Bearer "+key)
 	req.Header.Set("Content-Type", "application/json")
 
-	httpClient := &http.Client{Timeout: 30 * time.Second}
-	resp, err := httpClient.Do(req)
+	resp, err := client.Do(req)
 	if err != nil {
 		return "", "", err
 	}
@@ -1067,6 +7600,7 @@ func chooseViaOpenAI(name string, webCandidates, igCandidates []string, key stri
 			}
 		}
 	}
+	cfg.Cache.store(cacheOpenAI, cacheKey, openAIAnswer{Website: website, Instagram: instagram})
 	return website, instagram, nil
 }
 
@@ -1074,15 +7608,22 @@ func chooseViaOpenAI(name string, webCandidates, igCandidates []string, key stri
 // Fallbacks:
 //   - If OpenAI key is missing or choose fails, returns SerpAPI's best guess.
 //   - If SerpAPI fails, falls back to the previous OpenAI single-shot method.
+//   - Once the paid call budget is spent, returns errBudgetExhausted.
 func enrichViaHybrid(client *http.Client, ua, name string, cfg EnrichConfig) (website, instagram string, err error) {
 	// 1) Gather candidates via SerpAPI
-	webs, igs, serr := serpCandidates(client, ua, name, cfg.SerpAPIKey)
+	webs, igs, serr := serpCandidates(client, ua, name, cfg)
+	if errors.Is(serr, errBudgetExhausted) {
+		return "", "", serr
+	}
 	if serr != nil || (len(webs) == 0 && len(igs) == 0) {
 		// Serp failed — try the single-shot OpenAI as a last resort
 		if strings.TrimSpace(cfg.OpenAIKey) == "" {
 			return "", "", nil
 		}
-		w, ig, oerr := enrichViaOpenAI(client, ua, name, cfg.OpenAIKey)
+		w, ig, oerr := enrichViaOpenAI(client, ua, name, cfg)
+		if errors.Is(oerr, errBudgetExhausted) {
+			return "", "", oerr
+		}
 		if oerr != nil {
 			dbg("OpenAI fallback error: %v", oerr)
 			return "", "", nil
@@ -1091,7 +7632,7 @@ func enrichViaHybrid(client *http.Client, ua, name string, cfg EnrichConfig) (we
 	}
 	// 2) If OpenAI key present, ask it to choose; otherwise pick heuristically
 	if strings.TrimSpace(cfg.OpenAIKey) != "" {
-		w, ig, oerr := chooseViaOpenAI(name, webs, igs, cfg.OpenAIKey)
+		w, ig, oerr := chooseViaOpenAI(client, name, webs, igs, cfg)
 		if oerr == nil && (w != "" || ig != "") {
 			return w, ig, nil
 		}
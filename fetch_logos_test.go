@@ -0,0 +1,1878 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/png"
+	"io"
+	"io/fs"
+	"math"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+// route is one canned response served by a fake site.
+type route struct {
+	status   int // 0 = 200
+	ct       string
+	body     []byte
+	location string // for redirects
+}
+
+// fakeSite serves routes by path and 404s everything else.
+func fakeSite(t *testing.T, routes map[string]route) *httptest.Server {
+	t.Helper()
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		rt, ok := routes[r.URL.Path]
+		if !ok {
+			http.NotFound(w, r)
+			return
+		}
+		if rt.location != "" {
+			w.Header().Set("Location", rt.location)
+		}
+		if rt.ct != "" {
+			w.Header().Set("Content-Type", rt.ct)
+		}
+		status := rt.status
+		if status == 0 {
+			status = http.StatusOK
+		}
+		w.WriteHeader(status)
+		if r.Method != http.MethodHead {
+			w.Write(rt.body)
+		}
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func htmlPage(body string) route {
+	return route{ct: "text/html; charset=utf-8", body: []byte("<!doctype html><html><head>" + body + "</head><body></body></html>")}
+}
+
+// pngBytes returns a w×h PNG with a dark mark on white, so it passes the
+// image gate and isn't blank.
+func pngBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	for y := 0; y < h; y++ {
+		for x := 0; x < w; x++ {
+			c := color.RGBA{255, 255, 255, 255}
+			if x > w/4 && x < 3*w/4 && y > h/4 && y < 3*h/4 {
+				c = color.RGBA{20, 40, 160, 255}
+			}
+			img.Set(x, y, c)
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// transparentPNGBytes is pngBytes with a transparent background.
+func transparentPNGBytes(t *testing.T, w, h int) []byte {
+	t.Helper()
+	img := image.NewNRGBA(image.Rect(0, 0, w, h))
+	for y := h / 4; y < 3*h/4; y++ {
+		for x := w / 4; x < 3*w/4; x++ {
+			img.Set(x, y, color.NRGBA{20, 40, 160, 255})
+		}
+	}
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, img); err != nil {
+		t.Fatal(err)
+	}
+	return buf.Bytes()
+}
+
+// icoWithPNG wraps a PNG frame in a single-entry ICO container.
+func icoWithPNG(t *testing.T, size int) []byte {
+	t.Helper()
+	frame := pngBytes(t, size, size)
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 1})
+	buf.Write([]byte{byte(size), byte(size), 0, 0})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 32})
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(len(frame)), 22})
+	buf.Write(frame)
+	return buf.Bytes()
+}
+
+func testClient() *http.Client {
+	return newHTTPClient(0, 0)
+}
+
+func TestRetryPolicy(t *testing.T) {
+	var hits atomic.Int32
+	var status atomic.Int32
+	var retryAfter atomic.Value // string
+	retryAfter.Store("")
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		io.Copy(io.Discard, r.Body)
+		if hits.Add(1) == 1 {
+			if ra := retryAfter.Load().(string); ra != "" {
+				w.Header().Set("Retry-After", ra)
+			}
+			w.WriteHeader(int(status.Load()))
+			return
+		}
+		fmt.Fprint(w, "ok")
+	}))
+	defer srv.Close()
+
+	client := newHTTPClient(0, 2)
+	post := func() int {
+		hits.Store(0)
+		resp, err := client.Post(srv.URL, "application/json", strings.NewReader("{}"))
+		if err != nil {
+			t.Fatal(err)
+		}
+		resp.Body.Close()
+		return resp.StatusCode
+	}
+
+	status.Store(http.StatusInternalServerError)
+	if code := post(); code != 500 || hits.Load() != 1 {
+		t.Errorf("POST after 500: status %d after %d attempts, want one attempt", code, hits.Load())
+	}
+	status.Store(http.StatusServiceUnavailable)
+	if code := post(); code != 503 || hits.Load() != 1 {
+		t.Errorf("POST after 503 without Retry-After: status %d after %d attempts", code, hits.Load())
+	}
+	retryAfter.Store("0")
+	status.Store(http.StatusTooManyRequests)
+	if code := post(); code != 200 || hits.Load() != 2 {
+		t.Errorf("POST after 429 with Retry-After: status %d after %d attempts, want 200 after 2", code, hits.Load())
+	}
+
+	retryAfter.Store("")
+	status.Store(http.StatusBadGateway)
+	hits.Store(0)
+	resp, err := client.Get(srv.URL)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != 200 || hits.Load() != 2 {
+		t.Errorf("GET after 502: status %d after %d attempts, want 200 after 2", resp.StatusCode, hits.Load())
+	}
+}
+
+func TestWriteFileAtomicConcurrent(t *testing.T) {
+	target := filepath.Join(t.TempDir(), "same-slug.png")
+	var wg sync.WaitGroup
+	for i := 0; i < 8; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			body := bytes.Repeat([]byte{byte('a' + i)}, 64<<10)
+			if err := writeFileAtomic(target, bytes.NewReader(body)); err != nil {
+				t.Error(err)
+			}
+		}(i)
+	}
+	wg.Wait()
+	b, err := os.ReadFile(target)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(b) != 64<<10 || bytes.Count(b, b[:1]) != len(b) {
+		t.Errorf("target mixes writers' bytes (len %d)", len(b))
+	}
+	if fi, _ := os.Stat(target); fi.Mode().Perm() != 0o644 {
+		t.Errorf("mode = %v, want 0644", fi.Mode().Perm())
+	}
+	if left, _ := filepath.Glob(filepath.Join(filepath.Dir(target), ".part-*")); len(left) > 0 {
+		t.Errorf("temp files left behind: %v", left)
+	}
+}
+
+func TestParseHTML(t *testing.T) {
+	tests := []struct {
+		name string
+		src  string
+		want []string // "source url" of each page candidate, in order
+	}{
+		{
+			name: "attribute order and case",
+			src:  `<HEADER><IMG ALT="Acme" Class="Logo" SRC="/a.png"></HEADER><img src="/b.png" class="site-logo">`,
+			want: []string{"img https://acme.example/a.png", "img https://acme.example/b.png"},
+		},
+		{
+			name: "unquoted and single-quoted values",
+			src:  `<img class=logo src=/a.png><img class='brand logo' src='/b c.png'>`,
+			want: []string{"img https://acme.example/a.png", "img https://acme.example/b%20c.png"},
+		},
+		{
+			name: "entities in attributes",
+			src:  `<img class="logo" src="/logo.png?w=200&amp;h=80&#38;v=2" alt="Ben &amp; Jerry&#39;s">`,
+			want: []string{"img https://acme.example/logo.png?w=200&h=80&v=2"},
+		},
+		{
+			name: "base href",
+			src:  `<head><base href="https://cdn.example/site/"></head><body><img class="logo" src="img/logo.svg"></body>`,
+			want: []string{"img https://cdn.example/site/img/logo.svg"},
+		},
+		{
+			name: "srcset picks the widest",
+			src:  `<img class="logo" src="/small.png" srcset="/logo-1x.png 200w, /logo-3x.png 600w, /logo-2x.png 400w">`,
+			want: []string{"img https://acme.example/logo-3x.png"},
+		},
+		{
+			name: "picture source before img",
+			src:  `<picture><source type="image/webp" srcset="/logo.webp 1x, /logo@2x.webp 2x"><img class="logo" src="/logo.png"></picture>`,
+			want: []string{"img https://acme.example/logo@2x.webp"},
+		},
+		{
+			name: "og:image variants and twitter:image",
+			src: `<meta property="og:image:secure_url" content="https://acme.example/og-secure.png">
+<meta name="twitter:image" content="/tw.png"><meta property="og:image:width" content="600">`,
+			want: []string{"og:image https://acme.example/og-secure.png", "og:image https://acme.example/tw.png"},
+		},
+		{
+			name: "itemprop logo",
+			src: `<div itemscope itemtype="https://schema.org/Organization">
+<meta itemprop="logo" content="/meta-logo.png"><link itemprop="url logo" href="/link-logo.png">
+<img itemprop="image logo" src="/img-logo.png"></div>`,
+			want: []string{
+				"itemprop https://acme.example/meta-logo.png",
+				"itemprop https://acme.example/link-logo.png",
+				"itemprop https://acme.example/img-logo.png",
+				"img https://acme.example/img-logo.png",
+			},
+		},
+		{
+			name: "unclosed tags",
+			src:  `<header><div class="brand"><a href="/"><img src="/brand.png"></header><p>text<img src="/photo.jpg"><div`,
+			want: []string{"img https://acme.example/brand.png"},
+		},
+	}
+	page, _ := url.Parse("https://acme.example/about/")
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			doc := parseHTML([]byte(tt.src))
+			var got []string
+			for _, c := range pageLogoCandidates(doc, doc.baseURL(page), []byte(tt.src)) {
+				got = append(got, c.Source+" "+c.URL)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("candidates:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(tt.want, "\n"))
+			}
+		})
+	}
+
+	// The tree itself: attributes keep document order with lowercased keys,
+	// and an unclosed element ends where the document does.
+	src := `<DIV ID="x" data-A='1' hidden><span>one<b>two</div><p title=&quot;q&quot;>`
+	doc := parseHTML([]byte(src))
+	div := doc.findAll("div")[0]
+	if len(div.attrs) != 3 || div.attrs[0] != (htmlAttr{"id", "x"}) || div.attrs[1] != (htmlAttr{"data-a", "1"}) || div.attrs[2] != (htmlAttr{"hidden", ""}) {
+		t.Errorf("div attrs = %+v", div.attrs)
+	}
+	if b := doc.findAll("b")[0]; b.parent.tag != "span" || b.closest(func(n *htmlNode) bool { return n.tag == "div" }) != div {
+		t.Errorf("<b> is not inside span inside div")
+	}
+	if p := doc.findAll("p"); len(p) != 1 || p[0].parent != doc || p[0].attr("title") != `"q"` || p[0].end != len(src) {
+		t.Errorf("<p> = %+v", p)
+	}
+}
+
+func TestRankCandidates(t *testing.T) {
+	base, _ := url.Parse("https://acme.example/")
+	doc := parseHTML([]byte(`<!doctype html><html><head>
+<meta property="og:image" content="/share-card.jpg">
+<meta property="og:image:width" content="1200"><meta property="og:image:height" content="630">
+<link rel="icon" href="/favicon-16.png" sizes="16x16">
+<link rel="apple-touch-icon" href="/touch.png">
+</head><body>
+<header><a href="/"><img class="site-logo" src="/img/acme-logo.svg" alt="Acme"></a></header>
+<main><img src="/img/hero-banner.jpg" alt="Acme logo on a truck"></main>
+</body></html>`))
+	ranked := rankCandidates(append(pageLogoCandidates(doc, base, nil), commonPathCandidates(base)...))
+
+	pos := make(map[string]int)
+	for i, c := range ranked {
+		path := strings.TrimPrefix(c.URL, "https://acme.example")
+		if _, dup := pos[path]; dup {
+			t.Errorf("%s ranked twice", path)
+		}
+		pos[path] = i
+	}
+	if ranked[0].URL != "https://acme.example/img/acme-logo.svg" {
+		t.Errorf("best = %s (%v), want the header logo", ranked[0].URL, ranked[0].Reasons)
+	}
+	for _, order := range [][2]string{
+		{"/touch.png", "/favicon-16.png"}, // declared size beats a tiny icon
+		{"/touch.png", "/share-card.jpg"}, // share cards are rarely the logo
+		{"/img/acme-logo.svg", "/img/hero-banner.jpg"},
+	} {
+		if pos[order[0]] >= pos[order[1]] {
+			t.Errorf("%s ranked %d, below %s at %d", order[0], pos[order[0]], order[1], pos[order[1]])
+		}
+	}
+}
+
+func TestJSONLD(t *testing.T) {
+	page := []byte(`<html><head><script type="application/ld+json">
+{"@context": "https://schema.org", "@graph": [
+  {"@type": "WebSite", "name": "Acme", "publisher": {"@type": "Organization", "logo": {"@type": "ImageObject", "url": "/p-logo.png"}}},
+  {"@type": ["Restaurant", "LocalBusiness"], "logo": "/logo.svg", "image": ["/storefront.jpg"],
+   "sameAs": ["https://www.facebook.com/acme", "https://www.instagram.com/acmetacos/"],
+   "brand": {"logo": "/brand.png"}, "parentOrganization": {"@type": "Corporation", "logo": "/parent.png", "sameAs": "https://x.com/acmecorp"},
+   "department": [{"@type": "Store", "logo": "/dept-a.png"}, {"@type": "Store", "logo": "/dept-b.png"}]},
+  {"@type": "Article", "image": "/article.jpg", "logo": "/not-ours.png"}
+]}
+</script><script type="application/ld+json">{not json</script></head></html>`)
+	base, _ := url.Parse("https://acme.example/")
+	first := parseJSONLD(parseHTML(page), base)
+
+	want := jsonLDInfo{
+		Logos: []string{"https://acme.example/p-logo.png", "https://acme.example/logo.svg", "https://acme.example/brand.png",
+			"https://acme.example/dept-a.png", "https://acme.example/dept-b.png", "https://acme.example/parent.png"},
+		Images: []string{"https://acme.example/storefront.jpg"},
+		SameAs: []string{"https://www.facebook.com/acme", "https://www.instagram.com/acmetacos/", "https://x.com/acmecorp"},
+	}
+	if fmt.Sprint(first) != fmt.Sprint(want) {
+		t.Errorf("parseJSONLD =\n%v\nwant\n%v", first, want)
+	}
+	// Nested objects are walked in key order, not map order.
+	for i := 0; i < 20; i++ {
+		if got := parseJSONLD(parseHTML(page), base); fmt.Sprint(got) != fmt.Sprint(first) {
+			t.Fatalf("run %d differs:\n%v\n%v", i, got, first)
+		}
+	}
+	if h := instagramFromSameAs(first.SameAs); h != "acmetacos" {
+		t.Errorf("instagram from sameAs = %q", h)
+	}
+
+	// Enrichment and discovery share one fetch of the homepage.
+	var homeHits atomic.Int32
+	srv := fakeSite(t, map[string]route{
+		"/logo.png": {ct: "image/png", body: pngBytes(t, 300, 150)},
+	})
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.Redirect(w, r, srv.URL+r.URL.Path, http.StatusFound)
+			return
+		}
+		homeHits.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><script type="application/ld+json">{"@type": "Organization", "logo": "/logo.png", "sameAs": ["https://www.instagram.com/acmetacos/"]}</script></head></html>`)
+	})
+	home := httptest.NewServer(mux)
+	defer home.Close()
+	dir := t.TempDir()
+	opts := &runOptions{
+		publicDir: dir,
+		logoDir:   filepath.Join(dir, "images", "logos"),
+		client:    testClient(),
+		ua:        "test",
+		econf:     EnrichConfig{Policy: enrichAll, Provider: "serpapi"},
+	}
+	res := processSponsor(opts, Sponsor{Name: "Acme Tacos", Href: home.URL + "/"})
+	if res.sponsor.Instagram != "acmetacos" || res.sponsor.Logo != "/images/logos/acme-tacos.png" {
+		t.Errorf("sponsor = %+v\n%s", res.sponsor, res.out.String())
+	}
+	if n := homeHits.Load(); n != 1 {
+		t.Errorf("homepage fetched %d times, want 1", n)
+	}
+}
+
+func TestManifestFallbackPaths(t *testing.T) {
+	manifest := []byte(`{"icons": [{"src": "icons/brand-512.png", "sizes": "512x512"}, {"src": "icons/mask.png", "sizes": "512x512", "purpose": "maskable"}]}`)
+	for _, path := range []string{"/site.webmanifest", "/manifest.json", "/manifest.webmanifest"} {
+		t.Run(path, func(t *testing.T) {
+			routes := map[string]route{path: {ct: "application/manifest+json", body: manifest}}
+			if path != "/site.webmanifest" {
+				// Soft 404: HTML served with 200 at the first conventional path.
+				routes["/site.webmanifest"] = htmlPage("<title>Home</title>")
+			}
+			srv := fakeSite(t, routes)
+			base, _ := url.Parse(srv.URL + "/")
+			cands := manifestCandidates(testClient(), "test", srv.URL, nil, base)
+			if len(cands) != 1 || cands[0].URL != srv.URL+"/icons/brand-512.png" || cands[0].Width != 512 {
+				t.Errorf("candidates = %+v", cands)
+			}
+		})
+	}
+}
+
+func TestInspectImage(t *testing.T) {
+	// VP8L header: signature 0x2f, then 14-bit width-1 and height-1.
+	// VP8L header: signature 0x2f, then 14-bit width-1 and height-1, and
+	// the alpha_is_used bit.
+	vp8l := func(w, h int, alpha bool) []byte {
+		b := []byte("RIFF\x00\x00\x00\x00WEBPVP8L\x00\x00\x00\x00\x2f")
+		bits := uint32(w-1) | uint32(h-1)<<14
+		if alpha {
+			bits |= 1 << 28
+		}
+		b = binary.LittleEndian.AppendUint32(b, bits)
+		return append(b, make([]byte, 16)...)
+	}
+	var gif89 bytes.Buffer
+	gif.Encode(&gif89, image.NewPaletted(image.Rect(0, 0, 8, 8), color.Palette{color.Transparent, color.Black}), nil)
+	tests := []struct {
+		name    string
+		data    []byte
+		format  string
+		w, h    int
+		alpha   bool
+		wantErr string
+	}{
+		{"png", pngBytes(t, 120, 40), "png", 120, 40, false, ""},
+		{"transparent png", transparentPNGBytes(t, 120, 40), "png", 120, 40, true, ""},
+		{"gif with transparent index", gif89.Bytes(), "gif", 8, 8, true, ""},
+		{"webp", vp8l(300, 100, false), "webp", 300, 100, false, ""},
+		{"webp with alpha", vp8l(300, 100, true), "webp", 300, 100, true, ""},
+		{"svg viewBox", []byte(`<?xml version="1.0"?><svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 240 80"></svg>`), "svg", 240, 80, true, ""},
+		{"svg px size", []byte(`<svg width="64px" height="32"></svg>`), "svg", 64, 32, true, ""},
+		{"ico", icoWithPNG(t, 32), "ico", 0, 0, false, ""},
+		{"html", []byte("<!DOCTYPE html><html><title>Not found</title></html>"), "", 0, 0, false, "HTML page"},
+		{"corrupt png", []byte("\x89PNG\r\n\x1a\nnope"), "", 0, 0, false, "corrupt image"},
+		{"svg root", []byte(`<html><body><svg></svg></body></html>`), "", 0, 0, false, "HTML page"},
+		{"text", []byte("hello"), "", 0, 0, false, "unrecognized"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			info, err := inspectImage(tt.data)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("err = %v, want %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if info.Format != tt.format || info.Width != tt.w || info.Height != tt.h || info.Alpha != tt.alpha {
+				t.Errorf("got %+v, want %s %dx%d alpha=%v", info, tt.format, tt.w, tt.h, tt.alpha)
+			}
+		})
+	}
+
+	gate := imageGate{MinSide: 64, MaxAspect: 6}
+	for _, tt := range []struct {
+		info imageInfo
+		ok   bool
+	}{
+		{imageInfo{Format: "png", Width: 200, Height: 100}, true},
+		{imageInfo{Format: "png", Width: 48, Height: 48}, false},    // too small
+		{imageInfo{Format: "png", Width: 1400, Height: 100}, false}, // too stretched
+		{imageInfo{Format: "svg"}, true},                            // scalable, unmeasured
+		{imageInfo{Format: "svg", Width: 32, Height: 32}, true},     // vectors have no minimum
+		{imageInfo{Format: "webp"}, false},
+	} {
+		if err := gate.check(tt.info); (err == nil) != tt.ok {
+			t.Errorf("check(%+v) = %v, want ok=%v", tt.info, err, tt.ok)
+		}
+	}
+}
+
+func TestNormalizeLogoOriginals(t *testing.T) {
+	dir := t.TempDir()
+	logos := filepath.Join(dir, "public", "images", "logos")
+	cfg := normalizeConfig{Enable: true, MaxWidth: 100, Tolerance: 8, Format: "jpg", Originals: filepath.Join(dir, "logos", originalsDirName)}
+	path := filepath.Join(logos, "acme.png")
+	if err := writeFileAtomic(path, bytes.NewReader(pngBytes(t, 400, 200))); err != nil {
+		t.Fatal(err)
+	}
+
+	out, err := normalizeLogo(path, cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if out != filepath.Join(logos, "acme.jpg") || fileExists(path) {
+		t.Errorf("normalized to %s (png left behind: %v)", out, fileExists(path))
+	}
+	if !fileExists(filepath.Join(cfg.Originals, "acme.png")) {
+		t.Error("original not stashed in the originals dir")
+	}
+
+	// Re-normalizing starts from the stored original, not the last result.
+	writeFileAtomic(out, bytes.NewReader(pngBytes(t, 10, 10)))
+	if _, err := normalizeLogo(out, cfg, false); err != nil {
+		t.Fatal(err)
+	}
+	b, _ := os.ReadFile(out)
+	if info, err := inspectImage(b); err != nil || info.Width != 100 {
+		t.Errorf("result %+v (%v), want 100px wide from the 400px original", info, err)
+	}
+}
+
+func TestNormalizeLogoWebP(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake cwebp is a shell script")
+	}
+	bin := t.TempDir()
+	// The fake encoder writes a marker to its last argument, the output file.
+	script := "#!/bin/sh\nfor a; do out=$a; done\nprintf 'RIFF-from-cwebp' > \"$out\"\n"
+	if err := os.WriteFile(filepath.Join(bin, cwebpTool), []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("PATH", bin)
+
+	dir := t.TempDir()
+	path := filepath.Join(dir, "acme.png")
+	writeFileAtomic(path, bytes.NewReader(pngBytes(t, 400, 200)))
+	cfg := normalizeConfig{Enable: true, MaxWidth: 100, Tolerance: 8, Format: "webp", Originals: filepath.Join(dir, "originals")}
+	out, err := normalizeLogo(path, cfg, true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b, _ := os.ReadFile(out); out != filepath.Join(dir, "acme.webp") || string(b) != "RIFF-from-cwebp" {
+		t.Errorf("normalized to %s = %q, want cwebp's output in acme.webp", out, b)
+	}
+
+	t.Setenv("PATH", t.TempDir())
+	bolt := filepath.Join(dir, "bolt.png")
+	writeFileAtomic(bolt, bytes.NewReader(pngBytes(t, 400, 200)))
+	if _, err := normalizeLogo(bolt, cfg, true); err == nil || !strings.Contains(err.Error(), cwebpTool) {
+		t.Errorf("without cwebp: err = %v", err)
+	}
+}
+
+// icoWithBMP builds a single-frame ICO holding a 24-bpp bitmap: red, with
+// the left half masked transparent.
+func icoWithBMP(size int) []byte {
+	stride := (size*3 + 3) &^ 3
+	maskStride := ((size + 31) / 32) * 4
+	var dib bytes.Buffer
+	binary.Write(&dib, binary.LittleEndian, []uint32{40, uint32(size), uint32(2 * size)})
+	binary.Write(&dib, binary.LittleEndian, []uint16{1, 24})
+	binary.Write(&dib, binary.LittleEndian, make([]uint32, 6))
+	row := make([]byte, stride)
+	for x := 0; x < size; x++ {
+		row[3*x+2] = 255 // BGR: red
+	}
+	for y := 0; y < size; y++ {
+		dib.Write(row)
+	}
+	mask := make([]byte, maskStride)
+	for x := 0; x < size/2; x++ {
+		mask[x/8] |= 0x80 >> (x % 8)
+	}
+	for y := 0; y < size; y++ {
+		dib.Write(mask)
+	}
+	var buf bytes.Buffer
+	binary.Write(&buf, binary.LittleEndian, []uint16{0, 1, 1})
+	buf.Write([]byte{byte(size), byte(size), 0, 0})
+	binary.Write(&buf, binary.LittleEndian, []uint16{1, 24})
+	binary.Write(&buf, binary.LittleEndian, []uint32{uint32(dib.Len()), 22})
+	buf.Write(dib.Bytes())
+	return buf.Bytes()
+}
+
+func TestICOToPNG(t *testing.T) {
+	b, err := icoToPNG(icoWithPNG(t, 48))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if info, _ := inspectImage(b); info.Format != "png" || info.Width != 48 {
+		t.Errorf("PNG frame: got %+v", info)
+	}
+
+	b, err = icoToPNG(icoWithBMP(32))
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(b))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, _, _, a := img.At(4, 4).RGBA(); a != 0 {
+		t.Errorf("masked pixel alpha = %d, want transparent", a)
+	}
+	if r, g, _, a := img.At(28, 4).RGBA(); r>>8 != 255 || g != 0 || a>>8 != 255 {
+		t.Errorf("unmasked pixel = %v, want opaque red", img.At(28, 4))
+	}
+
+	// A small favicon clears the ICO floor though it is under MinSide.
+	dir := t.TempDir()
+	gate := imageGate{MinSide: 64, MinICOSide: 16, MaxAspect: 6}
+	b, info, err := checkImage(icoWithBMP(32), gate)
+	if err != nil || info.Format != "png" {
+		t.Fatalf("checkImage = %+v, %v; want a PNG", info, err)
+	}
+	saved, err := writeImage(&logoImage{Data: b, Info: info}, filepath.Join(dir, "acme.ico"))
+	if err != nil || saved != filepath.Join(dir, "acme.png") {
+		t.Errorf("writeImage = %q, %v; want acme.png", saved, err)
+	}
+	gate.MinICOSide = 48
+	if _, _, err := checkImage(icoWithBMP(32), gate); err == nil {
+		t.Error("32px ICO accepted below -min-ico-px 48")
+	}
+}
+
+func TestSanitizeSVG(t *testing.T) {
+	in := []byte(`<?xml version="1.0"?>
+<!DOCTYPE svg [<!ENTITY x "boom">]>
+<svg xmlns="http://www.w3.org/2000/svg" xmlns:xlink="http://www.w3.org/1999/xlink" xmlns:inkscape="http://www.inkscape.org/namespaces/inkscape" width="120px" height="40" onload="alert(1)">
+  <script>alert(document.cookie)</script>
+  <inkscape:grid/>
+  <style>.a{fill:url(#g)} .b{background:url(https://evil.example/x.png)}</style>
+  <defs><linearGradient id="g"><stop offset="0" stop-color="#f00"/></linearGradient></defs>
+  <a href="javascript:alert(1)"><path d="M0 0h10v10z"/></a>
+  <path class="a" d="M0 0h120v40z" onclick="steal()" fill="url(#g)" style="fill:url('https://evil.example/t')"/>
+  <use xlink:href="#g"/><use href="https://evil.example/sprite.svg#logo"/>
+  <image href="data:image/png;base64,iVBORw0KGgo="/><image xlink:href="https://evil.example/pixel.gif"/>
+  <foreignObject><div xmlns="http://www.w3.org/1999/xhtml"><iframe src="https://evil.example"></iframe></div></foreignObject>
+  <text x="4" y="30">Acme &amp; Co</text>
+  <style>@im/**/port "https://evil.example/c.css";</style>
+  <rect width="1" height="1" style="fill:\75rl(https://evil.example/u)"/><rect width="2" height="1" fill="u\rl(https://evil.example/r)"/>
+</svg>`)
+	out, err := sanitizeSVG(in)
+	if err != nil {
+		t.Fatal(err)
+	}
+	s := string(out)
+	for _, bad := range []string{"script", "alert", "onload", "onclick", "evil.example", "foreignObject", "iframe", "inkscape", "ENTITY", "<a"} {
+		if strings.Contains(s, bad) {
+			t.Errorf("sanitized SVG still contains %q:\n%s", bad, s)
+		}
+	}
+	for _, keep := range []string{`viewBox="0 0 120 40"`, `fill="url(#g)"`, `xlink:href="#g"`, `xmlns:xlink=`, "data:image/png", "Acme &amp; Co", `<stop offset="0"`} {
+		if !strings.Contains(s, keep) {
+			t.Errorf("sanitized SVG lost %q:\n%s", keep, s)
+		}
+	}
+	if info, err := inspectImage(out); err != nil || info.Width != 120 || info.Height != 40 {
+		t.Errorf("sanitized SVG inspects as %+v, %v", info, err)
+	}
+
+	for _, css := range []string{
+		`fill:\75rl(https://evil.example/x)`,
+		`fill:\000075 rl(https://evil.example/x)`,
+		`fill:u\rl(https://evil.example/x)`,
+		`fill:u/**/rl(https://evil.example/x)`,
+		`@im/**/port "https://evil.example/x.css";`,
+		`@\69mport "https://evil.example/x.css";`,
+		`fill:url(\68ttps://evil.example/x)`,
+		`width:expr\
+ession(alert(1))`,
+		`behavior/* x */:url(#b)`,
+		`fill:red;background:image-set("https://evil.example/x.png" 1x)`,
+		`background:-webkit-image-set('https://evil.example/x.png' 1x)`,
+		`background:cross-fade(url(#a), "https://evil.example/x.png", 50%)`,
+		`background:image("https://evil.example/x.png")`,
+		`background:src("https://evil.example/x.png")`,
+		`.a{background:i\6d age-set("//evil.example/x.png" 1x)}`,
+		`@font-face{font-family:x}.a{list-style:"https://evil.example/x"}`,
+		`.a{cursor:"//evil.example/c.cur"}`,
+		`.a{content:'data:image/svg+xml,<svg/>'}`,
+	} {
+		if _, ok := sanitizeCSS(css); ok {
+			t.Errorf("sanitizeCSS kept %q", css)
+		}
+	}
+	for _, css := range []string{`fill:url(#g) /* brand \2014 red */`, `.a{font-family:"Acme\20 Sans"}`, `.a{font-family:'Open Sans', "Helvetica"}`} {
+		if got, ok := sanitizeCSS(css); !ok || got != css {
+			t.Errorf("sanitizeCSS(%q) = %q, %v", css, got, ok)
+		}
+	}
+
+	for name, bad := range map[string]string{
+		"no size":      `<svg xmlns="http://www.w3.org/2000/svg"><path d="M0 0h1v1z"/></svg>`,
+		"not svg root": `<html><svg viewBox="0 0 1 1"></svg></html>`,
+		"unclosed":     `<svg viewBox="0 0 10 10"><g><path d="M0 0"/>`,
+	} {
+		if _, err := sanitizeSVG([]byte(bad)); err == nil {
+			t.Errorf("%s: sanitized without error", name)
+		}
+	}
+	if _, _, err := checkImage([]byte(`<svg xmlns="http://www.w3.org/2000/svg"><script>x()</script></svg>`), imageGate{}); err == nil || !strings.Contains(err.Error(), "unsafe SVG") {
+		t.Errorf("checkImage of an unsizable scripted SVG: err = %v", err)
+	}
+}
+
+func TestRasterizeSVG(t *testing.T) {
+	// A red square with an even-odd hole, and a blue class-styled circle
+	// placed by a transform on the right half.
+	svg := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100">
+<style>.dot{fill:#00f}</style>
+<path fill="red" fill-rule="evenodd" d="M10 10h80v80H10zM30 30v40h40V30z"/>
+<g transform="translate(100 0)"><circle class="dot" cx="50" cy="50" r="40"/></g>
+</svg>`)
+	img, err := rasterizeSVG(svg, 400)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 400 || b.Dy() != 200 {
+		t.Fatalf("size = %v, want 400x200", b)
+	}
+	for _, tt := range []struct {
+		x, y    int
+		r, g, b uint8
+		a       uint8
+	}{
+		{40, 40, 255, 0, 0, 255}, // square
+		{100, 100, 0, 0, 0, 0},   // hole
+		{300, 100, 0, 0, 255, 255},
+		{5, 5, 0, 0, 0, 0}, // background stays transparent
+	} {
+		if c := img.RGBAAt(tt.x, tt.y); c.R != tt.r || c.G != tt.g || c.B != tt.b || c.A != tt.a {
+			t.Errorf("pixel (%d,%d) = %v, want %d,%d,%d,%d", tt.x, tt.y, c, tt.r, tt.g, tt.b, tt.a)
+		}
+	}
+
+	// A sponsor with an SVG logo on disk gets a PNG companion and logoPng.
+	dir := t.TempDir()
+	opts := &runOptions{publicDir: dir, logoDir: filepath.Join(dir, "images", "logos"), pngSize: 64}
+	if err := writeFileAtomic(filepath.Join(opts.logoDir, "acme.svg"), bytes.NewReader(svg)); err != nil {
+		t.Fatal(err)
+	}
+	res := processSponsor(opts, Sponsor{Name: "Acme", Href: "https://acme.example/", Instagram: "acme", Logo: "/images/logos/acme.svg"})
+	if res.sponsor.LogoPng != "/images/logos/acme.png" || !res.changed {
+		t.Errorf("logoPng = %q (changed %v)\n%s", res.sponsor.LogoPng, res.changed, res.out.String())
+	}
+	b, _ := os.ReadFile(filepath.Join(opts.logoDir, "acme.png"))
+	if info, err := inspectImage(b); err != nil || info.Width != 64 || info.Height != 32 {
+		t.Errorf("companion = %+v, %v", info, err)
+	}
+
+	// What the renderer would draw wrongly is refused, not drawn.
+	for name, body := range map[string]string{
+		"text":           `<text x="0" y="10">Acme</text>`,
+		"clip-path":      `<clipPath id="c"><rect width="5" height="5"/></clipPath><rect width="10" height="10" clip-path="url(#c)"/>`,
+		"mask in style":  `<mask id="m"><rect width="5" height="5" fill="#fff"/></mask><g style="mask:url(#m)"><rect width="10" height="10"/></g>`,
+		"clip-path rule": `<style>.c{clip-path:url(#c)}</style><rect class="c" width="10" height="10"/>`,
+	} {
+		if _, err := rasterizeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10">`+body+`</svg>`), 64); err == nil {
+			t.Errorf("%s: rendered without error", name)
+		}
+	}
+	if _, err := rasterizeSVG([]byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 10 10"><rect width="10" height="10" clip-path="none"/></svg>`), 64); err != nil {
+		t.Errorf("clip-path none: %v", err)
+	}
+
+	// A word mark gets no companion, and the sponsor's output says so.
+	wordmark := []byte(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 20"><text x="0" y="15">Bolt</text></svg>`)
+	if err := writeFileAtomic(filepath.Join(opts.logoDir, "bolt.svg"), bytes.NewReader(wordmark)); err != nil {
+		t.Fatal(err)
+	}
+	res = processSponsor(opts, Sponsor{Name: "Bolt", Href: "https://bolt.example/", Instagram: "bolt", Logo: "/images/logos/bolt.svg"})
+	if res.sponsor.LogoPng != "" || fileExists(filepath.Join(opts.logoDir, "bolt.png")) || !strings.Contains(res.out.String(), "PNG companion failed") {
+		t.Errorf("word mark: logoPng %q\n%s", res.sponsor.LogoPng, res.out.String())
+	}
+}
+
+func TestParseSVGPath(t *testing.T) {
+	// Compact exporter output: implicit commands, packed decimals, and arc
+	// flags with no separators.
+	subs := parseSVGPath("M0,0l10-5.5.5 2a5 5 0 014 4zm1 1H5V9c1 1 2 2 3 3s4 4 5 5Z")
+	if len(subs) != 2 {
+		t.Fatalf("got %d subpaths, want 2", len(subs))
+	}
+	first := subs[0].segs
+	if last := first[len(first)-1].pts; len(last) != 3 || math.Abs(last[2][0]-14.5) > 1e-9 || math.Abs(last[2][1]-0.5) > 1e-9 {
+		t.Errorf("arc ends at %v, want (14.5, 0.5)", last)
+	}
+	if !subs[0].closed || !subs[1].closed {
+		t.Error("subpaths not closed")
+	}
+	if start := subs[1].segs[0].pts[0]; start != [2]float64{1, 1} {
+		t.Errorf("relative move after close starts at %v, want (1, 1)", start)
+	}
+}
+
+func TestDuplicateLogos(t *testing.T) {
+	dir := t.TempDir()
+	logos := filepath.Join(dir, "images", "logos")
+	stripes := image.NewRGBA(image.Rect(0, 0, 200, 100))
+	for y := 0; y < 100; y++ {
+		for x := 0; x < 200; x++ {
+			stripes.Set(x, y, color.RGBA{uint8(255 * (x / 25 % 2)), 0, 0, 255})
+		}
+	}
+	var sb bytes.Buffer
+	png.Encode(&sb, stripes)
+	for name, b := range map[string][]byte{
+		"acme.png":       pngBytes(t, 200, 100),
+		"acme-copy.png":  pngBytes(t, 200, 100), // same bytes
+		"acme-small.png": pngBytes(t, 100, 50),  // rescaled
+		"bolt.png":       sb.Bytes(),
+	} {
+		if err := writeFileAtomic(filepath.Join(logos, name), bytes.NewReader(b)); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sponsors := []Sponsor{
+		{Name: "Acme", Logo: "/images/logos/acme.png", Instagram: "acme"},
+		{Name: "Bolt", Logo: "/images/logos/bolt.png", Instagram: "https://www.instagram.com/Acme/"},
+		{Name: "Acme West", Logo: "/images/logos/acme-copy.png"},
+	}
+	files, err := scanLogoFiles(logos, sponsors, dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	identical, similar := findDuplicates(files, 6)
+	if len(identical) != 1 || len(identical[0]) != 2 {
+		t.Fatalf("identical = %v", identical)
+	}
+	if len(similar) != 1 || !strings.HasPrefix(filepath.Base(similar[0].A.Path)+filepath.Base(similar[0].B.Path), "acme") {
+		t.Errorf("similar = %+v", similar)
+	}
+
+	var out bytes.Buffer
+	if !reportDuplicates(&out, sponsors, files, 6, true) {
+		t.Fatal("share made no change")
+	}
+	if sponsors[2].Logo != "/images/logos/acme.png" {
+		t.Errorf("Acme West logo = %q, want the first sponsor's file", sponsors[2].Logo)
+	}
+	if !strings.Contains(out.String(), "@acme") || !strings.Contains(out.String(), "Acme, Bolt") {
+		t.Errorf("shared Instagram handle not reported:\n%s", out.String())
+	}
+}
+
+func TestPruneOrphans(t *testing.T) {
+	dir := t.TempDir()
+	logos := filepath.Join(dir, "public", "images", "logos")
+	for _, name := range []string{"acme.svg", "acme.png", "bolt.jpg", "old.png", "ceramic_maroon", ".part-123", ".gitkeep"} {
+		if err := writeFileAtomic(filepath.Join(logos, name), strings.NewReader("x")); err != nil {
+			t.Fatal(err)
+		}
+	}
+	sponsors := []Sponsor{
+		{Name: "Acme", Logo: "/images/logos/acme.svg", LogoPng: "/images/logos/acme.png"},
+		{Name: "Bolt", Logo: "/images/logos/bolt.png"}, // file is bolt.jpg
+		{Name: "Ceramic", Logo: "/images/logos/ceramic.png"},
+		{Name: "No Logo"},
+	}
+	orphans, broken, err := findOrphans(logos, filepath.Join(dir, "public"), sponsors)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var names []string
+	for _, o := range orphans {
+		names = append(names, filepath.Base(o))
+	}
+	if got := strings.Join(names, " "); got != ".part-123 bolt.jpg ceramic_maroon old.png" {
+		t.Errorf("orphans = %s", got)
+	}
+	if len(broken) != 2 || broken[0].Sponsor != "Bolt" || filepath.Base(broken[0].Hint) != "bolt.jpg" || broken[1].Hint != "" {
+		t.Errorf("broken = %+v", broken)
+	}
+
+	trash, err := moveToTrash(orphans, filepath.Join(dir, "logos", "trash"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if fileExists(filepath.Join(logos, "old.png")) || !fileExists(filepath.Join(trash, "old.png")) {
+		t.Error("old.png not moved to the trash")
+	}
+	if !fileExists(filepath.Join(logos, "acme.png")) {
+		t.Error("a logoPng companion was pruned")
+	}
+}
+
+func TestEnrichOnlySkipsLogos(t *testing.T) {
+	var logoHits atomic.Int32
+	mux := http.NewServeMux()
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/html")
+		fmt.Fprint(w, `<html><head><script type="application/ld+json">{"@type": "Organization", "logo": "/logo.png", "sameAs": ["https://www.instagram.com/acmetacos/"]}</script></head></html>`)
+	})
+	mux.HandleFunc("/logo.png", func(w http.ResponseWriter, r *http.Request) {
+		logoHits.Add(1)
+		w.Header().Set("Content-Type", "image/png")
+		w.Write(pngBytes(t, 300, 150))
+	})
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	opts := &runOptions{
+		publicDir:  dir,
+		logoDir:    filepath.Join(dir, "images", "logos"),
+		enrichOnly: true,
+		client:     testClient(),
+		ua:         "test",
+		econf:      EnrichConfig{Policy: enrichAll, Provider: "serpapi"},
+	}
+	res := processSponsor(opts, Sponsor{Name: "Acme Tacos", Href: srv.URL + "/"})
+	if res.outcome != outcomeSaved || res.sponsor.Instagram != "acmetacos" || res.sponsor.Logo != "" {
+		t.Errorf("outcome %d, sponsor %+v\n%s", res.outcome, res.sponsor, res.out.String())
+	}
+	if n := logoHits.Load(); n != 0 {
+		t.Errorf("logo fetched %d times in enrich-only mode", n)
+	}
+	res = processSponsor(opts, res.sponsor)
+	if res.outcome != outcomeOK || res.changed {
+		t.Errorf("second pass: outcome %d changed %v", res.outcome, res.changed)
+	}
+}
+
+// captureStdout returns what f prints to os.Stdout.
+func captureStdout(t *testing.T, f func()) string {
+	t.Helper()
+	r, w, err := os.Pipe()
+	if err != nil {
+		t.Fatal(err)
+	}
+	stdout := os.Stdout
+	os.Stdout = w
+	defer func() { os.Stdout = stdout }()
+	out := make(chan []byte)
+	go func() {
+		b, _ := io.ReadAll(r)
+		out <- b
+	}()
+	f()
+	w.Close()
+	return string(<-out)
+}
+
+func TestRunSponsorsConcurrent(t *testing.T) {
+	// Earlier sponsors' homepages answer slowest, so workers finish out of
+	// order.
+	delays := map[string]time.Duration{"/alpha/": 150 * time.Millisecond, "/delta/": 0, "/echo/": 75 * time.Millisecond}
+	mux := http.NewServeMux()
+	for path, delay := range delays {
+		delay := delay
+		mux.HandleFunc(path, func(w http.ResponseWriter, r *http.Request) {
+			time.Sleep(delay)
+			w.Header().Set("Content-Type", "text/html")
+			fmt.Fprint(w, `<html><head></head><body><header><img class="logo" src="logo.png"></header></body></html>`)
+		})
+		mux.HandleFunc(path+"logo.png", func(w http.ResponseWriter, r *http.Request) {
+			w.Header().Set("Content-Type", "image/png")
+			w.Write(pngBytes(t, 300, 150))
+		})
+	}
+	srv := httptest.NewServer(mux)
+	defer srv.Close()
+
+	dir := t.TempDir()
+	public := filepath.Join(dir, "public")
+	logos := filepath.Join(public, "images", "logos")
+	os.MkdirAll(logos, 0o755)
+	os.WriteFile(filepath.Join(logos, "bravo.png"), pngBytes(t, 300, 150), 0o644)
+	os.WriteFile(filepath.Join(logos, "foxtrot.png"), pngBytes(t, 300, 150), 0o644)
+	sitePath := filepath.Join(dir, "site.json")
+	os.WriteFile(sitePath, []byte(`{"sponsors": [
+  {"name": "Alpha", "href": "`+srv.URL+`/alpha/", "active": true},
+  {"name": "Bravo", "logo": "/images/logos/bravo.png", "active": true},
+  {"name": "Charlie", "active": true},
+  {"name": "Delta", "href": "`+srv.URL+`/delta/", "active": true},
+  {"name": "Echo", "href": "`+srv.URL+`/echo/", "active": true},
+  {"name": "Foxtrot", "logo": "/images/logos/foxtrot.png", "active": true}
+]}`), 0o644)
+
+	g := &globalOptions{sitePath: sitePath, publicDir: public}
+	opts := &runOptions{
+		publicDir: public,
+		logoDir:   logos,
+		gate:      imageGate{MinSide: 64, MaxAspect: 6},
+		client:    testClient(),
+		ua:        "test",
+		econf:     EnrichConfig{Policy: enrichNever},
+	}
+	out := captureStdout(t, func() {
+		runSponsors(g, opts, &sponsorFilter{}, &reviewFlags{}, 4, "saved", "ok", "failed")
+	})
+
+	// Each sponsor's lines come out together, in site.json order.
+	names := []string{"Alpha", "Bravo", "Charlie", "Delta", "Echo", "Foxtrot"}
+	at := make([]int, len(names)+1)
+	for i, name := range names {
+		at[i] = strings.Index(out, name)
+		if at[i] < 0 || i > 0 && at[i] < at[i-1] {
+			t.Fatalf("%s out of order:\n%s", name, out)
+		}
+	}
+	at[len(names)] = strings.Index(out, "\nSummary:")
+	for i, slug := range []string{"alpha", "", "", "delta", "echo", ""} {
+		if slug == "" {
+			continue
+		}
+		if block := out[at[i]:at[i+1]]; !strings.Contains(block, "saved: ") || !strings.Contains(block, slug+".png") {
+			t.Errorf("%s's block is missing its saved logo:\n%s", names[i], block)
+		}
+	}
+	if !strings.Contains(out, "Summary: 3 saved, 2 ok, 1 failed\n") {
+		t.Errorf("wrong summary:\n%s", out)
+	}
+
+	// Results land on the sponsors they belong to.
+	sponsors, _, err := readSite(sitePath)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, s := range sponsors {
+		got = append(got, s.Name+"="+s.Logo)
+	}
+	want := []string{
+		"Alpha=/images/logos/alpha.png",
+		"Bravo=/images/logos/bravo.png",
+		"Charlie=",
+		"Delta=/images/logos/delta.png",
+		"Echo=/images/logos/echo.png",
+		"Foxtrot=/images/logos/foxtrot.png",
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("sponsors = %q\nwant %q", got, want)
+	}
+}
+
+func TestEnrichPolicyAndBudget(t *testing.T) {
+	dir := t.TempDir()
+	opts := &runOptions{
+		publicDir:  dir,
+		logoDir:    filepath.Join(dir, "images", "logos"),
+		enrichOnly: true,
+		client:     testClient(),
+		ua:         "test",
+		econf:      EnrichConfig{Policy: enrichNever, Provider: "serpapi", SerpAPIKey: "k", Budget: newCallBudget(0)},
+	}
+	// No provider is reachable: any lookup would show up as a paid call.
+	res := processSponsor(opts, Sponsor{Name: "Acme Tacos"})
+	if res.outcome != outcomeOK || opts.econf.Budget.total() != 0 {
+		t.Errorf("policy never: outcome %d, %s", res.outcome, opts.econf.Budget.summary())
+	}
+	opts.econf.Policy = enrichMissingInstagram
+	res = processSponsor(opts, Sponsor{Name: "Acme Tacos", Instagram: "acmetacos"})
+	if res.outcome != outcomeOK || opts.econf.Budget.total() != 0 {
+		t.Errorf("policy missing-instagram with instagram set: outcome %d, %s", res.outcome, opts.econf.Budget.summary())
+	}
+
+	b := newCallBudget(2)
+	cfg := EnrichConfig{Policy: enrichAll, Provider: "hybrid", SerpAPIKey: "k", OpenAIKey: "k", Budget: b}
+	for i := 0; i < 2; i++ {
+		if err := b.spend("serpapi"); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if _, _, err := enrichViaHybrid(testClient(), "test", "Acme Tacos", cfg); !errors.Is(err, errBudgetExhausted) {
+		t.Errorf("over budget: err = %v", err)
+	}
+	if got, want := b.summary(), "Paid calls: 2 of 2 (serpapi 2); budget reached, 1 calls refused"; got != want {
+		t.Errorf("summary = %q, want %q", got, want)
+	}
+}
+
+func TestDiskCache(t *testing.T) {
+	var full, notModified atomic.Int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path == "/old" {
+			http.Redirect(w, r, "/", http.StatusMovedPermanently)
+			return
+		}
+		if r.Header.Get("If-None-Match") == `"v1"` {
+			notModified.Add(1)
+			w.WriteHeader(http.StatusNotModified)
+			return
+		}
+		full.Add(1)
+		w.Header().Set("Content-Type", "text/html")
+		w.Header().Set("ETag", `"v1"`)
+		fmt.Fprint(w, "<title>Acme</title>")
+	}))
+	defer srv.Close()
+
+	c := &diskCache{dir: t.TempDir()}
+	client := testClient()
+	client.Transport = &cacheTransport{base: client.Transport, cache: c}
+	get := func() string {
+		t.Helper()
+		b, err := getHTML(client, "test", "", srv.URL+"/old")
+		if err != nil {
+			t.Fatal(err)
+		}
+		return string(b)
+	}
+	get()
+	get() // fresh: no request at all
+	if full.Load() != 1 || notModified.Load() != 0 {
+		t.Fatalf("fresh entry: %d full, %d not-modified responses", full.Load(), notModified.Load())
+	}
+
+	// Age the page past its TTL: it is revalidated, not downloaded again.
+	p := c.path(cacheHTML, srv.URL+"/")
+	b, _ := os.ReadFile(p)
+	var e cacheEntry
+	if err := json.Unmarshal(b, &e); err != nil {
+		t.Fatal(err)
+	}
+	e.Stored = e.Stored.Add(-48 * time.Hour)
+	b, _ = json.Marshal(e)
+	os.WriteFile(p, b, 0o644)
+	if got := get(); got != "<title>Acme</title>" || full.Load() != 1 || notModified.Load() != 1 {
+		t.Errorf("stale entry: body %q, %d full, %d not-modified responses", got, full.Load(), notModified.Load())
+	}
+
+	// Offline: cached pages still load, everything else fails without a request.
+	c.offline = true
+	get()
+	if _, err := getHTML(client, "test", "", srv.URL+"/new"); !errors.Is(err, errOffline) {
+		t.Errorf("offline miss: err = %v", err)
+	}
+
+	// Cached search results cost nothing, even with the budget spent.
+	c.store(cacheSerp, "Acme Tacos", serpResult{Web: []string{"https://acmetacos.com/"}})
+	cfg := EnrichConfig{Provider: "serpapi", Budget: newCallBudget(1), Cache: c}
+	cfg.Budget.spend("serpapi")
+	if w, _, err := enrichViaSerpAPI(client, "test", "Acme Tacos", cfg); err != nil || w != "https://acmetacos.com/" {
+		t.Errorf("cached serp lookup = %q, %v", w, err)
+	}
+	c.offline, c.refresh = false, true
+	var r serpResult
+	if c.lookup(cacheSerp, "Acme Tacos", &r) {
+		t.Error("refresh mode served a cached entry")
+	}
+}
+
+func TestRecordReplay(t *testing.T) {
+	srv := fakeSite(t, map[string]route{
+		"/":         htmlPage(`<img class="logo" src="/logo.png">`),
+		"/logo.png": {ct: "image/png", body: pngBytes(t, 300, 150)},
+	})
+	dir := t.TempDir()
+	rec := &http.Client{Transport: newRecordTransport(http.DefaultTransport, dir)}
+	page, err := getHTML(rec, "test", "", srv.URL+"/?api_key=secret")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !headOKImage(rec, "test", srv.URL, srv.URL+"/logo.png", false) {
+		t.Fatal("HEAD logo.png failed while recording")
+	}
+	logo, err := httpGET(rec, "test", srv.URL+"/logo.png", "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	files, _ := filepath.Glob(filepath.Join(dir, "*.json"))
+	if len(files) != 3 {
+		t.Errorf("recorded %d exchanges, want 3", len(files))
+	}
+	for _, f := range files {
+		if b, _ := os.ReadFile(f); bytes.Contains(b, []byte("secret")) {
+			t.Errorf("%s contains the API key", filepath.Base(f))
+		}
+	}
+
+	srv.Close()
+	rp := &http.Client{Transport: newRecordTransport(nil, dir)}
+	if got, err := getHTML(rp, "test", "", srv.URL+"/?api_key=other"); err != nil || !bytes.Equal(got, page) {
+		t.Errorf("replayed page = %q, %v", got, err)
+	}
+	if !headOKImage(rp, "test", srv.URL, srv.URL+"/logo.png", false) {
+		t.Error("replayed HEAD failed")
+	}
+	if got, err := httpGET(rp, "test", srv.URL+"/logo.png", ""); err != nil || !bytes.Equal(got, logo) {
+		t.Errorf("replayed logo differs (%v)", err)
+	}
+	if _, err := httpGET(rp, "test", srv.URL+"/missing.png", ""); err == nil {
+		t.Error("unrecorded request succeeded in replay")
+	}
+
+	// A new recording starts from an empty directory.
+	if err := clearRecordings(dir); err != nil {
+		t.Fatal(err)
+	}
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); len(files) != 0 {
+		t.Errorf("%d exchanges left after clearing", len(files))
+	}
+
+	// Bodies past the download cap pass through, unrecorded.
+	big := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/octet-stream")
+		io.CopyN(w, zeroReader{}, maxImageBytes+1)
+	}))
+	defer big.Close()
+	resp, err := rec.Get(big.URL + "/huge.bin")
+	if err != nil {
+		t.Fatal(err)
+	}
+	n, _ := io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if files, _ := filepath.Glob(filepath.Join(dir, "*.json")); n != maxImageBytes+1 || len(files) != 0 {
+		t.Errorf("oversized body: read %d bytes, recorded %d exchanges", n, len(files))
+	}
+
+	// Replayed calls are free.
+	cfg := EnrichConfig{Policy: enrichAll, Budget: newCallBudget(1)}
+	cfg.forReplay()
+	if cfg.Budget != nil || cfg.SerpAPIKey == "" || cfg.OpenAIKey == "" {
+		t.Errorf("replay config = %+v", cfg)
+	}
+}
+
+type zeroReader struct{}
+
+func (zeroReader) Read(p []byte) (int, error) {
+	clear(p)
+	return len(p), nil
+}
+
+func TestDiscoverLogoURL(t *testing.T) {
+	gate := imageGate{MinSide: 64, MinICOSide: 16, MaxAspect: 6}
+	cases := []struct {
+		name   string
+		routes map[string]route
+		want   string // path of the picked image; "" = discovery fails
+	}{
+		{"og:image", map[string]route{
+			"/":       htmlPage(`<meta property="og:image" content="/og.png">`),
+			"/og.png": {ct: "image/png", body: pngBytes(t, 300, 150)},
+		}, "/og.png"},
+		{"icon only", map[string]route{
+			"/":             htmlPage(`<link rel="icon" sizes="192x192" href="/icon-192.png">`),
+			"/icon-192.png": {ct: "image/png", body: pngBytes(t, 192, 192)},
+		}, "/icon-192.png"},
+		{"ico only", map[string]route{
+			"/":            htmlPage("<title>Home</title>"),
+			"/favicon.ico": {ct: "image/x-icon", body: icoWithPNG(t, 32)},
+		}, "/favicon.ico"},
+		{"redirect loop", map[string]route{
+			"/":     {status: http.StatusFound, location: "/home"},
+			"/home": {status: http.StatusFound, location: "/"},
+		}, ""},
+		{"html as image", map[string]route{
+			"/":                     htmlPage(`<meta property="og:image" content="/og.png">`),
+			"/og.png":               {ct: "image/png", body: []byte("<!doctype html><p>Not found</p>")},
+			"/apple-touch-icon.png": {ct: "image/png", body: pngBytes(t, 180, 180)},
+		}, "/apple-touch-icon.png"},
+		// Both rank the same before download; the bytes decide.
+		{"transparent beats opaque", map[string]route{
+			"/":           htmlPage(`<header><img class="logo" src="/logo-a.png"><img class="logo" src="/logo-b.png"></header>`),
+			"/logo-a.png": {ct: "image/png", body: pngBytes(t, 300, 300)},
+			"/logo-b.png": {ct: "image/png", body: transparentPNGBytes(t, 300, 300)},
+		}, "/logo-b.png"},
+		{"measured size beats undeclared", map[string]route{
+			"/":           htmlPage(`<header><img class="logo" src="/logo-a.png"><img class="logo" src="/logo-b.png"></header>`),
+			"/logo-a.png": {ct: "image/png", body: pngBytes(t, 100, 100)},
+			"/logo-b.png": {ct: "image/png", body: pngBytes(t, 600, 600)},
+		}, "/logo-b.png"},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			srv := fakeSite(t, tc.routes)
+			img, err := discoverLogoURL(testClient(), "test", srv.URL+"/", nil, nil, func(imgURL string) (*logoImage, error) {
+				return fetchImage(testClient(), "test", srv.URL, imgURL, gate)
+			})
+			if tc.want == "" {
+				if err == nil {
+					t.Errorf("picked %s, want failure", img.URL)
+				}
+				return
+			}
+			if err != nil || img.URL != srv.URL+tc.want {
+				t.Errorf("picked %+v (%v), want %s", img, err, tc.want)
+			}
+		})
+	}
+}
+
+// fakeProviders starts stand-ins for SerpAPI and OpenAI. The OpenAI server
+// answers chat completions with *answer as the message content, or fails
+// with a 500 when it is empty.
+func fakeProviders(t *testing.T, serpDown bool, answer *string) EnrichConfig {
+	t.Helper()
+	serp := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if serpDown || r.URL.Query().Get("api_key") != "serp-key" {
+			http.Error(w, "unavailable", http.StatusServiceUnavailable)
+			return
+		}
+		links := []string{"https://www.yelp.com/biz/acme-tacos", "https://acmetacos.example/menu?ref=serp"}
+		if strings.Contains(r.URL.Query().Get("q"), "instagram") {
+			links = []string{"https://www.instagram.com/acmetacos/"}
+		}
+		var res struct {
+			Organic []map[string]string `json:"organic_results"`
+		}
+		for _, l := range links {
+			res.Organic = append(res.Organic, map[string]string{"link": l})
+		}
+		json.NewEncoder(w).Encode(res)
+	}))
+	t.Cleanup(serp.Close)
+	openai := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/v1/chat/completions" || r.Header.Get("Authorization") != "Bearer openai-key" || *answer == "" {
+			http.Error(w, `{"error": "nope"}`, http.StatusInternalServerError)
+			return
+		}
+		msg := map[string]string{"role": "assistant", "content": *answer}
+		json.NewEncoder(w).Encode(map[string]interface{}{
+			"choices": []interface{}{map[string]interface{}{"message": msg}},
+		})
+	}))
+	t.Cleanup(openai.Close)
+	return EnrichConfig{
+		Provider:      "hybrid",
+		SerpAPIKey:    "serp-key",
+		OpenAIKey:     "openai-key",
+		SerpAPIURL:    serp.URL + "/search.json",
+		OpenAIBaseURL: openai.URL + "/v1/",
+	}
+}
+
+func TestEnrichViaHybrid(t *testing.T) {
+	const official = `{"website": "https://acmetacos.example/", "instagram": "https://www.instagram.com/acmetacos/"}`
+	cases := []struct {
+		name           string
+		serpDown       bool
+		answer         string
+		website, insta string
+	}{
+		{"openai chooses", false, official, "https://acmetacos.example/", "https://www.instagram.com/acmetacos/"},
+		{"openai answers in prose", false, "Website https://acmetacos.example/ and https://instagram.com/acme_official/ for sure",
+			"https://acmetacos.example/", "https://www.instagram.com/acme_official/"},
+		{"openai fails", false, "", "https://acmetacos.example/menu", "https://www.instagram.com/acmetacos/"},
+		{"openai unsure", false, `{"website": "", "instagram": ""}`, "https://acmetacos.example/menu", "https://www.instagram.com/acmetacos/"},
+		{"serpapi down", true, official, "https://acmetacos.example/", "https://www.instagram.com/acmetacos/"},
+		{"both down", true, "", "", ""},
+	}
+	for _, tc := range cases {
+		t.Run(tc.name, func(t *testing.T) {
+			answer := tc.answer
+			cfg := fakeProviders(t, tc.serpDown, &answer)
+			w, ig, err := enrichViaHybrid(testClient(), "test", "Acme Tacos", cfg)
+			if err != nil || w != tc.website || ig != tc.insta {
+				t.Errorf("got %q, %q, %v; want %q, %q", w, ig, err, tc.website, tc.insta)
+			}
+		})
+	}
+}
+
+func TestSerpCandidatesCachesOnlyCompleteResults(t *testing.T) {
+	var hits, igFailures atomic.Int32
+	igFailures.Store(1)
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		hits.Add(1)
+		link := "https://acmetacos.example/"
+		if strings.Contains(r.URL.Query().Get("q"), "instagram") {
+			if igFailures.Add(-1) >= 0 {
+				http.Error(w, "slow down", http.StatusTooManyRequests)
+				return
+			}
+			link = "https://www.instagram.com/acmetacos/"
+		}
+		fmt.Fprintf(w, `{"organic_results":[{"link":%q}]}`, link)
+	}))
+	defer srv.Close()
+	cfg := EnrichConfig{SerpAPIKey: "serp-key", SerpAPIURL: srv.URL + "/search.json", Cache: &diskCache{dir: t.TempDir()}}
+
+	// A failed Instagram search still returns the web results, but isn't
+	// cached as "no Instagram".
+	webs, igs, err := serpCandidates(testClient(), "test", "Acme Tacos", cfg)
+	if err != nil || len(webs) != 1 || len(igs) != 0 {
+		t.Fatalf("first: %q, %q, %v", webs, igs, err)
+	}
+	var hit serpResult
+	if cfg.Cache.lookup(cacheSerp, "Acme Tacos", &hit) {
+		t.Fatalf("partial result cached: %+v", hit)
+	}
+
+	webs, igs, err = serpCandidates(testClient(), "test", "Acme Tacos", cfg)
+	if err != nil || len(webs) != 1 || len(igs) != 1 {
+		t.Fatalf("retry: %q, %q, %v", webs, igs, err)
+	}
+	n := hits.Load()
+	if _, igs, _ = serpCandidates(testClient(), "test", "Acme Tacos", cfg); len(igs) != 1 || hits.Load() != n {
+		t.Errorf("complete result not served from the cache (%d requests, was %d)", hits.Load(), n)
+	}
+}
+
+func TestSiteRoundTrip(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "site.json")
+	original := `{
+  "year": 2025,
+  "links": {"tickets": "https://example.com/t?a=1&b=2"},
+  "sponsors": [
+    {
+      "name": "Acme Tacos",
+      "href": "",
+      "logo": "/images/logos/acme.svg",
+      "logoPng": "/images/logos/acme.png",
+      "active": true,
+      "featured": true,
+      "category": ["sponsor"],
+      "type": "community"
+    },   
+    {"name": "Bee \u0026 Co", "class": "wide", "href": "https://bee.example/", "active": false}
+  ],
+  "contactEmail": "hi@example.com"
+}
+`
+	if err := os.WriteFile(path, []byte(original), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sponsors, raw, err := readSite(path)
+	if err != nil || len(sponsors) != 2 {
+		t.Fatalf("readSite: %d sponsors, %v", len(sponsors), err)
+	}
+	sponsors[0].Href = "https://acmetacos.example/?a=1&b=2"
+	sponsors[0].Instagram = "acmetacos"
+	sponsors[0].LogoPng = ""
+	sponsors[0].Category = []string{"sponsor", "chili"}
+	if err := writeSite(path, raw, sponsors, false); err != nil {
+		t.Fatal(err)
+	}
+
+	// Only the changed values move; the second sponsor, unknown fields, key
+	// order, and odd whitespace are untouched.
+	want := strings.NewReplacer(
+		`"href": "",`, `"href": "https://acmetacos.example/?a=1&b=2",
+      "instagram": "acmetacos",`,
+		`
+      "logoPng": "/images/logos/acme.png",`, ``,
+		`"category": ["sponsor"],`, `"category": ["sponsor", "chili"],`,
+	).Replace(original)
+	got, _ := os.ReadFile(path)
+	if string(got) != want {
+		t.Errorf("site.json after write:\n%s\nwant:\n%s", got, want)
+	}
+	backups, _ := filepath.Glob(path + ".bak-*")
+	if len(backups) != 1 {
+		t.Fatalf("%d backups, want 1", len(backups))
+	}
+	if b, _ := os.ReadFile(backups[0]); string(b) != original {
+		t.Error("backup differs from the original file")
+	}
+
+	// Writing back what was read changes nothing.
+	sponsors, raw, _ = readSite(path)
+	if err := writeSite(path, raw, sponsors, false); err != nil {
+		t.Fatal(err)
+	}
+	if out, _ := os.ReadFile(path); !bytes.Equal(out, raw) {
+		t.Error("no-op write changed the file")
+	}
+}
+
+func TestNormalizeSponsors(t *testing.T) {
+	rules := normalizeRules{
+		Types:      lowerKeys(map[string]string{"Best Friend": "best-friend"}),
+		Categories: lowerKeys(map[string]string{"food truck": "foodtruck", "kids": ""}),
+	}
+	doc := []byte(`{"contactEmail": "", "social": {"facebook": ""}, "sponsors": [
+  {"name": " Acme ", "href": "", "instagram": "https://www.instagram.com/acme_tacos/", "logo": "", "active": true, "category": ["Sponsor", "food truck", "sponsor", "kids"], "type": "best friend", "featured": true},
+  {"name": "Bee", "href": "https://bee.example/", "instagram": "bee", "active": false, "category": ["chili"], "type": "community"}
+]}`)
+	path := filepath.Join(t.TempDir(), "site.json")
+	if err := os.WriteFile(path, doc, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	sponsors, raw, err := readSite(path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	empty, err := emptySponsorFields(raw)
+	if err != nil || !reflect.DeepEqual(empty, [][]string{{"href", "logo"}, nil}) {
+		t.Errorf("emptySponsorFields = %q, %v", empty, err)
+	}
+
+	s, changes := normalizeSponsor(sponsors[0], rules)
+	want := Sponsor{Name: "Acme", Instagram: "acme_tacos", Active: true, Category: []string{"sponsor", "foodtruck"}, Type: "best-friend", Featured: true}
+	if !reflect.DeepEqual(s, want) || len(changes) != 4 {
+		t.Errorf("normalizeSponsor = %+v, changes %q", s, changes)
+	}
+	sponsors[0] = s
+	if _, changes := normalizeSponsor(sponsors[1], rules); len(changes) != 0 {
+		t.Errorf("canonical sponsor changed: %q", changes)
+	}
+
+	if err := writeSite(path, raw, sponsors, true); err != nil {
+		t.Fatal(err)
+	}
+	got, _ := os.ReadFile(path)
+	wantDoc := `{"contactEmail": "", "social": {"facebook": ""}, "sponsors": [
+  {"name": "Acme", "instagram": "acme_tacos", "active": true, "category": ["sponsor", "foodtruck"], "type": "best-friend", "featured": true},
+  {"name": "Bee", "href": "https://bee.example/", "instagram": "bee", "active": false, "category": ["chili"], "type": "community"}
+]}`
+	if string(got) != wantDoc {
+		t.Errorf("site.json after normalize:\n%s\nwant:\n%s", got, wantDoc)
+	}
+}
+
+func TestReviewAndApplyPatch(t *testing.T) {
+	before := []Sponsor{
+		{Name: "Acme Tacos", Logo: "/images/logos/acme.svg", LogoPng: "/images/logos/acme.png"},
+		{Name: "Bee Co", Href: "https://bee.example/"},
+	}
+	after := append([]Sponsor(nil), before...)
+	after[0].Href = "https://acme-tacos-phoenix.example/"
+	after[0].Instagram = "acmetacos"
+	after[0].LogoPng = ""
+	after[1].Logo = "/images/logos/bee-co.png"
+	ops, err := diffSponsors(before, after)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var paths []string
+	for _, op := range ops {
+		paths = append(paths, op.Op+" "+op.Path)
+	}
+	want := []string{"add /sponsors/0/href", "add /sponsors/0/instagram", "remove /sponsors/0/logoPng", "add /sponsors/1/logo"}
+	if !reflect.DeepEqual(paths, want) {
+		t.Fatalf("ops = %q, want %q", paths, want)
+	}
+
+	// Reject the wrong website, accept the handle, edit the logo path after
+	// an invalid answer, and stop before the last change.
+	var out bytes.Buffer
+	accepted := reviewOps(strings.NewReader("r\na\nx\ne\n/images/logos/acme.jpg\n"), &out, []patchOp{ops[0], ops[1], ops[2]})
+	if len(accepted) != 2 || string(accepted[1].Value) != `"/images/logos/acme.jpg"` || accepted[1].Op != "add" {
+		t.Fatalf("accepted %+v\n%s", accepted, out.String())
+	}
+
+	// Round-trip through a patch file, as -patch and -apply do.
+	b, _ := json.Marshal(append(accepted, ops[3]))
+	var fromFile []patchOp
+	if err := json.Unmarshal(b, &fromFile); err != nil {
+		t.Fatal(err)
+	}
+	site := append([]Sponsor(nil), before...)
+	site[1].Logo = "/images/logos/bee.svg" // changed since the patch was made
+	applied, conflicts, err := applyPatch(site, fromFile, nil)
+	if err != nil || applied != 2 || len(conflicts) != 1 {
+		t.Fatalf("applied %d, conflicts %q, err %v", applied, conflicts, err)
+	}
+	if site[0].Href != "" || site[0].Instagram != "acmetacos" || site[0].LogoPng != "/images/logos/acme.jpg" || site[1].Logo != "/images/logos/bee.svg" {
+		t.Errorf("sponsors after apply: %+v", site)
+	}
+}
+
+// Under -review, downloads and derived files wait outside public/ and only
+// an applied operation moves its files in.
+func TestReviewStagesFiles(t *testing.T) {
+	srv := fakeSite(t, map[string]route{
+		"/":         htmlPage(`<header><img class="logo" src="/logo.png"></header>`),
+		"/logo.png": {ct: "image/png", body: pngBytes(t, 300, 150)},
+	})
+	dir := t.TempDir()
+	public := filepath.Join(dir, "public")
+	logos := filepath.Join(public, "images", "logos")
+	originals := filepath.Join(dir, "logos", originalsDirName)
+	os.MkdirAll(logos, 0o755)
+	os.WriteFile(filepath.Join(logos, "old-co.png"), pngBytes(t, 400, 100), 0o644)
+	sitePath := filepath.Join(dir, "site.json")
+	os.WriteFile(sitePath, []byte(`{"sponsors": [
+  {"name": "Old Co", "logo": "/images/logos/old-co.png", "active": true},
+  {"name": "Acme Tacos", "href": "`+srv.URL+`/", "active": true}
+]}`), 0o644)
+
+	tree := func() map[string]string {
+		files := make(map[string]string)
+		for _, root := range []string{public, originals} {
+			filepath.WalkDir(root, func(p string, d fs.DirEntry, err error) error {
+				if err == nil && !d.IsDir() {
+					b, _ := os.ReadFile(p)
+					files[p] = string(b)
+				}
+				return nil
+			})
+		}
+		return files
+	}
+	before := tree()
+
+	g := &globalOptions{sitePath: sitePath, publicDir: public}
+	opts := &runOptions{
+		publicDir: public,
+		logoDir:   logos,
+		gate:      imageGate{MinSide: 64, MaxAspect: 6},
+		normalize: normalizeConfig{Enable: true, MaxWidth: 200, Format: "png", Originals: originals},
+		client:    testClient(),
+		ua:        "test",
+		econf:     EnrichConfig{Policy: enrichNever},
+	}
+	patchPath := filepath.Join(dir, "changes.json")
+	runSponsors(g, opts, &sponsorFilter{}, &reviewFlags{review: true, patch: patchPath}, 1, "saved", "ok", "failed")
+
+	if !reflect.DeepEqual(tree(), before) {
+		t.Fatal("a -review run changed public/ or the originals")
+	}
+	var ops []patchOp
+	b, _ := os.ReadFile(patchPath)
+	if err := json.Unmarshal(b, &ops); err != nil || len(ops) != 1 || ops[0].Path != "/sponsors/1/logo" {
+		t.Fatalf("ops = %s (%v)", b, err)
+	}
+	logo, original := filepath.Join(logos, "acme-tacos.png"), filepath.Join(originals, "acme-tacos.png")
+	if len(ops[0].Files) != 2 || !fileExists(ops[0].Preview) || ops[0].Files[ops[0].Preview] != logo {
+		t.Fatalf("staged files = %v, preview %s", ops[0].Files, ops[0].Preview)
+	}
+
+	// Rejecting the change leaves public/ as it was.
+	sponsors, _, _ := readSite(sitePath)
+	if applied, _, err := applyPatch(sponsors, nil, nil); err != nil || applied != 0 || !reflect.DeepEqual(tree(), before) {
+		t.Fatalf("rejected patch: applied %d, %v; public/ changed: %v", applied, err, !reflect.DeepEqual(tree(), before))
+	}
+
+	// Accepting it moves the logo and its original in.
+	if applied, conflicts, err := applyPatch(sponsors, ops, nil); err != nil || applied != 1 || len(conflicts) != 0 {
+		t.Fatalf("applied %d, conflicts %q, err %v", applied, conflicts, err)
+	}
+	if !fileExists(logo) || !fileExists(original) || fileExists(ops[0].Preview) || sponsors[1].Logo != "/images/logos/acme-tacos.png" {
+		t.Errorf("after apply: logo %v, original %v, sponsor %+v", fileExists(logo), fileExists(original), sponsors[1])
+	}
+	if after := tree(); after[filepath.Join(logos, "old-co.png")] != before[filepath.Join(logos, "old-co.png")] {
+		t.Error("Old Co's logo was re-encoded without an approved change")
+	}
+
+	// A second apply finds the staged files gone.
+	sponsors, _, _ = readSite(sitePath)
+	if _, conflicts, _ := applyPatch(sponsors, ops, nil); len(conflicts) != 1 || !strings.Contains(conflicts[0], "no longer exists") {
+		t.Errorf("conflicts = %q", conflicts)
+	}
+}
+
+func TestApplyPatchRollsBack(t *testing.T) {
+	dir := t.TempDir()
+	stage, logos := filepath.Join(dir, "stage"), filepath.Join(dir, "public", "images", "logos")
+	os.MkdirAll(stage, 0o755)
+	os.MkdirAll(logos, 0o755)
+	staged, logo := filepath.Join(stage, "acme.png"), filepath.Join(logos, "acme.png")
+	os.WriteFile(staged, []byte("new"), 0o644)
+	os.WriteFile(logo, []byte("old"), 0o644)
+	good := patchOp{
+		Op: "replace", Path: "/sponsors/0/logo", Sponsor: "Acme",
+		Value: json.RawMessage(`"/images/logos/acme.png"`), Old: json.RawMessage(`"/images/logos/acme.jpg"`),
+		Files: map[string]string{staged: logo},
+	}
+	fresh := func() []Sponsor {
+		return []Sponsor{{Name: "Acme", Logo: "/images/logos/acme.jpg"}, {Name: "Beta"}}
+	}
+	unchanged := func(t *testing.T, sponsors []Sponsor) {
+		t.Helper()
+		if !reflect.DeepEqual(sponsors, fresh()) {
+			t.Errorf("sponsors changed: %+v", sponsors)
+		}
+		b, _ := os.ReadFile(logo)
+		if !fileExists(staged) || string(b) != "old" {
+			t.Errorf("staged file kept: %v, live logo %q", fileExists(staged), b)
+		}
+		if entries, _ := os.ReadDir(logos); len(entries) != 1 {
+			t.Errorf("leftovers in logos: %v", entries)
+		}
+	}
+
+	for _, bad := range []patchOp{
+		{Op: "move", Path: "/sponsors/1/href", Sponsor: "Beta"},
+		{Op: "add", Path: "/sponsors/x/href", Sponsor: "Beta"},
+	} {
+		sponsors := fresh()
+		if _, _, err := applyPatch(sponsors, []patchOp{good, bad}, nil); err == nil {
+			t.Errorf("%s %s: no error", bad.Op, bad.Path)
+		}
+		unchanged(t, sponsors)
+	}
+
+	// A failed save moves the logo back to the stage and restores the old one.
+	sponsors := fresh()
+	if _, _, err := applyPatch(sponsors, []patchOp{good}, func() error { return errors.New("disk full") }); err == nil {
+		t.Error("save error not returned")
+	}
+	unchanged(t, sponsors)
+
+	saved := false
+	if applied, _, err := applyPatch(sponsors, []patchOp{good}, func() error { saved = true; return nil }); err != nil || applied != 1 || !saved {
+		t.Fatalf("applied %d, saved %v, err %v", applied, saved, err)
+	}
+	b, _ := os.ReadFile(logo)
+	if entries, _ := os.ReadDir(logos); string(b) != "new" || fileExists(staged) || len(entries) != 1 || sponsors[0].Logo != "/images/logos/acme.png" {
+		t.Errorf("after apply: logo %q, staged %v, entries %v, sponsor %+v", b, fileExists(staged), entries, sponsors[0])
+	}
+}
+
+func TestValidateContent(t *testing.T) {
+	public := t.TempDir()
+	os.MkdirAll(filepath.Join(public, "images", "logos"), 0o755)
+	os.WriteFile(filepath.Join(public, "images", "logos", "acme.png"), pngBytes(t, 64, 64), 0o644)
+	doc := []byte(`{
+  "year": 2025,
+  "eventDate": "2025-12-07T11:00:00-08:00",
+  "location": "North Park",
+  "ticketTailorUrl": "https://buytickets.at/x",
+  "contactEmail": "info@",
+  "social": {},
+  "docs": {"sponsorPacket": "/docs/a.pdf", "sponsorProspectusPdf": "docs/b.pdf"},
+  "links": {
+    "vendorApplicationForm": "https://forms.example/v",
+    "foodTruckApplicationForm": "https://forms.example/f",
+    "sponsorPacket": "/docs/a.pdf",
+    "sponsorApplicationForm": "https://forms.example/s"
+  },
+  "sponsors": [
+    {"name": "Acme", "href": "", "instagram": "acme", "logo": "/images/logos/acme.png", "active": true, "category": ["sponsor"], "type": "community"},
+    {
+      "name": "Bee Co",
+      "href": "bee.example",
+      "instagram": "https://instagram.com/beeco",
+      "logo": "/images/logos/bee.png",
+      "active": "yes",
+      "category": ["kids", "chili", "chili"],
+      "type": "best friend",
+      "featurd": true
+    }
+  ],
+  "bands": [{"href": "https://band.example/"}]
+}`)
+	problems, err := validateContent(doc, siteRule, public)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var got []string
+	for _, p := range problems {
+		got = append(got, p.String())
+	}
+	want := []string{
+		`6: contactEmail: "info@" is not an email address`,
+		`8: docs.sponsorProspectusPdf: "docs/b.pdf" is neither an http(s) URL nor a /path on the site`,
+		`9: links: missing required field "donate"`,
+		`19: sponsors[1] "Bee Co".href: "bee.example" is not an http(s) URL`,
+		`20: sponsors[1] "Bee Co".instagram: "https://instagram.com/beeco" is not an Instagram handle (no @ or URL)`,
+		`21: sponsors[1] "Bee Co".logo: ` + rel(filepath.Join(public, "images", "logos", "bee.png")) + ` does not exist`,
+		`22: sponsors[1] "Bee Co".active: want true or false, got "yes"`,
+		`23: sponsors[1] "Bee Co".category[0]: "kids" is not one of sponsor, partner, chili, music, booze, foodtruck, merch, vendor`,
+		`23: sponsors[1] "Bee Co".category[2]: duplicate "chili"`,
+		`24: sponsors[1] "Bee Co".type: "best friend" is not one of community, best-friend, neighbor, benefactor`,
+		`25: sponsors[1] "Bee Co": unknown field "featurd"`,
+		`28: bands[0]: missing required field "name"`,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("problems:\n%s\nwant:\n%s", strings.Join(got, "\n"), strings.Join(want, "\n"))
+	}
+}
+
+func TestLinkcheck(t *testing.T) {
+	srv := fakeSite(t, map[string]route{
+		"/":         htmlPage("<title>Home</title>"),
+		"/ok":       htmlPage("<title>Tickets</title>"),
+		"/moved":    {status: http.StatusMovedPermanently, location: "/new"},
+		"/new":      htmlPage("<title>New</title>"),
+		"/slash":    {status: http.StatusMovedPermanently, location: "/slash/"},
+		"/slash/":   htmlPage("<title>Slash</title>"),
+		"/form":     htmlPage("<title>Vendors</title><p>This form is no longer accepting responses</p>"),
+		"/missing":  htmlPage("<title>404 | Example</title>"),
+		"/old-page": {status: http.StatusFound, location: "/"},
+		"/ig":       {status: http.StatusForbidden},
+	})
+	dir := t.TempDir()
+	public := filepath.Join(dir, "public")
+	os.MkdirAll(filepath.Join(public, "docs"), 0o755)
+	os.MkdirAll(filepath.Join(public, "images", "logos"), 0o755)
+	os.WriteFile(filepath.Join(public, "docs", "a.pdf"), []byte("%PDF"), 0o644)
+	os.WriteFile(filepath.Join(public, "images", "logos", "bee.svg"), []byte("<svg/>"), 0o644)
+
+	u := srv.URL
+	site := `{
+  "year": 2025,
+  "ticketTailorUrl": "` + u + `/ok",
+  "social": {"instagram": "` + u + `/ig"},
+  "docs": {"sponsorPacket": "/docs/a.pdf", "sponsorProspectusPdf": "` + u + `/moved"},
+  "links": {
+    "vendorApplicationForm": "` + u + `/form",
+    "foodTruckApplicationForm": "` + u + `/gone",
+    "sponsorPacket": "/docs/a.pdf",
+    "donate": "/sponsor",
+    "sponsorApplicationForm": "` + u + `/slash"
+  },
+  "sponsors": [
+    {"name": "Acme", "href": "` + u + `/old-page", "logo": "/images/logos/bee.png", "active": true},
+    {"name": "Bolt", "href": "` + u + `/missing", "active": true}
+  ]
+}`
+	posts := `[{"href": "` + u + `/ok", "image": "/images/ig/1.jpg"}]`
+	os.WriteFile(filepath.Join(dir, "site.json"), []byte(site), 0o644)
+	os.WriteFile(filepath.Join(dir, "instagram.json"), []byte(posts), 0o644)
+
+	links, err := collectLinks(filepath.Join(dir, "site.json"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	checkLinks(testClient(), links, public, 4)
+
+	got := make(map[string]linkGroup)
+	for _, r := range links {
+		got[strings.TrimPrefix(r.URL, u)] = r.Group
+	}
+	want := map[string]linkGroup{
+		"/ok":                   linkOK,
+		"/ig":                   linkBlocked,
+		"/docs/a.pdf":           linkOK,
+		"/moved":                linkRedirect,
+		"/form":                 linkSoft404,
+		"/gone":                 linkDead,
+		"/sponsor":              linkSkipped,
+		"/slash":                linkOK,
+		"/old-page":             linkSoft404,
+		"/images/logos/bee.png": linkDead,
+		"/missing":              linkSoft404,
+		"/images/ig/1.jpg":      linkDead,
+	}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("groups = %v\nwant %v", got, want)
+	}
+	if links[0].URL != u+"/ok" || !reflect.DeepEqual(links[0].Where, []string{"site.json ticketTailorUrl", "instagram.json [0].href"}) {
+		t.Errorf("first link = %+v", links[0])
+	}
+
+	var out bytes.Buffer
+	if !reportLinks(&out, links) {
+		t.Error("dead links not reported as failure")
+	}
+	report := out.String()
+	for _, line := range []string{
+		"💀 Dead links: 3",
+		"      status 404\n      in site.json links.foodTruckApplicationForm\n",
+		"(found " + rel(filepath.Join(public, "images", "logos", "bee.svg")) + ")\n      in site.json sponsors[0] \"Acme\".logo\n",
+		"      → " + u + "/new (301)\n",
+		"      redirects to the home page\n",
+		"      page says \"no longer accepting responses\"\n",
+		"      title \"404 | example\"\n",
+		"Summary: 3 ok, 3 dead, 3 soft 404, 1 to update, 1 blocked, 1 site routes skipped\n",
+	} {
+		if !strings.Contains(report, line) {
+			t.Errorf("report is missing %q:\n%s", line, report)
+		}
+	}
+}
+
+func TestLinkcheckSkipsCache(t *testing.T) {
+	routes := map[string]route{"/page": htmlPage("<title>Tickets</title>")}
+	srv := fakeSite(t, routes)
+	u := srv.URL + "/page"
+	hf := &httpFlags{cacheDir: t.TempDir()}
+
+	// Fetch the page once so the cache holds its 200, then take it down.
+	cached, _ := hf.client()
+	check := func(client *http.Client) *linkResult {
+		links := []*linkResult{{URL: u}}
+		checkLinks(client, links, t.TempDir(), 1)
+		return links[0]
+	}
+	if r := check(cached); r.Group != linkOK {
+		t.Fatalf("first check = %v (%d)", r.Group, r.Status)
+	}
+	routes["/page"] = route{status: http.StatusNotFound}
+	if r := check(cached); r.Group != linkOK {
+		t.Fatalf("cached client saw the 404; the test no longer exercises the cache")
+	}
+
+	if r := check(hf.liveClient()); r.Group != linkDead || r.Status != http.StatusNotFound {
+		t.Errorf("live check = %v (%d), want dead 404", r.Group, r.Status)
+	}
+}
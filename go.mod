@@ -0,0 +1,3 @@
+module sonofest
+
+go 1.22
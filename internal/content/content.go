@@ -0,0 +1,401 @@
+// Package content is the Go side of the site's content files: typed models
+// mirroring app/lib/types.ts, and load/save functions for the JSON files in
+// app/content that keep everything a model doesn't know.
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// The content files, relative to the repository root.
+const (
+	SiteFile      = "app/content/site.json"
+	TicketsFile   = "app/content/tickets.json"
+	FAQFile       = "app/content/faq.json"
+	InstagramFile = "app/content/instagram.json"
+)
+
+// The enums of app/lib/types.ts.
+var (
+	SponsorTypes      = []string{"community", "best-friend", "neighbor", "benefactor"}
+	SponsorCategories = []string{"sponsor", "partner", "chili", "music", "booze", "foodtruck", "merch", "vendor"}
+	TicketChannels    = []string{"in-person", "online", "booth", "ceramic-connection"}
+	ButtonVariants    = []string{"primary", "secondary", "light"}
+)
+
+// Extra holds the members of an object that its model has no field for, so
+// they survive a load/save round trip. They are written after the known
+// fields, sorted by key.
+type Extra map[string]json.RawMessage
+
+// Optional fields are omitempty, so a save never adds empty values the file
+// didn't have. Active is the exception: an explicit false is kept, and the
+// field is tagged optional instead.
+//
+// The content tag says what a string holds, for validation and the JSON
+// Schemas (see Fields): url (absolute http(s) URL), link (URL or a /path on
+// the site), email, date (RFC 3339), handle (Instagram handle, without @ or
+// URL), asset (/path of a file under public/), or enum=<list> naming one of
+// the lists above.
+
+type CtaButton struct {
+	Label   string `json:"label"`
+	Href    string `json:"href" content:"link"`
+	Variant string `json:"variant,omitempty" content:"enum=ButtonVariants"`
+	Extra   Extra  `json:"-"`
+}
+
+type Sponsor struct {
+	Name      string   `json:"name"`
+	Href      string   `json:"href,omitempty" content:"url"`
+	Instagram string   `json:"instagram,omitempty" content:"handle"`
+	Logo      string   `json:"logo,omitempty" content:"asset"`
+	LogoPng   string   `json:"logoPng,omitempty" content:"asset"` // PNG rendering of an SVG logo, for social cards and email
+	Active    bool     `json:"active" content:"optional"`
+	Category  []string `json:"category,omitempty" content:"enum=SponsorCategories"`
+	Type      string   `json:"type,omitempty" content:"enum=SponsorTypes"`
+	Featured  bool     `json:"featured,omitempty"`
+	Class     string   `json:"class,omitempty"`
+	Extra     Extra    `json:"-"`
+}
+
+type InstagramPost struct {
+	ID    string `json:"id"`
+	Href  string `json:"href" content:"url"`
+	Image string `json:"image" content:"link"`
+	Alt   string `json:"alt"`
+	Extra Extra  `json:"-"`
+}
+
+type Vendor struct {
+	Name        string `json:"name"`
+	Href        string `json:"href,omitempty" content:"url"`
+	Logo        string `json:"logo,omitempty" content:"asset"`
+	Active      bool   `json:"active" content:"optional"`
+	Contact     string `json:"contact,omitempty"`
+	Email       string `json:"email,omitempty" content:"email"`
+	Phone       string `json:"phone,omitempty"`
+	Description string `json:"description,omitempty"`
+	Instagram   string `json:"instagram,omitempty" content:"handle"`
+	Facebook    string `json:"facebook,omitempty" content:"url"`
+	Twitter     string `json:"twitter,omitempty" content:"url"`
+	Notes       string `json:"notes,omitempty"`
+	Extra       Extra  `json:"-"`
+}
+
+type Band struct {
+	Name   string `json:"name"`
+	Href   string `json:"href,omitempty" content:"url"`
+	Logo   string `json:"logo,omitempty" content:"asset"`
+	Active bool   `json:"active" content:"optional"`
+	Extra  Extra  `json:"-"`
+}
+
+// SiteContent is site.json.
+type SiteContent struct {
+	Year            int        `json:"year"`
+	EventDate       string     `json:"eventDate" content:"date"`
+	Location        string     `json:"location"`
+	TicketTailorURL string     `json:"ticketTailorUrl" content:"url"`
+	ContactEmail    string     `json:"contactEmail,omitempty" content:"email"`
+	Organizer       *Organizer `json:"organizer,omitempty"`
+	Social          Social     `json:"social"`
+	Docs            Docs       `json:"docs"`
+	Links           Links      `json:"links"`
+	Sponsors        []Sponsor  `json:"sponsors,omitempty"`
+	Vendors         []Sponsor  `json:"vendors,omitempty"`
+	Bands           []Band     `json:"bands,omitempty"`
+	Extra           Extra      `json:"-"`
+}
+
+type Organizer struct {
+	Name  string `json:"name"`
+	URL   string `json:"url,omitempty" content:"url"`
+	Extra Extra  `json:"-"`
+}
+
+type Social struct {
+	Facebook  string `json:"facebook,omitempty" content:"url"`
+	Instagram string `json:"instagram,omitempty" content:"url"`
+	Extra     Extra  `json:"-"`
+}
+
+type Docs struct {
+	SponsorPacket        string `json:"sponsorPacket" content:"link"`
+	SponsorProspectusPdf string `json:"sponsorProspectusPdf" content:"link"`
+	Extra                Extra  `json:"-"`
+}
+
+type Links struct {
+	ChiliEntryForm           string `json:"chiliEntryForm,omitempty" content:"link"`
+	VolunteerSignup          string `json:"volunteerSignup,omitempty" content:"link"`
+	MerchShop                string `json:"merchShop,omitempty" content:"link"`
+	VendorApplicationForm    string `json:"vendorApplicationForm" content:"link"`
+	FoodTruckApplicationForm string `json:"foodTruckApplicationForm" content:"link"`
+	SponsorPacket            string `json:"sponsorPacket" content:"link"`
+	Donate                   string `json:"donate" content:"link"`
+	SponsorApplicationForm   string `json:"sponsorApplicationForm" content:"link"`
+	Extra                    Extra  `json:"-"`
+}
+
+type TicketOption struct {
+	Name     string  `json:"name"`
+	Price    float64 `json:"price"`
+	Tastings int     `json:"tastings"`
+	Channel  string  `json:"channel" content:"enum=TicketChannels"`
+	Extra    Extra   `json:"-"`
+}
+
+type PickupStop struct {
+	When        string `json:"when"`
+	Where       string `json:"where"`
+	Description string `json:"description,omitempty"`
+	Extra       Extra  `json:"-"`
+}
+
+// TicketsContent is tickets.json.
+type TicketsContent struct {
+	Options []TicketOption `json:"options"`
+	Pickup  []PickupStop   `json:"pickup"`
+	Extra   Extra          `json:"-"`
+}
+
+// FaqItem is one entry of faq.json.
+type FaqItem struct {
+	ID    string `json:"id,omitempty"`
+	Q     string `json:"q"`
+	A     string `json:"a"`
+	Extra Extra  `json:"-"`
+}
+
+// Each model decodes and encodes through a local "plain" type, which has
+// the same fields but not these methods.
+
+func (v *CtaButton) UnmarshalJSON(b []byte) error {
+	type plain CtaButton
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v CtaButton) MarshalJSON() ([]byte, error) {
+	type plain CtaButton
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Sponsor) UnmarshalJSON(b []byte) error {
+	type plain Sponsor
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Sponsor) MarshalJSON() ([]byte, error) {
+	type plain Sponsor
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *InstagramPost) UnmarshalJSON(b []byte) error {
+	type plain InstagramPost
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v InstagramPost) MarshalJSON() ([]byte, error) {
+	type plain InstagramPost
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Vendor) UnmarshalJSON(b []byte) error {
+	type plain Vendor
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Vendor) MarshalJSON() ([]byte, error) {
+	type plain Vendor
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Band) UnmarshalJSON(b []byte) error {
+	type plain Band
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Band) MarshalJSON() ([]byte, error) {
+	type plain Band
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *SiteContent) UnmarshalJSON(b []byte) error {
+	type plain SiteContent
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v SiteContent) MarshalJSON() ([]byte, error) {
+	type plain SiteContent
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Organizer) UnmarshalJSON(b []byte) error {
+	type plain Organizer
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Organizer) MarshalJSON() ([]byte, error) {
+	type plain Organizer
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Social) UnmarshalJSON(b []byte) error {
+	type plain Social
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Social) MarshalJSON() ([]byte, error) {
+	type plain Social
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Docs) UnmarshalJSON(b []byte) error {
+	type plain Docs
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Docs) MarshalJSON() ([]byte, error) {
+	type plain Docs
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *Links) UnmarshalJSON(b []byte) error {
+	type plain Links
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v Links) MarshalJSON() ([]byte, error) {
+	type plain Links
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *TicketOption) UnmarshalJSON(b []byte) error {
+	type plain TicketOption
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v TicketOption) MarshalJSON() ([]byte, error) {
+	type plain TicketOption
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *PickupStop) UnmarshalJSON(b []byte) error {
+	type plain PickupStop
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v PickupStop) MarshalJSON() ([]byte, error) {
+	type plain PickupStop
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *TicketsContent) UnmarshalJSON(b []byte) error {
+	type plain TicketsContent
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v TicketsContent) MarshalJSON() ([]byte, error) {
+	type plain TicketsContent
+	return encodeObject(plain(v), v.Extra)
+}
+
+func (v *FaqItem) UnmarshalJSON(b []byte) error {
+	type plain FaqItem
+	return decodeObject(b, (*plain)(v), &v.Extra)
+}
+
+func (v FaqItem) MarshalJSON() ([]byte, error) {
+	type plain FaqItem
+	return encodeObject(plain(v), v.Extra)
+}
+
+// JSONKey returns the JSON member name of struct field f, or "" if the
+// field isn't encoded.
+func JSONKey(f reflect.StructField) string {
+	name, _, _ := strings.Cut(f.Tag.Get("json"), ",")
+	if name == "-" || !f.IsExported() {
+		return ""
+	}
+	if name == "" {
+		name = f.Name
+	}
+	return name
+}
+
+// decodeObject decodes the object b into v, a pointer to a struct, and sets
+// *extra to the members none of its fields took.
+func decodeObject(b []byte, v interface{}, extra *Extra) error {
+	if err := json.Unmarshal(b, v); err != nil {
+		return err
+	}
+	var all map[string]json.RawMessage
+	if err := json.Unmarshal(b, &all); err != nil {
+		return err
+	}
+	// encoding/json matches keys case-insensitively, so do the same here.
+	t := reflect.TypeOf(v).Elem()
+	known := make(map[string]bool, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		if k := JSONKey(t.Field(i)); k != "" {
+			known[strings.ToLower(k)] = true
+		}
+	}
+	*extra = nil
+	for k, raw := range all {
+		if known[strings.ToLower(k)] {
+			continue
+		}
+		if *extra == nil {
+			*extra = make(Extra)
+		}
+		(*extra)[k] = raw
+	}
+	return nil
+}
+
+// encodeObject encodes v, a struct, followed by the members of extra.
+func encodeObject(v interface{}, extra Extra) ([]byte, error) {
+	b, err := encode(v)
+	if err != nil || len(extra) == 0 {
+		return b, err
+	}
+	keys := make([]string, 0, len(extra))
+	for k := range extra {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	buf := bytes.NewBuffer(b[:len(b)-1])
+	for _, k := range keys {
+		if buf.Len() > 1 {
+			buf.WriteByte(',')
+		}
+		kb, err := encode(k)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(kb)
+		buf.WriteByte(':')
+		if err := json.Compact(buf, extra[k]); err != nil {
+			return nil, err
+		}
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// encode is json.Marshal without HTML escaping, so "&" stays "&".
+func encode(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimRight(buf.Bytes(), "\n"), nil
+}
@@ -0,0 +1,331 @@
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+// The real content files load, and saving them unchanged changes nothing.
+func TestLoadSaveRoundTrip(t *testing.T) {
+	root := filepath.Join("..", "..")
+	site, raw, err := LoadSite(filepath.Join(root, SiteFile))
+	if err != nil || len(site.Sponsors) == 0 {
+		t.Fatalf("LoadSite: %d sponsors, %v", len(site.Sponsors), err)
+	}
+	files := map[string]func() (interface{}, []byte, error){
+		SiteFile: func() (interface{}, []byte, error) { return site, raw, nil },
+		TicketsFile: func() (interface{}, []byte, error) {
+			return LoadTickets(filepath.Join(root, TicketsFile))
+		},
+		FAQFile: func() (interface{}, []byte, error) {
+			return LoadFAQ(filepath.Join(root, FAQFile))
+		},
+		InstagramFile: func() (interface{}, []byte, error) {
+			return LoadInstagram(filepath.Join(root, InstagramFile))
+		},
+	}
+	for name, load := range files {
+		v, raw, err := load()
+		if err != nil {
+			t.Errorf("%s: %v", name, err)
+			continue
+		}
+		if out, err := Patch(raw, v, ""); err != nil || !bytes.Equal(out, raw) {
+			t.Errorf("%s: unchanged save rewrote the file (%v)", name, err)
+		}
+	}
+
+	// Saving goes through a backup.
+	path := filepath.Join(t.TempDir(), "site.json")
+	if err := os.WriteFile(path, raw, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	site.Sponsors[0].Name += " Co"
+	if err := Save(path, raw, site, ""); err != nil {
+		t.Fatal(err)
+	}
+	backups, _ := filepath.Glob(path + ".bak-*")
+	if len(backups) != 1 {
+		t.Fatalf("%d backups, want 1", len(backups))
+	}
+	if got, _, _ := LoadSite(path); got.Sponsors[0].Name != site.Sponsors[0].Name {
+		t.Errorf("saved name = %q", got.Sponsors[0].Name)
+	}
+	// A patch that would not decode back to the model is refused, and
+	// neither the backup nor the file is written. encoding/json matches
+	// keys case-insensitively with the last one winning, so editing "a"
+	// here leaves the model's value shadowed by "A".
+	type shadowed struct {
+		A string `json:"a"`
+	}
+	path = filepath.Join(t.TempDir(), "shadowed.json")
+	doc := []byte(`{"a": "x", "A": "y"}`)
+	if err := os.WriteFile(path, doc, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if err := Save(path, doc, &shadowed{A: "z"}, ""); err == nil {
+		t.Error("Save wrote a file that does not decode back to the model")
+	}
+	if got, _ := os.ReadFile(path); !bytes.Equal(got, doc) {
+		t.Errorf("file rewritten to %s", got)
+	}
+	if backups, _ := filepath.Glob(path + ".bak-*"); len(backups) != 0 {
+		t.Errorf("%d backups written", len(backups))
+	}
+}
+
+func TestUnknownFields(t *testing.T) {
+	doc := `{"year": 2025, "theme": {"color": "red"}, "links": {"donate": "/give", "raffle": "/raffle"},
+"sponsors": [{"name": "Acme", "tier": 2, "active": true}]}`
+	var site SiteContent
+	if err := json.Unmarshal([]byte(doc), &site); err != nil {
+		t.Fatal(err)
+	}
+	if string(site.Extra["theme"]) != `{"color": "red"}` || string(site.Links.Extra["raffle"]) != `"/raffle"` ||
+		string(site.Sponsors[0].Extra["tier"]) != "2" || site.Links.Donate != "/give" {
+		t.Fatalf("decoded %+v", site)
+	}
+	b, err := json.Marshal(site)
+	if err != nil {
+		t.Fatal(err)
+	}
+	var back map[string]interface{}
+	json.Unmarshal(b, &back)
+	if back["theme"].(map[string]interface{})["color"] != "red" || back["sponsors"].([]interface{})[0].(map[string]interface{})["tier"] != 2.0 {
+		t.Errorf("encoded %s", b)
+	}
+}
+
+// sponsorsDoc leaves out "active" where it's false and mixes layouts, so
+// re-encoding any untouched element would show in the output.
+const sponsorsDoc = `{
+  "sponsors": [
+    {
+      "name": "A",
+      "href": "https://a.example"
+    },
+    { "name": "B", "active": true },
+    {
+      "name": "C"
+    }
+  ]
+}
+`
+
+func TestPatch(t *testing.T) {
+	tests := []struct {
+		name      string
+		doc       string
+		edit      func(s *SiteContent)
+		dropEmpty string
+		want      string
+	}{
+		{
+			name: "replace in place",
+			doc:  "{\n  \"location\": \"32nd \\u0026 Thorn\",\n  \"year\": 2024\n}\n",
+			edit: func(s *SiteContent) { s.Year = 2025 },
+			want: "{\n  \"location\": \"32nd \\u0026 Thorn\",\n  \"year\": 2025\n}\n",
+		},
+		{
+			name: "insert after the preceding field",
+			doc:  "{\n    \"year\": 2025,\n    \"location\": \"North Park\"\n}",
+			edit: func(s *SiteContent) { s.EventDate = "2025-12-07T11:00:00-08:00" },
+			want: "{\n    \"year\": 2025,\n    \"eventDate\": \"2025-12-07T11:00:00-08:00\",\n    \"location\": \"North Park\"\n}",
+		},
+		{
+			name: "insert nested object",
+			doc:  "{\n  \"year\": 2025\n}",
+			edit: func(s *SiteContent) { s.Organizer = &Organizer{Name: "PTA"} },
+			want: "{\n  \"year\": 2025,\n  \"organizer\": {\n    \"name\": \"PTA\"\n  }\n}",
+		},
+		{
+			name: "remove emptied optional field",
+			doc:  `{"contactEmail": "a@b.org", "year": 2025}`,
+			edit: func(s *SiteContent) { s.ContactEmail = "" },
+			want: `{"year": 2025}`,
+		},
+		{
+			name: "one-line object stays on one line",
+			doc:  "{\"sponsors\": [\n  {\"name\": \"A\", \"active\": true},\n  {\"name\": \"B\", \"active\": true, \"x\": 1}\n]}",
+			edit: func(s *SiteContent) {
+				s.Sponsors[1].Active = false
+				s.Sponsors[1].Category = []string{"chili"}
+			},
+			want: "{\"sponsors\": [\n  {\"name\": \"A\", \"active\": true},\n  {\"name\": \"B\", \"active\": false, \"category\": [\"chili\"], \"x\": 1}\n]}",
+		},
+		{
+			name: "unchanged array round-trips",
+			doc:  sponsorsDoc,
+			edit: func(s *SiteContent) {},
+			want: sponsorsDoc,
+		},
+		{
+			name: "field changed in one element",
+			doc:  sponsorsDoc,
+			edit: func(s *SiteContent) { s.Sponsors[2].Href = "https://c.example" },
+			want: strings.Replace(sponsorsDoc, `"name": "C"`, `"name": "C",
+      "href": "https://c.example"`, 1),
+		},
+		{
+			name: "element added in the middle",
+			doc:  sponsorsDoc,
+			edit: func(s *SiteContent) {
+				s.Sponsors = append(s.Sponsors[:2], append([]Sponsor{{Name: "B2", Active: true}}, s.Sponsors[2:]...)...)
+			},
+			want: strings.Replace(sponsorsDoc, `{ "name": "B", "active": true },`, `{ "name": "B", "active": true },
+    {
+      "name": "B2",
+      "active": true
+    },`, 1),
+		},
+		{
+			name: "elements added at both ends",
+			doc:  sponsorsDoc,
+			edit: func(s *SiteContent) {
+				s.Sponsors = append([]Sponsor{{Name: "First"}}, append(s.Sponsors, Sponsor{Name: "Last"})...)
+			},
+			want: "{\n  \"sponsors\": [\n    {\n      \"name\": \"First\",\n      \"active\": false\n    },\n    {\n      \"name\": \"A\"," +
+				strings.TrimPrefix(strings.TrimSuffix(sponsorsDoc, "\n  ]\n}\n"), "{\n  \"sponsors\": [\n    {\n      \"name\": \"A\",") +
+				",\n    {\n      \"name\": \"Last\",\n      \"active\": false\n    }\n  ]\n}\n",
+		},
+		{
+			name: "element removed from the middle",
+			doc:  sponsorsDoc,
+			edit: func(s *SiteContent) { s.Sponsors = append(s.Sponsors[:1], s.Sponsors[2:]...) },
+			want: strings.Replace(sponsorsDoc, "\n    { \"name\": \"B\", \"active\": true },", "", 1),
+		},
+		{
+			name: "first two elements removed",
+			doc:  sponsorsDoc,
+			edit: func(s *SiteContent) { s.Sponsors = s.Sponsors[2:] },
+			want: "{\n  \"sponsors\": [\n    {\n      \"name\": \"C\"\n    }\n  ]\n}\n",
+		},
+		{
+			name: "element added to a one-line array",
+			doc:  "{\n  \"bands\": [{\"name\": \"A\", \"active\": true}]\n}",
+			edit: func(s *SiteContent) { s.Bands = append(s.Bands, Band{Name: "B", Href: "https://b.example"}) },
+			want: "{\n  \"bands\": [{\"name\": \"A\", \"active\": true}, {\"name\": \"B\", \"href\": \"https://b.example\", \"active\": false}]\n}",
+		},
+		{
+			name:      "drop empty strings",
+			doc:       `{"social": {"facebook": "", "instagram": "https://instagram.com/x"}, "theme": ""}`,
+			edit:      func(s *SiteContent) {},
+			dropEmpty: "/social",
+			want:      `{"social": {"instagram": "https://instagram.com/x"}, "theme": ""}`,
+		},
+		{
+			name:      "drop every member",
+			doc:       "{\n  \"social\": {\n    \"facebook\": \"\",\n    \"instagram\": \"\"\n  }\n}",
+			edit:      func(s *SiteContent) {},
+			dropEmpty: "/social",
+			want:      "{\n  \"social\": {}\n}",
+		},
+		{
+			name:      "drop the first two members",
+			doc:       "{\"sponsors\": [\n  {\n    \"href\": \"\",\n    \"instagram\": \"\",\n    \"name\": \"A\",\n    \"logo\": \"\"\n  },\n  {\"href\": \"\", \"logo\": \"\", \"name\": \"B\"}\n]}",
+			edit:      func(s *SiteContent) {},
+			dropEmpty: "/sponsors",
+			want:      "{\"sponsors\": [\n  {\n    \"name\": \"A\"\n  },\n  {\"name\": \"B\"}\n]}",
+		},
+		{
+			name:      "drop only inside the pointer",
+			doc:       `{"social": {"facebook": ""}, "sponsors": [{"name": "A", "href": ""}], "contactEmail": ""}`,
+			edit:      func(s *SiteContent) { s.Sponsors[0].Active = true },
+			dropEmpty: "/sponsors",
+			want:      `{"social": {"facebook": ""}, "sponsors": [{"name": "A", "active": true}], "contactEmail": ""}`,
+		},
+		{
+			name: "keep empty strings",
+			doc:  `{"social": {"facebook": "", "instagram": ""}}`,
+			edit: func(s *SiteContent) { s.Social.Instagram = "https://instagram.com/x" },
+			want: `{"social": {"facebook": "", "instagram": "https://instagram.com/x"}}`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var site SiteContent
+			if err := json.Unmarshal([]byte(tt.doc), &site); err != nil {
+				t.Fatal(err)
+			}
+			tt.edit(&site)
+			got, err := Patch([]byte(tt.doc), &site, tt.dropEmpty)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if string(got) != tt.want {
+				t.Errorf("got:\n%s\nwant:\n%s", got, tt.want)
+			}
+			var back SiteContent
+			if err := json.Unmarshal(got, &back); err != nil || !reflect.DeepEqual(back, site) {
+				t.Errorf("patched document decodes to %+v (%v)", back, err)
+			}
+		})
+	}
+}
+
+// The committed schema files are what the models generate.
+func TestSchemas(t *testing.T) {
+	schemas, err := Schemas()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, sf := range SchemaFiles {
+		onDisk, err := os.ReadFile(filepath.Join("..", "..", "app", "content", "schema", sf.Name))
+		if err != nil || !bytes.Equal(onDisk, schemas[sf.Name]) {
+			t.Errorf("%s is stale; run 'go run fetch_logos.go content schema' (%v)", sf.Name, err)
+		}
+	}
+
+	var site struct {
+		Required    []string
+		Definitions map[string]struct {
+			Properties map[string]map[string]interface{}
+			Required   []string
+		}
+	}
+	if err := json.Unmarshal(schemas["site.schema.json"], &site); err != nil {
+		t.Fatal(err)
+	}
+	sponsor := site.Definitions["Sponsor"]
+	if got := sponsor.Properties["type"]["enum"]; !reflect.DeepEqual(got, []interface{}{"community", "best-friend", "neighbor", "benefactor"}) {
+		t.Errorf("Sponsor.type enum = %v", got)
+	}
+	if got := sponsor.Properties["href"]["pattern"]; got != "^$|^https?://[^/\\s]+" {
+		t.Errorf("Sponsor.href pattern = %v", got)
+	}
+	if !reflect.DeepEqual(sponsor.Required, []string{"name"}) || !reflect.DeepEqual(site.Required, []string{"year", "eventDate", "location", "ticketTailorUrl", "social", "docs", "links"}) {
+		t.Errorf("required: sponsor %q, site %q", sponsor.Required, site.Required)
+	}
+}
+
+func TestCheckTypeScript(t *testing.T) {
+	src, err := os.ReadFile(filepath.Join("..", "..", TypesFile))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if problems, err := CheckTypeScript(string(src)); err != nil || len(problems) > 0 {
+		t.Errorf("models disagree with %s (%v):\n%s", TypesFile, err, strings.Join(problems, "\n"))
+	}
+
+	drifted := strings.NewReplacer(
+		"donate: string;", "donate?: string;",
+		"'booth' | ", "",
+		"  notes?: string;", "  notes?: string;\n  tiktok?: string;",
+		"  year: number;", "  year: string;",
+	).Replace(string(src))
+	problems, err := CheckTypeScript(drifted)
+	want := []string{
+		"SiteContent.links.donate: required in Go, not in app/lib/types.ts",
+		`SiteContent.year: string in app/lib/types.ts, a number in Go`,
+		`TicketOption.channel: ["in-person" "online" "ceramic-connection"] in app/lib/types.ts, ["in-person" "online" "booth" "ceramic-connection"] in Go`,
+		"Vendor.tiktok: missing from the Go model",
+	}
+	if err != nil || !reflect.DeepEqual(problems, want) {
+		t.Errorf("problems (%v):\n%s", err, strings.Join(problems, "\n"))
+	}
+}
@@ -0,0 +1,464 @@
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// LoadSite reads site.json. The file's bytes are returned for Save.
+func LoadSite(path string) (*SiteContent, []byte, error) {
+	var site SiteContent
+	raw, err := load(path, &site)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &site, raw, nil
+}
+
+// LoadTickets reads tickets.json.
+func LoadTickets(path string) (*TicketsContent, []byte, error) {
+	var tickets TicketsContent
+	raw, err := load(path, &tickets)
+	if err != nil {
+		return nil, nil, err
+	}
+	return &tickets, raw, nil
+}
+
+// LoadFAQ reads faq.json.
+func LoadFAQ(path string) ([]FaqItem, []byte, error) {
+	var faq []FaqItem
+	raw, err := load(path, &faq)
+	return faq, raw, err
+}
+
+// LoadInstagram reads instagram.json.
+func LoadInstagram(path string) ([]InstagramPost, []byte, error) {
+	var posts []InstagramPost
+	raw, err := load(path, &posts)
+	return posts, raw, err
+}
+
+func load(path string, v interface{}) ([]byte, error) {
+	raw, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(raw, v); err != nil {
+		return nil, fmt.Errorf("%s: %w", path, err)
+	}
+	return raw, nil
+}
+
+// Save backs up path (whose contents are original) and writes v, a model
+// loaded from original, into it with Patch. Nothing is written unless the
+// patched document is valid JSON that decodes back to v.
+func Save(path string, original []byte, v interface{}, dropEmpty string) error {
+	updated, err := Patch(original, v, dropEmpty)
+	if err != nil {
+		return fmt.Errorf("patch %s: %w", path, err)
+	}
+	if !json.Valid(updated) {
+		return fmt.Errorf("patch %s: result is not valid JSON", path)
+	}
+	got, err := decodeAs(updated, v)
+	if err != nil {
+		return fmt.Errorf("patch %s: %w", path, err)
+	}
+	want, err := encode(v)
+	if err != nil {
+		return fmt.Errorf("patch %s: %w", path, err)
+	}
+	if !bytes.Equal(got, want) {
+		return fmt.Errorf("patch %s: result does not decode back to the model", path)
+	}
+	backup := fmt.Sprintf("%s.bak-%d", path, time.Now().Unix())
+	if err := os.WriteFile(backup, original, 0o644); err != nil {
+		return fmt.Errorf("backup write: %w", err)
+	}
+	return os.WriteFile(path, updated, 0o644)
+}
+
+// Patch returns doc with v written into it in place. Only values that
+// differ from what doc decodes to are rewritten: key order, formatting,
+// escapes, and untouched values stay byte-for-byte. A changed value is
+// replaced where it stands; a new member goes after the member that
+// precedes it in the model; a member the model now leaves out is removed.
+// Array elements are matched by name, so adding or removing one inserts or
+// deletes only that element.
+// dropEmpty, a JSON Pointer such as "/sponsors" ("" for none), names the
+// part of doc whose objects also lose members holding "" that the model
+// leaves out (empty optional strings).
+func Patch(doc []byte, v interface{}, dropEmpty string) ([]byte, error) {
+	root, err := Root(doc)
+	if err != nil {
+		return nil, err
+	}
+	oldEnc, err := decodeAs(doc, v)
+	if err != nil {
+		return nil, err
+	}
+	newEnc, err := encode(v)
+	if err != nil {
+		return nil, err
+	}
+	p := &patcher{doc: doc, dropEmpty: dropEmpty}
+	p.value(root, oldEnc, newEnc, lineIndent(doc, root.Start), "  ", "")
+	if p.err != nil {
+		return nil, p.err
+	}
+	return applyEdits(doc, p.edits), nil
+}
+
+// decodeAs decodes doc into a fresh value of v's type and returns its
+// compact encoding.
+func decodeAs(doc []byte, v interface{}) ([]byte, error) {
+	t := reflect.TypeOf(v)
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	d := reflect.New(t).Interface()
+	if err := json.Unmarshal(doc, d); err != nil {
+		return nil, err
+	}
+	return encode(d)
+}
+
+// patcher collects the edits turning doc, whose values encode as old, into
+// new. old and new are compact encodings of the model.
+type patcher struct {
+	doc       []byte
+	dropEmpty string // JSON Pointer; "" = none
+	edits     []edit
+	err       error
+}
+
+// dropsIn reports whether objects at ptr lose their "" members.
+func (p *patcher) dropsIn(ptr string) bool {
+	return p.dropEmpty != "" && (ptr == p.dropEmpty || strings.HasPrefix(ptr, p.dropEmpty+"/"))
+}
+
+// dropsUnder reports whether the value at ptr holds objects that do.
+func (p *patcher) dropsUnder(ptr string) bool {
+	return p.dropsIn(ptr) || p.dropEmpty != "" && strings.HasPrefix(p.dropEmpty, ptr+"/")
+}
+
+// value patches the value at span, found at the JSON Pointer ptr, from old
+// to new. A replacement is indented by prefix plus unit per level (one line
+// when unit is empty). Unchanged values are still walked where dropEmpty
+// applies, for their members.
+func (p *patcher) value(span Span, old, new []byte, prefix, unit, ptr string) {
+	same := bytes.Equal(old, new)
+	if same && !p.dropsUnder(ptr) || p.err != nil {
+		return
+	}
+	switch c := p.doc[span.Start]; {
+	case c == '{' && len(old) > 0 && old[0] == '{' && new[0] == '{':
+		p.object(span, old, new, ptr)
+	case c == '[' && len(old) > 0 && old[0] == '[' && new[0] == '[':
+		p.array(span, old, new, prefix, unit, ptr)
+	case !same:
+		p.edits = append(p.edits, edit{span.Start, span.End, indent(new, prefix, unit)})
+	}
+}
+
+// layout returns how the members or elements of the container at span are
+// laid out: one per line, with their indentation and the indentation unit,
+// or all on one line (unit "").
+func (p *patcher) layout(span Span, first int, empty bool) (prefix, unit, sep string) {
+	if bytes.IndexByte(p.doc[span.Start:span.End], '\n') < 0 {
+		return "", "", ", "
+	}
+	outer := lineIndent(p.doc, span.Start)
+	prefix, unit = outer+"  ", "  "
+	if !empty {
+		prefix = lineIndent(p.doc, first)
+		unit = strings.TrimPrefix(prefix, outer)
+	}
+	return prefix, unit, ",\n" + prefix
+}
+
+func (p *patcher) object(span Span, old, new []byte, ptr string) {
+	members, err := Members(p.doc, span)
+	if err != nil {
+		p.err = err
+		return
+	}
+	oldMembers, err := Members(old, Span{0, len(old)})
+	if err != nil {
+		p.err = err
+		return
+	}
+	newMembers, err := Members(new, Span{0, len(new)})
+	if err != nil {
+		p.err = err
+		return
+	}
+	if len(members) == 0 {
+		if bytes.Equal(old, new) {
+			return
+		}
+		_, unit, _ := p.layout(span, 0, true)
+		p.edits = append(p.edits, edit{span.Start, span.End, indent(new, lineIndent(p.doc, span.Start), unit)})
+		return
+	}
+	at := make(map[string]int, len(members))
+	for i, m := range members {
+		at[m.Key] = i
+	}
+	oldVal := make(map[string][]byte, len(oldMembers))
+	for _, m := range oldMembers {
+		oldVal[m.Key] = old[m.Val.Start:m.Val.End]
+	}
+	prefix, unit, sep := p.layout(span, members[0].KeyStart, false)
+
+	inNew := make(map[string]bool, len(newMembers))
+	anchor := -1 // member a new one is inserted after; -1 = before the first
+	var inserts []edit
+	for _, n := range newMembers {
+		inNew[n.Key] = true
+		nv := new[n.Val.Start:n.Val.End]
+		idx, present := at[n.Key]
+		ov, inOld := oldVal[n.Key]
+		switch {
+		case present:
+			p.value(members[idx].Val, ov, nv, prefix, unit, ptr+"/"+pointerEscape(n.Key))
+			anchor = idx
+		case inOld && bytes.Equal(ov, nv):
+			// A default the file leaves out, e.g. "active": false.
+		default:
+			key := new[n.KeyStart : n.Val.Start-1] // compact: the colon is right before the value
+			kv := string(key) + ": " + string(indent(nv, prefix, unit))
+			if anchor < 0 {
+				inserts = append(inserts, edit{members[0].KeyStart, members[0].KeyStart, []byte(kv + strings.TrimPrefix(sep, " "))})
+			} else {
+				pos := members[anchor].Val.End
+				inserts = append(inserts, edit{pos, pos, []byte(sep + kv)})
+			}
+		}
+	}
+	removed := make([]bool, len(members))
+	left := len(members)
+	for i, m := range members {
+		if inNew[m.Key] {
+			continue
+		}
+		_, inOld := oldVal[m.Key]
+		if inOld || p.dropsIn(ptr) && string(p.doc[m.Val.Start:m.Val.End]) == `""` {
+			removed[i] = true
+			left--
+		}
+	}
+	if left == 0 {
+		// Nothing of the old object stays: write the new one whole.
+		_, unit, _ := p.layout(span, 0, true)
+		p.edits = append(p.edits, edit{span.Start, span.End, indent(new, lineIndent(p.doc, span.Start), unit)})
+		return
+	}
+	p.edits = append(p.edits, inserts...)
+	p.edits = append(p.edits, removeMembers(members, removed)...)
+}
+
+// pointerEscape escapes a key for use in a JSON Pointer (RFC 6901).
+func pointerEscape(key string) string {
+	return strings.NewReplacer("~", "~0", "/", "~1").Replace(key)
+}
+
+// array patches the elements in place. Elements are matched by identity
+// (an object's "name", or the whole value) so that adding or removing one
+// only inserts or deletes that element's span; unmatched elements between
+// matches are patched pairwise, by position.
+func (p *patcher) array(span Span, old, new []byte, prefix, unit, ptr string) {
+	elems, err := Elems(p.doc, span)
+	if err != nil {
+		p.err = err
+		return
+	}
+	oldElems, err := Elems(old, Span{0, len(old)})
+	if err != nil {
+		p.err = err
+		return
+	}
+	newElems, err := Elems(new, Span{0, len(new)})
+	if err != nil {
+		p.err = err
+		return
+	}
+	if len(elems) == 0 && bytes.Equal(old, new) {
+		return
+	}
+	pairs := alignElems(elemKeys(old, oldElems), elemKeys(new, newElems))
+	kept := 0
+	for _, pr := range pairs {
+		if pr[0] >= 0 && pr[1] >= 0 {
+			kept++
+		}
+	}
+	if len(elems) != len(oldElems) || kept == 0 {
+		p.edits = append(p.edits, edit{span.Start, span.End, indent(new, prefix, unit)})
+		return
+	}
+
+	ep, eu, sep := p.layout(span, elems[0].Start, false)
+	inserts := make(map[int][]byte) // doc element → text after it; -1 = before the first
+	var order []int
+	anchor := -1
+	removed := make([]bool, len(elems))
+	for _, pr := range pairs {
+		o, n := pr[0], pr[1]
+		switch {
+		case o >= 0 && n >= 0:
+			p.value(elems[o], old[oldElems[o].Start:oldElems[o].End], new[newElems[n].Start:newElems[n].End], ep, eu, ptr+"/"+strconv.Itoa(o))
+			anchor = o
+		case o >= 0:
+			removed[o] = true
+		default:
+			if _, ok := inserts[anchor]; !ok {
+				order = append(order, anchor)
+			}
+			el := indent(new[newElems[n].Start:newElems[n].End], ep, eu)
+			if anchor < 0 {
+				inserts[anchor] = append(append(inserts[anchor], el...), strings.TrimPrefix(sep, " ")...)
+			} else {
+				inserts[anchor] = append(append(inserts[anchor], sep...), el...)
+			}
+		}
+	}
+	for _, at := range order {
+		pos := elems[0].Start
+		if at >= 0 {
+			pos = elems[at].End
+		}
+		p.edits = append(p.edits, edit{pos, pos, inserts[at]})
+	}
+	// Each run of removed elements is one edit, taking the comma before it
+	// (or, at the front, the one after it).
+	for i := 0; i < len(elems); i++ {
+		if !removed[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(elems) && removed[j+1] {
+			j++
+		}
+		if i > 0 {
+			p.edits = append(p.edits, edit{elems[i-1].End, elems[j].End, nil})
+		} else {
+			p.edits = append(p.edits, edit{elems[0].Start, elems[j+1].Start, nil})
+		}
+		i = j
+	}
+}
+
+// elemKeys returns the identity of each element of the compact array b: an
+// object's "name" member, or else the element's encoding.
+func elemKeys(b []byte, elems []Span) []string {
+	keys := make([]string, len(elems))
+	for i, el := range elems {
+		keys[i] = string(b[el.Start:el.End])
+		if b[el.Start] != '{' {
+			continue
+		}
+		members, err := Members(b, el)
+		if err != nil {
+			continue
+		}
+		for _, m := range members {
+			if m.Key == "name" {
+				keys[i] = "name " + string(b[m.Val.Start:m.Val.End])
+			}
+		}
+	}
+	return keys
+}
+
+// alignElems lines up old and new element keys along their longest common
+// subsequence. Between matches, leftover elements are paired by position;
+// the rest are removals ([i, -1]) or insertions ([-1, j]), in order.
+func alignElems(old, new []string) [][2]int {
+	// lcs[i][j] is the LCS length of old[i:] and new[j:].
+	lcs := make([][]int, len(old)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(new)+1)
+	}
+	for i := len(old) - 1; i >= 0; i-- {
+		for j := len(new) - 1; j >= 0; j-- {
+			if old[i] == new[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else {
+				lcs[i][j] = max(lcs[i+1][j], lcs[i][j+1])
+			}
+		}
+	}
+	var pairs [][2]int
+	var gapOld, gapNew []int
+	flush := func() {
+		for k := 0; k < len(gapOld) || k < len(gapNew); k++ {
+			pr := [2]int{-1, -1}
+			if k < len(gapOld) {
+				pr[0] = gapOld[k]
+			}
+			if k < len(gapNew) {
+				pr[1] = gapNew[k]
+			}
+			pairs = append(pairs, pr)
+		}
+		gapOld, gapNew = gapOld[:0], gapNew[:0]
+	}
+	i, j := 0, 0
+	for i < len(old) || j < len(new) {
+		switch {
+		case i < len(old) && j < len(new) && old[i] == new[j]:
+			flush()
+			pairs = append(pairs, [2]int{i, j})
+			i, j = i+1, j+1
+		case j == len(new) || i < len(old) && lcs[i+1][j] >= lcs[i][j+1]:
+			gapOld = append(gapOld, i)
+			i++
+		default:
+			gapNew = append(gapNew, j)
+			j++
+		}
+	}
+	flush()
+	return pairs
+}
+
+// indent re-indents the compact encoding b by prefix plus unit per level,
+// or, when unit is empty, keeps it on one line with a space after each
+// colon and comma.
+func indent(b []byte, prefix, unit string) []byte {
+	if unit == "" {
+		return oneLine(b)
+	}
+	var buf bytes.Buffer
+	if err := json.Indent(&buf, b, prefix, unit); err != nil {
+		return b
+	}
+	return buf.Bytes()
+}
+
+func oneLine(b []byte) []byte {
+	out := make([]byte, 0, len(b)+len(b)/8)
+	inString := false
+	for i := 0; i < len(b); i++ {
+		c := b[i]
+		out = append(out, c)
+		switch {
+		case inString && c == '\\':
+			i++
+			out = append(out, b[i])
+		case c == '"':
+			inString = !inString
+		case !inString && (c == ':' || c == ','):
+			out = append(out, ' ')
+		}
+	}
+	return out
+}
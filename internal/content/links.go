@@ -0,0 +1,54 @@
+package content
+
+import (
+	"fmt"
+	"reflect"
+)
+
+// Link is a URL or site path held by a url, link, or asset field.
+type Link struct {
+	Path string // where it is, e.g. sponsors[3] "Acme Tacos".href
+	URL  string
+}
+
+// FindLinks lists the non-empty url, link, and asset values in v, a model or a
+// slice of models, in document order.
+func FindLinks(v interface{}) []Link {
+	var links []Link
+	walkLinks(reflect.ValueOf(v), "", Field{}, &links)
+	return links
+}
+
+func walkLinks(v reflect.Value, path string, f Field, links *[]Link) {
+	switch v.Kind() {
+	case reflect.Ptr:
+		if !v.IsNil() {
+			walkLinks(v.Elem(), path, f, links)
+		}
+	case reflect.Struct:
+		for _, sub := range Fields(v.Type()) {
+			p := sub.Key
+			if path != "" {
+				p = path + "." + sub.Key
+			}
+			walkLinks(v.Field(sub.Index), p, sub, links)
+		}
+	case reflect.Slice:
+		for i := 0; i < v.Len(); i++ {
+			label := fmt.Sprintf("%s[%d]", path, i)
+			if el := reflect.Indirect(v.Index(i)); el.Kind() == reflect.Struct {
+				if name := el.FieldByName("Name"); name.IsValid() && name.String() != "" {
+					label += fmt.Sprintf(" %q", name.String())
+				}
+			}
+			walkLinks(v.Index(i), label, f, links)
+		}
+	case reflect.String:
+		switch f.Kind {
+		case "url", "link", "asset":
+			if s := v.String(); s != "" {
+				*links = append(*links, Link{Path: path, URL: s})
+			}
+		}
+	}
+}
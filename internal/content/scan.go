@@ -0,0 +1,201 @@
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Minimal JSON scanning: where values sit in a document, for in-place edits
+// and for reporting problems by line. Documents are assumed valid (they have
+// been through json.Unmarshal); this only finds boundaries.
+
+// Span is the byte range [Start, End) of a value in a document.
+type Span struct{ Start, End int }
+
+// Member is one key/value pair of an object.
+type Member struct {
+	Key      string
+	KeyStart int
+	Val      Span
+}
+
+// edit replaces doc[start:end] with text.
+type edit struct {
+	start, end int
+	text       []byte
+}
+
+// Root returns the span of doc's top-level value.
+func Root(doc []byte) (Span, error) {
+	start := skipSpace(doc, 0)
+	end, err := scanValue(doc, start)
+	if err != nil {
+		return Span{}, err
+	}
+	return Span{start, end}, nil
+}
+
+// Line returns the 1-based line number of doc[i].
+func Line(doc []byte, i int) int {
+	return bytes.Count(doc[:i], []byte("\n")) + 1
+}
+
+func skipSpace(b []byte, i int) int {
+	for i < len(b) && (b[i] == ' ' || b[i] == '\t' || b[i] == '\n' || b[i] == '\r') {
+		i++
+	}
+	return i
+}
+
+// scanValue returns the end of the value starting at b[i].
+func scanValue(b []byte, i int) (int, error) {
+	if i >= len(b) {
+		return 0, errors.New("unexpected end of JSON")
+	}
+	switch b[i] {
+	case '"':
+		for j := i + 1; j < len(b); j++ {
+			switch b[j] {
+			case '\\':
+				j++
+			case '"':
+				return j + 1, nil
+			}
+		}
+		return 0, fmt.Errorf("offset %d: unterminated string", i)
+	case '{', '[':
+		depth := 0
+		for j := i; j < len(b); j++ {
+			switch b[j] {
+			case '"':
+				end, err := scanValue(b, j)
+				if err != nil {
+					return 0, err
+				}
+				j = end - 1
+			case '{', '[':
+				depth++
+			case '}', ']':
+				if depth--; depth == 0 {
+					return j + 1, nil
+				}
+			}
+		}
+		return 0, fmt.Errorf("offset %d: unterminated %c", i, b[i])
+	default:
+		j := i
+		for j < len(b) && !strings.ContainsRune(",:{}[] \t\r\n", rune(b[j])) {
+			j++
+		}
+		if j == i {
+			return 0, fmt.Errorf("offset %d: unexpected %q", i, b[i])
+		}
+		return j, nil
+	}
+}
+
+// Members lists the members of the object at obj, in order.
+func Members(b []byte, obj Span) ([]Member, error) {
+	if b[obj.Start] != '{' {
+		return nil, fmt.Errorf("offset %d: not an object", obj.Start)
+	}
+	var members []Member
+	for i := skipSpace(b, obj.Start+1); i < obj.End-1; {
+		keyEnd, err := scanValue(b, i)
+		if err != nil {
+			return nil, err
+		}
+		var key string
+		if err := json.Unmarshal(b[i:keyEnd], &key); err != nil {
+			return nil, fmt.Errorf("offset %d: bad key: %w", i, err)
+		}
+		colon := skipSpace(b, keyEnd)
+		if colon >= len(b) || b[colon] != ':' {
+			return nil, fmt.Errorf("offset %d: expected ':'", colon)
+		}
+		vs := skipSpace(b, colon+1)
+		ve, err := scanValue(b, vs)
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, Member{Key: key, KeyStart: i, Val: Span{vs, ve}})
+		i = skipSpace(b, ve)
+		if i < len(b) && b[i] == ',' {
+			i = skipSpace(b, i+1)
+		}
+	}
+	return members, nil
+}
+
+// Elems lists the elements of the array at arr, in order.
+func Elems(b []byte, arr Span) ([]Span, error) {
+	if b[arr.Start] != '[' {
+		return nil, fmt.Errorf("offset %d: not an array", arr.Start)
+	}
+	var elems []Span
+	for i := skipSpace(b, arr.Start+1); i < arr.End-1; {
+		end, err := scanValue(b, i)
+		if err != nil {
+			return nil, err
+		}
+		elems = append(elems, Span{i, end})
+		i = skipSpace(b, end)
+		if i < len(b) && b[i] == ',' {
+			i = skipSpace(b, i+1)
+		}
+	}
+	return elems, nil
+}
+
+// removeMembers returns the edits deleting the members marked removed, and
+// one adjoining comma for each: a run of removed members is one edit, so
+// the edits never overlap. At least one member must stay.
+func removeMembers(members []Member, removed []bool) []edit {
+	var edits []edit
+	for i := 0; i < len(members); i++ {
+		if !removed[i] {
+			continue
+		}
+		j := i
+		for j+1 < len(members) && removed[j+1] {
+			j++
+		}
+		if i > 0 {
+			edits = append(edits, edit{members[i-1].Val.End, members[j].Val.End, nil})
+		} else {
+			edits = append(edits, edit{members[0].KeyStart, members[j+1].KeyStart, nil})
+		}
+		i = j
+	}
+	return edits
+}
+
+// lineIndent returns the leading whitespace of the line containing b[i].
+func lineIndent(b []byte, i int) string {
+	start := bytes.LastIndexByte(b[:i], '\n') + 1
+	end := start
+	for end < i && (b[end] == ' ' || b[end] == '\t') {
+		end++
+	}
+	return string(b[start:end])
+}
+
+// applyEdits applies non-overlapping edits to doc. An insertion and a
+// deletion starting at the same offset leave the inserted text in place.
+func applyEdits(doc []byte, edits []edit) []byte {
+	sort.SliceStable(edits, func(i, j int) bool {
+		if edits[i].start != edits[j].start {
+			return edits[i].start > edits[j].start
+		}
+		return edits[i].end > edits[j].end
+	})
+	out := append([]byte(nil), doc...)
+	for _, e := range edits {
+		out = append(out[:e.start], append(append([]byte(nil), e.text...), out[e.end:]...)...)
+	}
+	return out
+}
@@ -0,0 +1,187 @@
+package content
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Field is one JSON field of a model.
+type Field struct {
+	Key      string
+	Index    int // in the struct
+	Type     reflect.Type
+	Kind     string   // from the content tag: url, link, email, ...; "" for none
+	Enum     []string // for strings, or the strings of a slice
+	Required bool     // no omitempty, and not tagged optional
+}
+
+var enums = map[string][]string{
+	"SponsorTypes":      SponsorTypes,
+	"SponsorCategories": SponsorCategories,
+	"TicketChannels":    TicketChannels,
+	"ButtonVariants":    ButtonVariants,
+}
+
+// Patterns of the handle and email string kinds, valid both as Go and as
+// JavaScript regular expressions.
+const (
+	HandlePattern = `^[A-Za-z0-9_.]{1,30}$`
+	EmailPattern  = `^[^@\s]+@[^@\s]+\.[A-Za-z]{2,}$`
+)
+
+// stringKinds describe the content tag kinds in JSON Schema terms.
+var stringKinds = map[string]struct{ pattern, format, desc string }{
+	"url":    {`^https?://[^/\s]+`, "", "Absolute http(s) URL"},
+	"link":   {`^(https?://[^/\s]+|/)`, "", "Absolute http(s) URL or a /path on the site"},
+	"email":  {EmailPattern, "", "Email address"},
+	"date":   {"", "date-time", "RFC 3339 timestamp, e.g. 2025-12-07T11:00:00-08:00"},
+	"handle": {HandlePattern, "", "Instagram handle, without @ or URL"},
+	"asset":  {`^/`, "", "/path of a file under public/"},
+}
+
+// Fields lists the JSON fields of the struct type t, in order.
+func Fields(t reflect.Type) []Field {
+	var fields []Field
+	for i := 0; i < t.NumField(); i++ {
+		sf := t.Field(i)
+		key := JSONKey(sf)
+		if key == "" {
+			continue
+		}
+		_, opts, _ := strings.Cut(sf.Tag.Get("json"), ",")
+		f := Field{Key: key, Index: i, Type: sf.Type, Required: !strings.Contains(opts, "omitempty")}
+		for _, tag := range strings.Split(sf.Tag.Get("content"), ",") {
+			switch {
+			case tag == "":
+			case tag == "optional":
+				f.Required = false
+			case strings.HasPrefix(tag, "enum="):
+				f.Enum = enums[strings.TrimPrefix(tag, "enum=")]
+			default:
+				f.Kind = tag
+			}
+		}
+		fields = append(fields, f)
+	}
+	return fields
+}
+
+// SchemaFiles are the JSON Schema documents Schemas generates, by name, and
+// the content file each describes.
+var SchemaFiles = []struct {
+	Name, File string
+	model      interface{}
+}{
+	{"site.schema.json", SiteFile, SiteContent{}},
+	{"tickets.schema.json", TicketsFile, TicketsContent{}},
+	{"faq.schema.json", FAQFile, []FaqItem{}},
+	{"instagram.schema.json", InstagramFile, []InstagramPost{}},
+}
+
+// Schemas returns the JSON Schema (draft-07) of each content file, keyed by
+// the names in SchemaFiles.
+func Schemas() (map[string][]byte, error) {
+	out := make(map[string][]byte, len(SchemaFiles))
+	for _, sf := range SchemaFiles {
+		g := &schemaGen{defs: make(map[string]interface{})}
+		s := g.root(reflect.TypeOf(sf.model))
+		s["$schema"] = "http://json-schema.org/draft-07/schema#"
+		s["title"] = sf.File
+		s["description"] = "Generated from internal/content by `go run fetch_logos.go content schema`; do not edit."
+		if len(g.defs) > 0 {
+			s["definitions"] = g.defs
+		}
+		var buf bytes.Buffer
+		enc := json.NewEncoder(&buf)
+		enc.SetEscapeHTML(false)
+		enc.SetIndent("", "  ")
+		if err := enc.Encode(s); err != nil {
+			return nil, fmt.Errorf("%s: %w", sf.Name, err)
+		}
+		out[sf.Name] = buf.Bytes()
+	}
+	return out, nil
+}
+
+// schemaGen builds one schema document. Structs below the top level go to
+// definitions, by type name.
+type schemaGen struct {
+	defs map[string]interface{}
+}
+
+type schema = map[string]interface{}
+
+func (g *schemaGen) root(t reflect.Type) schema {
+	if t.Kind() == reflect.Struct {
+		return g.object(t)
+	}
+	return g.value(t, Field{})
+}
+
+func (g *schemaGen) object(t reflect.Type) schema {
+	props := make(schema)
+	required := []string{}
+	for _, f := range Fields(t) {
+		props[f.Key] = g.value(f.Type, f)
+		if f.Required {
+			required = append(required, f.Key)
+		}
+	}
+	s := schema{"type": "object", "properties": props, "additionalProperties": false}
+	if len(required) > 0 {
+		s["required"] = required
+	}
+	return s
+}
+
+// value is the schema of a t, held by field f.
+func (g *schemaGen) value(t reflect.Type, f Field) schema {
+	switch t.Kind() {
+	case reflect.Ptr:
+		return g.value(t.Elem(), f)
+	case reflect.Struct:
+		if _, ok := g.defs[t.Name()]; !ok {
+			g.defs[t.Name()] = nil // claimed, in case of recursion
+			g.defs[t.Name()] = g.object(t)
+		}
+		return schema{"$ref": "#/definitions/" + t.Name()}
+	case reflect.Slice:
+		s := schema{"type": "array", "items": g.value(t.Elem(), f)}
+		if f.Enum != nil {
+			s["uniqueItems"] = true
+		}
+		return s
+	case reflect.Bool:
+		return schema{"type": "boolean"}
+	case reflect.Int:
+		return schema{"type": "integer"}
+	case reflect.Float64:
+		return schema{"type": "number"}
+	}
+	s := schema{"type": "string"}
+	if f.Enum != nil {
+		s["enum"] = f.Enum
+		return s
+	}
+	k, ok := stringKinds[f.Kind]
+	if ok {
+		s["description"] = k.desc
+		if k.format != "" {
+			s["format"] = k.format
+		}
+		if k.pattern != "" {
+			s["pattern"] = k.pattern
+			if !f.Required {
+				// Optional strings may be empty, as in the validator.
+				s["pattern"] = "^$|" + k.pattern
+			}
+		}
+	}
+	if f.Required {
+		s["minLength"] = 1
+	}
+	return s
+}
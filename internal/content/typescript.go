@@ -0,0 +1,357 @@
+package content
+
+import (
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+	"unicode"
+)
+
+// TypesFile holds the TypeScript side of the models.
+const TypesFile = "app/lib/types.ts"
+
+// tsModels are the models with a type of the same name in TypesFile.
+// Nested structs (Organizer, Links, ...) are inline object types there.
+var tsModels = map[string]reflect.Type{
+	"CtaButton":      reflect.TypeOf(CtaButton{}),
+	"Sponsor":        reflect.TypeOf(Sponsor{}),
+	"InstagramPost":  reflect.TypeOf(InstagramPost{}),
+	"Vendor":         reflect.TypeOf(Vendor{}),
+	"Band":           reflect.TypeOf(Band{}),
+	"SiteContent":    reflect.TypeOf(SiteContent{}),
+	"TicketOption":   reflect.TypeOf(TicketOption{}),
+	"PickupStop":     reflect.TypeOf(PickupStop{}),
+	"TicketsContent": reflect.TypeOf(TicketsContent{}),
+	"FaqItem":        reflect.TypeOf(FaqItem{}),
+}
+
+// CheckTypeScript compares the models with the types declared in src, the
+// text of TypesFile: fields, which are optional, their types, and string
+// literal unions against enums. It describes each disagreement.
+func CheckTypeScript(src string) ([]string, error) {
+	p := &tsParser{toks: tsTokens(src)}
+	decls, err := p.file()
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", TypesFile, err)
+	}
+	var problems []string
+	for _, name := range sortedNames(tsModels) {
+		ts, ok := decls[name]
+		if !ok {
+			problems = append(problems, fmt.Sprintf("%s: missing from %s", name, TypesFile))
+			continue
+		}
+		problems = append(problems, compareTS(name, ts, tsModels[name], Field{Required: true})...)
+	}
+	for name, ts := range decls {
+		if _, ok := tsModels[name]; !ok && ts.kind == "object" {
+			problems = append(problems, fmt.Sprintf("%s: no Go model in internal/content", name))
+		}
+	}
+	sort.Strings(problems)
+	return problems, nil
+}
+
+func sortedNames(m map[string]reflect.Type) []string {
+	names := make([]string, 0, len(m))
+	for n := range m {
+		names = append(names, n)
+	}
+	sort.Strings(names)
+	return names
+}
+
+// compareTS compares the TypeScript type ts with t, held by field f.
+func compareTS(path string, ts *tsType, t reflect.Type, f Field) []string {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
+	}
+	mismatch := func(want string) []string {
+		return []string{fmt.Sprintf("%s: %s in %s, %s in Go", path, ts, TypesFile, want)}
+	}
+	switch t.Kind() {
+	case reflect.Struct:
+		if ts.kind == "ref" {
+			if ts.name != t.Name() {
+				return mismatch(t.Name())
+			}
+			return nil // compared on its own
+		}
+		if ts.kind != "object" {
+			return mismatch("an object")
+		}
+		var problems []string
+		seen := make(map[string]bool)
+		for _, gf := range Fields(t) {
+			seen[gf.Key] = true
+			tf := ts.field(gf.Key)
+			if tf == nil {
+				problems = append(problems, fmt.Sprintf("%s.%s: missing from %s", path, gf.Key, TypesFile))
+				continue
+			}
+			if tf.optional == gf.Required {
+				want := "optional"
+				if gf.Required {
+					want = "required"
+				}
+				problems = append(problems, fmt.Sprintf("%s.%s: %s in Go, not in %s", path, gf.Key, want, TypesFile))
+			}
+			problems = append(problems, compareTS(path+"."+gf.Key, tf.typ, gf.Type, gf)...)
+		}
+		for _, tf := range ts.fields {
+			if !seen[tf.name] {
+				problems = append(problems, fmt.Sprintf("%s.%s: missing from the Go model", path, tf.name))
+			}
+		}
+		return problems
+	case reflect.Slice:
+		if ts.kind != "array" {
+			return mismatch("an array")
+		}
+		return compareTS(path+"[]", ts.elem, t.Elem(), f)
+	case reflect.Bool:
+		if ts.kind != "boolean" {
+			return mismatch("a boolean")
+		}
+	case reflect.Int, reflect.Float64:
+		if ts.kind != "number" {
+			return mismatch("a number")
+		}
+	case reflect.String:
+		if f.Enum == nil {
+			if ts.kind != "string" {
+				return mismatch("a string")
+			}
+			return nil
+		}
+		if ts.kind != "literals" || strings.Join(ts.values, "|") != strings.Join(f.Enum, "|") {
+			return mismatch(fmt.Sprintf("%q", f.Enum))
+		}
+	}
+	return nil
+}
+
+// tsType is a parsed TypeScript type expression.
+type tsType struct {
+	kind   string // object, array, ref, string, number, boolean, literals, or other
+	name   string // ref
+	fields []tsField
+	elem   *tsType
+	values []string // literals, in order
+}
+
+type tsField struct {
+	name     string
+	optional bool
+	typ      *tsType
+}
+
+func (t *tsType) field(name string) *tsField {
+	for i := range t.fields {
+		if t.fields[i].name == name {
+			return &t.fields[i]
+		}
+	}
+	return nil
+}
+
+func (t *tsType) String() string {
+	switch t.kind {
+	case "ref":
+		return t.name
+	case "array":
+		return t.elem.String() + "[]"
+	case "literals":
+		return fmt.Sprintf("%q", t.values)
+	}
+	return t.kind
+}
+
+// tsTokens splits src into identifiers, string literals (with their
+// quotes), and single punctuation characters, dropping comments.
+func tsTokens(src string) []string {
+	var toks []string
+	for i := 0; i < len(src); {
+		c := src[i]
+		switch {
+		case strings.HasPrefix(src[i:], "//"):
+			for i < len(src) && src[i] != '\n' {
+				i++
+			}
+		case strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return toks
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			j := i + 1
+			for j < len(src) && src[j] != c {
+				if src[j] == '\\' {
+					j++
+				}
+				j++
+			}
+			toks = append(toks, src[i:min(j+1, len(src))])
+			i = j + 1
+		case unicode.IsSpace(rune(c)):
+			i++
+		case c == '_' || c == '$' || unicode.IsLetter(rune(c)) || unicode.IsDigit(rune(c)):
+			j := i
+			for j < len(src) && (src[j] == '_' || src[j] == '$' || unicode.IsLetter(rune(src[j])) || unicode.IsDigit(rune(src[j]))) {
+				j++
+			}
+			toks = append(toks, src[i:j])
+			i = j
+		default:
+			toks = append(toks, string(c))
+			i++
+		}
+	}
+	return toks
+}
+
+// tsParser reads the "export type Name = ..." declarations of a file and
+// skips everything else.
+type tsParser struct {
+	toks []string
+	i    int
+}
+
+func (p *tsParser) peek() string {
+	if p.i < len(p.toks) {
+		return p.toks[p.i]
+	}
+	return ""
+}
+
+func (p *tsParser) next() string {
+	t := p.peek()
+	p.i++
+	return t
+}
+
+func (p *tsParser) expect(tok string) error {
+	if got := p.next(); got != tok {
+		return fmt.Errorf("expected %q, got %q", tok, got)
+	}
+	return nil
+}
+
+func (p *tsParser) file() (map[string]*tsType, error) {
+	decls := make(map[string]*tsType)
+	for p.i < len(p.toks) {
+		if p.next() != "export" || p.peek() != "type" {
+			continue
+		}
+		p.next()
+		name := p.next()
+		if err := p.expect("="); err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+		t, err := p.union()
+		if err != nil {
+			return nil, fmt.Errorf("type %s: %w", name, err)
+		}
+		decls[name] = t
+	}
+	return decls, nil
+}
+
+func (p *tsParser) union() (*tsType, error) {
+	if p.peek() == "|" {
+		p.next()
+	}
+	var members []*tsType
+	for {
+		t, err := p.postfix()
+		if err != nil {
+			return nil, err
+		}
+		members = append(members, t)
+		if p.peek() != "|" {
+			break
+		}
+		p.next()
+	}
+	if len(members) == 1 {
+		return members[0], nil
+	}
+	lit := &tsType{kind: "literals"}
+	for _, m := range members {
+		if m.kind != "literals" {
+			return &tsType{kind: "other"}, nil
+		}
+		lit.values = append(lit.values, m.values...)
+	}
+	return lit, nil
+}
+
+func (p *tsParser) postfix() (*tsType, error) {
+	t, err := p.primary()
+	if err != nil {
+		return nil, err
+	}
+	for p.peek() == "[" {
+		p.next()
+		if err := p.expect("]"); err != nil {
+			return nil, err
+		}
+		t = &tsType{kind: "array", elem: t}
+	}
+	return t, nil
+}
+
+func (p *tsParser) primary() (*tsType, error) {
+	tok := p.next()
+	switch {
+	case tok == "{":
+		obj := &tsType{kind: "object"}
+		for p.peek() != "}" {
+			if p.peek() == "" {
+				return nil, fmt.Errorf("unterminated object type")
+			}
+			name := strings.Trim(p.next(), `'"`)
+			f := tsField{name: name}
+			if p.peek() == "?" {
+				p.next()
+				f.optional = true
+			}
+			if err := p.expect(":"); err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			t, err := p.union()
+			if err != nil {
+				return nil, fmt.Errorf("field %s: %w", name, err)
+			}
+			f.typ = t
+			obj.fields = append(obj.fields, f)
+			if p.peek() == ";" || p.peek() == "," {
+				p.next()
+			}
+		}
+		p.next()
+		return obj, nil
+	case tok == "(":
+		t, err := p.union()
+		if err != nil {
+			return nil, err
+		}
+		return t, p.expect(")")
+	case strings.HasPrefix(tok, "'") || strings.HasPrefix(tok, `"`):
+		return &tsType{kind: "literals", values: []string{tok[1 : len(tok)-1]}}, nil
+	case tok == "string" || tok == "number" || tok == "boolean":
+		return &tsType{kind: tok}, nil
+	case tok == "Array" && p.peek() == "<":
+		p.next()
+		elem, err := p.union()
+		if err != nil {
+			return nil, err
+		}
+		return &tsType{kind: "array", elem: elem}, p.expect(">")
+	case tok != "" && (tok[0] == '_' || unicode.IsLetter(rune(tok[0]))):
+		return &tsType{kind: "ref", name: tok}, nil
+	}
+	return nil, fmt.Errorf("unexpected %q", tok)
+}